@@ -0,0 +1,142 @@
+package orderbook
+
+import (
+	"encoding/json"
+
+	"github.com/shopspring/decimal"
+)
+
+// BestBid returns price level with maximal bid price, nil if there are no bids
+func (ob *OrderBook) BestBid() *OrderQueue {
+	return ob.bids.MaxPriceQueue()
+}
+
+// BestAsk returns price level with minimal ask price, nil if there are no asks
+func (ob *OrderBook) BestAsk() *OrderQueue {
+	return ob.asks.MinPriceQueue()
+}
+
+func (ob *OrderBook) top() (bid, ask *OrderQueue, err error) {
+	bid, ask = ob.BestBid(), ob.BestAsk()
+	if bid == nil || ask == nil {
+		err = ErrNoLiquidity
+	}
+	return
+}
+
+// Spread returns difference between best ask and best bid prices
+func (ob *OrderBook) Spread() (decimal.Decimal, error) {
+	bid, ask, err := ob.top()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return ask.Price().Sub(bid.Price()), nil
+}
+
+// MidPrice returns average of best ask and best bid prices
+func (ob *OrderBook) MidPrice() (decimal.Decimal, error) {
+	bid, ask, err := ob.top()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return ask.Price().Add(bid.Price()).Div(decimal.New(2, 0)), nil
+}
+
+// MicroPrice returns mid price weighted by volumes of the best levels:
+// (bid price * ask volume + ask price * bid volume) / (bid volume + ask volume)
+func (ob *OrderBook) MicroPrice() (decimal.Decimal, error) {
+	bid, ask, err := ob.top()
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	weighted := bid.Price().Mul(ask.Volume()).Add(ask.Price().Mul(bid.Volume()))
+	return weighted.Div(bid.Volume().Add(ask.Volume())), nil
+}
+
+// Imbalance returns (bid volume - ask volume) / (bid volume + ask volume) of top levels,
+// result is in range [-1, 1], positive value means buy pressure. All levels are used if levels <= 0
+func (ob *OrderBook) Imbalance(levels int) (decimal.Decimal, error) {
+	bidVolume, askVolume := decimal.Zero, decimal.Zero
+
+	depth := ob.Depth(levels)
+	for _, level := range depth.Bids {
+		bidVolume = bidVolume.Add(level[1])
+	}
+	for _, level := range depth.Asks {
+		askVolume = askVolume.Add(level[1])
+	}
+
+	total := bidVolume.Add(askVolume)
+	if total.Sign() == 0 {
+		return decimal.Zero, ErrNoLiquidity
+	}
+	return bidVolume.Sub(askVolume).Div(total), nil
+}
+
+// MarketImpact stores result of walking the side of the order book with definite quantity
+type MarketImpact struct {
+	Quantity     decimal.Decimal `json:"quantity"`     // available quantity up to requested
+	Total        decimal.Decimal `json:"total"`        // total price of the quantity
+	AveragePrice decimal.Decimal `json:"averagePrice"` // volume weighted average price
+	WorstPrice   decimal.Decimal `json:"worstPrice"`   // price of the last consumed level
+	Levels       int             `json:"levels"`       // amount of consumed price levels
+}
+
+// String implements fmt.Stringer interface
+func (mi *MarketImpact) String() string {
+	data, _ := json.Marshal(mi)
+	return string(data)
+}
+
+// CalculateMarketImpact returns volume weighted average price, worst price and amount of
+// price levels consumed by market order with requested quantity.
+// If err is not nil result contains values of all levels in side
+func (ob *OrderBook) CalculateMarketImpact(side Side, quantity decimal.Decimal) (impact *MarketImpact, err error) {
+	impact = &MarketImpact{
+		Quantity:     decimal.Zero,
+		Total:        decimal.Zero,
+		AveragePrice: decimal.Zero,
+		WorstPrice:   decimal.Zero,
+	}
+
+	var (
+		level *OrderQueue
+		iter  func(decimal.Decimal) *OrderQueue
+	)
+
+	if side == Buy {
+		level = ob.asks.MinPriceQueue()
+		iter = ob.asks.GreaterThan
+	} else {
+		level = ob.bids.MaxPriceQueue()
+		iter = ob.bids.LessThan
+	}
+
+	for quantity.Sign() > 0 && level != nil {
+		levelVolume := level.Volume()
+		levelPrice := level.Price()
+		impact.Levels++
+		impact.WorstPrice = levelPrice
+		if quantity.GreaterThanOrEqual(levelVolume) {
+			impact.Total = impact.Total.Add(levelPrice.Mul(levelVolume))
+			impact.Quantity = impact.Quantity.Add(levelVolume)
+			quantity = quantity.Sub(levelVolume)
+			level = iter(levelPrice)
+		} else {
+			impact.Total = impact.Total.Add(levelPrice.Mul(quantity))
+			impact.Quantity = impact.Quantity.Add(quantity)
+			quantity = decimal.Zero
+		}
+	}
+
+	if impact.Quantity.Sign() > 0 {
+		impact.AveragePrice = impact.Total.Div(impact.Quantity)
+	}
+
+	if quantity.Sign() > 0 {
+		err = ErrInsufficientQuantity
+	}
+
+	return
+}
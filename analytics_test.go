@@ -0,0 +1,74 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestTopOfBook(t *testing.T) {
+	ob := NewOrderBook()
+	if _, err := ob.Spread(); err != ErrNoLiquidity {
+		t.Fatal("can get spread of empty book")
+	}
+
+	addDepth(ob, "", decimal.New(2, 0))
+	ob.ProcessLimitOrder(Sell, "s-100", decimal.New(6, 0), decimal.New(100, 0))
+
+	if ob.BestBid().Price().String() != "90" || ob.BestAsk().Price().String() != "100" {
+		t.Fatal("invalid best levels")
+	}
+
+	if spread, _ := ob.Spread(); !spread.Equal(decimal.New(10, 0)) {
+		t.Fatalf("invalid spread: %s", spread)
+	}
+
+	if mid, _ := ob.MidPrice(); !mid.Equal(decimal.New(95, 0)) {
+		t.Fatalf("invalid mid price: %s", mid)
+	}
+
+	// (90 * 8 + 100 * 2) / 10
+	if micro, _ := ob.MicroPrice(); !micro.Equal(decimal.New(92, 0)) {
+		t.Fatalf("invalid micro price: %s", micro)
+	}
+
+	// (2 - 8) / 10
+	if imbalance, _ := ob.Imbalance(1); !imbalance.Equal(decimal.New(-6, -1)) {
+		t.Fatalf("invalid imbalance: %s", imbalance)
+	}
+
+	// (10 - 16) / 26
+	if imbalance, _ := ob.Imbalance(0); !imbalance.Equal(decimal.New(-6, 0).Div(decimal.New(26, 0))) {
+		t.Fatalf("invalid full imbalance: %s", imbalance)
+	}
+
+	if _, err := NewOrderBook().Imbalance(5); err != ErrNoLiquidity {
+		t.Fatal("can get imbalance of empty book")
+	}
+}
+
+func TestMarketImpact(t *testing.T) {
+	ob := NewOrderBook()
+	addDepth(ob, "", decimal.New(2, 0))
+
+	impact, err := ob.CalculateMarketImpact(Buy, decimal.New(5, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 100 * 2 + 110 * 2 + 120 * 1
+	if !impact.Total.Equal(decimal.New(540, 0)) || !impact.AveragePrice.Equal(decimal.New(108, 0)) ||
+		!impact.WorstPrice.Equal(decimal.New(120, 0)) || impact.Levels != 3 {
+		t.Fatalf("invalid impact: %s", impact)
+	}
+
+	impact, err = ob.CalculateMarketImpact(Sell, decimal.New(4, 0))
+	if err != nil || !impact.AveragePrice.Equal(decimal.New(85, 0)) || impact.Levels != 2 {
+		t.Fatalf("invalid impact: %s", impact)
+	}
+
+	impact, err = ob.CalculateMarketImpact(Sell, decimal.New(11, 0))
+	if err != ErrInsufficientQuantity || !impact.Quantity.Equal(decimal.New(10, 0)) || !impact.WorstPrice.Equal(decimal.New(50, 0)) {
+		t.Fatalf("invalid insufficient impact: %s, %v", impact, err)
+	}
+}
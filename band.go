@@ -0,0 +1,127 @@
+package orderbook
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// BandAction is the reaction of the OrderBook on order which would trade outside of the price band
+type BandAction int
+
+// BandReject rejects the order, BandHalt rejects the order and switches the book to Halted phase for the cool-down
+const (
+	BandReject BandAction = iota
+	BandHalt
+)
+
+// PriceBand stores volatility limits of trade prices
+type PriceBand struct {
+	Static   decimal.Decimal // max relative deviation from the reference price (0.1 is 10%), zero disables
+	Dynamic  decimal.Decimal // max relative deviation from the last trade price, zero disables
+	Action   BandAction
+	CoolDown time.Duration // duration of the halt for BandHalt action, zero halts until the phase is changed
+}
+
+// SetPriceBand sets up price band checked before matching of every market and limit order,
+// nil band disables the check
+func (ob *OrderBook) SetPriceBand(band *PriceBand) {
+	ob.band = band
+}
+
+// SetReferencePrice sets up reference price of the static band (e.g. previous close)
+func (ob *OrderBook) SetReferencePrice(price decimal.Decimal) {
+	ob.refPrice = price
+}
+
+// ReferencePrice returns reference price of the static band
+func (ob *OrderBook) ReferencePrice() decimal.Decimal {
+	return ob.refPrice
+}
+
+// LastPrice returns price of the last trade, zero if there were no trades
+func (ob *OrderBook) LastPrice() decimal.Decimal {
+	return ob.lastPrice
+}
+
+// SetLastPrice sets up price of the last trade (e.g. when the book is restored from snapshot)
+func (ob *OrderBook) SetLastPrice(price decimal.Decimal) {
+	ob.lastPrice = price
+}
+
+// Halted reports whether the book is in Halted phase (e.g. stopped by the circuit breaker)
+func (ob *OrderBook) Halted() bool {
+	return ob.Phase() == Halted
+}
+
+// Resume switches halted book to Open phase, the end of the halt cool-down is dropped
+func (ob *OrderBook) Resume() {
+	if ob.Halted() {
+		ob.SetPhase(Open)
+	}
+}
+
+// PriceLimits returns allowed range of trade prices, zero limit means there is no limit
+func (ob *OrderBook) PriceLimits() (low, high decimal.Decimal) {
+	low, high = decimal.Zero, decimal.Zero
+	if ob.band == nil {
+		return
+	}
+
+	narrow := func(base, deviation decimal.Decimal) {
+		if base.Sign() <= 0 || deviation.Sign() <= 0 {
+			return
+		}
+		l := base.Sub(base.Mul(deviation))
+		h := base.Add(base.Mul(deviation))
+		if low.Sign() == 0 || l.GreaterThan(low) {
+			low = l
+		}
+		if high.Sign() == 0 || h.LessThan(high) {
+			high = h
+		}
+	}
+
+	narrow(ob.refPrice, ob.band.Static)
+	narrow(ob.lastPrice, ob.band.Dynamic)
+	return
+}
+
+// checkBand returns error if the order would trade outside of the band.
+// Order walks the opposite side until quantity (or funds for market price order) is consumed,
+// limit stops the walk if it is not zero
+func (ob *OrderBook) checkBand(side Side, quantity, limit, funds decimal.Decimal) error {
+	low, high := ob.PriceLimits()
+	if low.Sign() == 0 && high.Sign() == 0 {
+		return nil
+	}
+
+	level, next := ob.asks.MinPriceQueue(), ob.asks.GreaterThan
+	if side == Sell {
+		level, next = ob.bids.MaxPriceQueue(), ob.bids.LessThan
+	}
+
+	for level != nil {
+		price := level.Price()
+		if limit.Sign() > 0 && ((side == Buy && price.GreaterThan(limit)) || (side == Sell && price.LessThan(limit))) {
+			return nil
+		}
+
+		if (low.Sign() > 0 && price.LessThan(low)) || (high.Sign() > 0 && price.GreaterThan(high)) {
+			if ob.band.Action == BandHalt {
+				ob.halt(ob.band.CoolDown)
+			}
+			return ErrPriceBand
+		}
+
+		if funds.Sign() > 0 {
+			if funds = funds.Sub(price.Mul(level.Volume())); funds.Sign() <= 0 {
+				return nil
+			}
+		} else if quantity = quantity.Sub(level.Volume()); quantity.Sign() <= 0 {
+			return nil
+		}
+		level = next(price)
+	}
+	return nil
+}
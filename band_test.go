@@ -0,0 +1,104 @@
+package orderbook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestStaticPriceBand(t *testing.T) {
+	ob := NewOrderBook()
+	addDepth(ob, "", decimal.New(2, 0))
+	ob.SetReferencePrice(decimal.New(100, 0))
+	ob.SetPriceBand(&PriceBand{Static: decimal.New(15, -2)})
+
+	if low, high := ob.PriceLimits(); !low.Equal(decimal.New(85, 0)) || !high.Equal(decimal.New(115, 0)) {
+		t.Fatalf("invalid limits: %s - %s", low, high)
+	}
+
+	// walks to 120 ask
+	if _, _, _, _, _, err := ob.ProcessMarketQuantityOrder(Buy, decimal.New(5, 0)); err != ErrPriceBand {
+		t.Fatal("market order trades outside of the band")
+	}
+
+	if _, _, _, _, _, err := ob.ProcessMarketPriceBuy(decimal.New(500, 0), 8); err != ErrPriceBand {
+		t.Fatal("market price order trades outside of the band")
+	}
+
+	if ob.asks.Len() != 5 || ob.Halted() {
+		t.Fatal("rejected order changes the book")
+	}
+
+	// limit stops the walk before the band
+	if _, _, _, _, err := ob.ProcessLimitOrder(Buy, "b-110", decimal.New(5, 0), decimal.New(110, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	// resting order outside of the band is allowed
+	if _, _, _, _, err := ob.ProcessLimitOrder(Sell, "s-200", decimal.New(1, 0), decimal.New(200, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, _, _, err := ob.ProcessMarketQuantityOrder(Sell, decimal.New(3, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	ob.SetPriceBand(nil)
+	if _, _, _, _, _, err := ob.ProcessMarketQuantityOrder(Sell, decimal.New(7, 0)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDynamicPriceBandHalt(t *testing.T) {
+	now := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	ob := NewOrderBook()
+	ob.SetClock(func() time.Time { return now })
+	addDepth(ob, "", decimal.New(2, 0))
+	ob.SetPriceBand(&PriceBand{Dynamic: decimal.New(1, -1), Action: BandHalt, CoolDown: time.Minute})
+
+	// no last trade price yet
+	if _, _, _, _, rollback, err := ob.ProcessMarketQuantityOrder(Buy, decimal.New(1, 0)); err != nil {
+		t.Fatal(err)
+	} else {
+		rollback()
+		if ob.LastPrice().Sign() != 0 {
+			t.Fatal("rollback is not restored last price")
+		}
+	}
+
+	ob.ProcessMarketQuantityOrder(Buy, decimal.New(1, 0))
+	if !ob.LastPrice().Equal(decimal.New(100, 0)) {
+		t.Fatalf("invalid last price: %s", ob.LastPrice())
+	}
+
+	if ob.Fills()[0].Time != now {
+		t.Fatal("fill time is not from the clock")
+	}
+
+	// 90 - 110 band, 80 bid is outside
+	if _, _, _, _, _, err := ob.ProcessMarketQuantityOrder(Sell, decimal.New(3, 0)); err != ErrPriceBand {
+		t.Fatal("market order trades outside of the band")
+	}
+
+	if !ob.Halted() {
+		t.Fatal("book is not halted")
+	}
+
+	if _, _, _, _, err := ob.ProcessLimitOrder(Sell, "s-90", decimal.New(1, 0), decimal.New(90, 0)); err != ErrHalted {
+		t.Fatal("halted book processes orders")
+	}
+
+	if o, _ := ob.CancelOrder("buy-50"); o == nil {
+		t.Fatal("can't cancel order in halted book")
+	}
+
+	now = now.Add(time.Minute)
+	if ob.Halted() {
+		t.Fatal("book is halted after cool-down")
+	}
+
+	if _, _, _, _, err := ob.ProcessLimitOrder(Sell, "s-90", decimal.New(1, 0), decimal.New(90, 0)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,339 @@
+package orderbook
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math/big"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Binary snapshot layout of the OrderBook:
+//
+//	magic "OBSN" | version (1 byte) | asks | bids | CRC-32 (IEEE, big endian) of the preceding bytes
+//
+// side is amount of price levels followed by the levels in ascending price order,
+// level is price and amount of orders followed by the orders in queue (FIFO) order,
+// order is id, owner, side, quantity and timestamp (the price is the level price).
+// Integers are varints, strings are length prefixed, decimals are exponent and coefficient
+const (
+	snapshotMagic   = "OBSN"
+	snapshotVersion = 1
+
+	// maxExponent limits exponents of decoded decimals, arithmetic with rescaled
+	// coefficients of huge exponents takes unbounded time and memory
+	maxExponent = 1 << 10
+)
+
+type binaryWriter struct {
+	buf     []byte
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (w *binaryWriter) uvarint(v uint64) {
+	n := binary.PutUvarint(w.scratch[:], v)
+	w.buf = append(w.buf, w.scratch[:n]...)
+}
+
+func (w *binaryWriter) varint(v int64) {
+	n := binary.PutVarint(w.scratch[:], v)
+	w.buf = append(w.buf, w.scratch[:n]...)
+}
+
+func (w *binaryWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *binaryWriter) decimal(d decimal.Decimal) {
+	w.varint(int64(d.Exponent()))
+	c := d.Coefficient()
+	if c.IsInt64() {
+		w.buf = append(w.buf, 0)
+		w.varint(c.Int64())
+		return
+	}
+
+	sign := byte(1)
+	if c.Sign() < 0 {
+		sign = 2
+	}
+	w.buf = append(w.buf, sign)
+	w.string(string(c.Bytes()))
+}
+
+func (w *binaryWriter) time(t time.Time) {
+	w.varint(t.Unix())
+	w.uvarint(uint64(t.Nanosecond()))
+}
+
+func (w *binaryWriter) order(o *Order) {
+	w.string(o.ID())
+	w.string(o.Owner())
+	w.buf = append(w.buf, byte(o.Side()))
+	w.decimal(o.Quantity())
+	w.time(o.Time())
+}
+
+func (w *binaryWriter) queue(oq *OrderQueue) {
+	w.decimal(oq.Price())
+	w.uvarint(uint64(oq.Len()))
+	for iter := oq.Head(); iter != nil; iter = iter.Next() {
+		w.order(iter.Value.(*Order))
+	}
+}
+
+func (w *binaryWriter) side(os *OrderSide) {
+	w.uvarint(uint64(os.Depth()))
+	for level := os.MinPriceQueue(); level != nil; level = os.GreaterThan(level.Price()) {
+		w.queue(level)
+	}
+}
+
+type binaryReader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (r *binaryReader) fail(format string, args ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: "+format+" at offset %d", append([]interface{}{ErrInvalidSnapshot}, append(args, r.off)...)...)
+	}
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data[r.off:])
+	if n <= 0 {
+		r.fail("malformed integer")
+		return 0
+	}
+	r.off += n
+	return v
+}
+
+func (r *binaryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data[r.off:])
+	if n <= 0 {
+		r.fail("malformed integer")
+		return 0
+	}
+	r.off += n
+	return v
+}
+
+func (r *binaryReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if r.off >= len(r.data) {
+		r.fail("unexpected end of data")
+		return 0
+	}
+	r.off++
+	return r.data[r.off-1]
+}
+
+func (r *binaryReader) bytes() []byte {
+	n := r.uvarint()
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(len(r.data)-r.off) {
+		r.fail("length %d is out of data", n)
+		return nil
+	}
+	b := r.data[r.off : r.off+int(n)]
+	r.off += int(n)
+	return b
+}
+
+func (r *binaryReader) string() string {
+	return string(r.bytes())
+}
+
+// count reads amount of items, each item takes at least one byte
+func (r *binaryReader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.data)-r.off) {
+		r.fail("count %d is out of data", n)
+		return 0
+	}
+	return int(n)
+}
+
+func (r *binaryReader) decimal() decimal.Decimal {
+	exp := r.varint()
+	if exp < -maxExponent || exp > maxExponent {
+		r.fail("decimal exponent %d is out of range", exp)
+		return decimal.Zero
+	}
+	switch r.byte() {
+	case 0:
+		return decimal.New(r.varint(), int32(exp))
+	case 1:
+		return decimal.NewFromBigInt(new(big.Int).SetBytes(r.bytes()), int32(exp))
+	case 2:
+		return decimal.NewFromBigInt(new(big.Int).Neg(new(big.Int).SetBytes(r.bytes())), int32(exp))
+	}
+	r.fail("unknown decimal encoding")
+	return decimal.Zero
+}
+
+func (r *binaryReader) time() time.Time {
+	sec := r.varint()
+	nsec := r.uvarint()
+	return time.Unix(sec, int64(nsec)).UTC()
+}
+
+func (r *binaryReader) orderSide() Side {
+	switch s := Side(r.byte()); s {
+	case Buy, Sell:
+		return s
+	}
+	r.fail("unknown order side")
+	return Sell
+}
+
+func (r *binaryReader) order(price decimal.Decimal) *Order {
+	o := &Order{price: price}
+	o.id = r.string()
+	o.owner = r.string()
+	o.side = r.orderSide()
+	o.quantity = r.decimal()
+	o.timestamp = r.time()
+	return o
+}
+
+func (r *binaryReader) queue(oq *OrderQueue) {
+	*oq = *NewOrderQueue(r.decimal())
+	for n := r.count(); n > 0 && r.err == nil; n-- {
+		oq.Append(r.order(oq.Price()))
+	}
+}
+
+func (r *binaryReader) side(os *OrderSide) {
+	*os = *NewOrderSide()
+	for n := r.count(); n > 0 && r.err == nil; n-- {
+		price := r.decimal()
+		for m := r.count(); m > 0 && r.err == nil; m-- {
+			os.Append(r.order(price))
+		}
+	}
+}
+
+func (r *binaryReader) end() error {
+	if r.err == nil && r.off != len(r.data) {
+		r.fail("unexpected trailing data")
+	}
+	return r.err
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface
+func (o *Order) MarshalBinary() ([]byte, error) {
+	w := &binaryWriter{}
+	w.decimal(o.Price())
+	w.order(o)
+	return w.buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface
+func (o *Order) UnmarshalBinary(data []byte) error {
+	r := &binaryReader{data: data}
+	order := r.order(r.decimal())
+	if err := r.end(); err != nil {
+		return err
+	}
+	*o = *order
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface
+func (oq *OrderQueue) MarshalBinary() ([]byte, error) {
+	w := &binaryWriter{}
+	w.queue(oq)
+	return w.buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface,
+// queue volume is recalculated from the orders
+func (oq *OrderQueue) UnmarshalBinary(data []byte) error {
+	r := &binaryReader{data: data}
+	queue := &OrderQueue{}
+	r.queue(queue)
+	if err := r.end(); err != nil {
+		return err
+	}
+	*oq = *queue
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface
+func (os *OrderSide) MarshalBinary() ([]byte, error) {
+	w := &binaryWriter{}
+	w.side(os)
+	return w.buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface,
+// side aggregates are recalculated from the orders
+func (os *OrderSide) UnmarshalBinary(data []byte) error {
+	r := &binaryReader{data: data}
+	side := &OrderSide{}
+	r.side(side)
+	if err := r.end(); err != nil {
+		return err
+	}
+	*os = *side
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface.
+// Snapshot contains format version and checksum, FIFO order of every price level is preserved
+func (ob *OrderBook) MarshalBinary() ([]byte, error) {
+	w := &binaryWriter{buf: make([]byte, 0, 64*len(ob.orders)+16)}
+	w.buf = append(w.buf, snapshotMagic...)
+	w.buf = append(w.buf, snapshotVersion)
+	w.side(ob.asks)
+	w.side(ob.bids)
+	binary.BigEndian.PutUint32(w.scratch[:crc32.Size], crc32.ChecksumIEEE(w.buf))
+	w.buf = append(w.buf, w.scratch[:crc32.Size]...)
+	return w.buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
+// Snapshot with unknown version, wrong checksum or inconsistent orders (see Validate) is rejected,
+// the book is not changed on error
+func (ob *OrderBook) UnmarshalBinary(data []byte) error {
+	header := len(snapshotMagic) + 1
+	if len(data) < header+crc32.Size || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return fmt.Errorf("%w: unknown format", ErrInvalidSnapshot)
+	}
+
+	if version := data[len(snapshotMagic)]; version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, version)
+	}
+
+	payload := data[:len(data)-crc32.Size]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(data[len(payload):]) {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidSnapshot)
+	}
+
+	r := &binaryReader{data: payload, off: header}
+	asks, bids := &OrderSide{}, &OrderSide{}
+	r.side(asks)
+	r.side(bids)
+	if err := r.end(); err != nil {
+		return err
+	}
+
+	return ob.restore(asks, bids)
+}
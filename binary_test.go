@@ -0,0 +1,144 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestOrderBookBinary(t *testing.T) {
+	ob := NewOrderBook()
+	addDepth(ob, "01-", decimal.New(10, 0))
+	addDepth(ob, "02-", decimal.RequireFromString("0.000000000000000000000000000001"))
+	ob.ProcessLimitOrderFor("alice", Buy, "big", decimal.RequireFromString("123456789012345678901234567890.5"), decimal.New(60, 0))
+	ob.ProcessMarketQuantityOrder(Buy, decimal.New(5, 0)) // partial of the queue head
+
+	data, err := ob.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewOrderBook()
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := json.Marshal(ob)
+	result, _ := json.Marshal(restored)
+	if string(expected) != string(result) {
+		t.Fatalf("restored book differs:\n%s\n%s", expected, result)
+	}
+
+	for _, id := range []string{"01-sell-100", "02-buy-60", "big"} {
+		if o, r := ob.Order(id), restored.Order(id); o.String() != r.String() || o.Owner() != r.Owner() {
+			t.Fatalf("invalid restored order: %s", r)
+		}
+	}
+
+	// FIFO order of the level
+	if restored.asks.MinPriceQueue().Head().Value.(*Order).ID() != "01-sell-100" {
+		t.Fatal("queue order is not preserved")
+	}
+
+	if orders, _ := restored.CancelAllOwner("alice"); len(orders) != 1 {
+		t.Fatal("owner index is not restored")
+	}
+}
+
+func TestOrderBookBinaryCorrupted(t *testing.T) {
+	ob := NewOrderBook()
+	addDepth(ob, "", decimal.New(1, 0))
+	data, _ := ob.MarshalBinary()
+
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)/2] ^= 0xff
+	if err := NewOrderBook().UnmarshalBinary(corrupted); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatal("corrupted snapshot is loaded", err)
+	}
+
+	versioned := append([]byte{}, data...)
+	versioned[4] = snapshotVersion + 1
+	if err := NewOrderBook().UnmarshalBinary(versioned); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatal("unknown version is loaded", err)
+	}
+
+	huge := NewOrderBook()
+	huge.ProcessLimitOrder(Sell, "huge", decimal.New(1, 0), decimal.New(1, maxExponent+1))
+	data, _ = huge.MarshalBinary()
+	if err := NewOrderBook().UnmarshalBinary(data); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatal("decimal exponent out of range is loaded", err)
+	}
+
+	for _, d := range [][]byte{nil, data[:8], []byte("JSON{}{}{}")} {
+		if err := NewOrderBook().UnmarshalBinary(d); !errors.Is(err, ErrInvalidSnapshot) {
+			t.Fatal("invalid snapshot is loaded", err)
+		}
+	}
+}
+
+func TestQueueBinary(t *testing.T) {
+	oq := NewOrderQueue(decimal.New(100, 0))
+	for i := 0; i < 3; i++ {
+		oq.Append(NewOrder(fmt.Sprint(i), Sell, decimal.New(int64(i+1), 0), oq.Price(), time.Unix(int64(i), 5).UTC()))
+	}
+
+	data, _ := oq.MarshalBinary()
+	restored := &OrderQueue{}
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Len() != 3 || !restored.Volume().Equal(decimal.New(6, 0)) || restored.Tail().Value.(*Order).ID() != "2" ||
+		!restored.Head().Value.(*Order).Time().Equal(time.Unix(0, 5)) {
+		t.Fatalf("invalid restored queue: %s", restored)
+	}
+
+	if err := restored.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Fatal("truncated queue is loaded")
+	}
+
+	side := NewOrderSide()
+	side.Append(NewOrder("b", Buy, decimal.New(1, 0), decimal.New(1, 0), time.Now().UTC()))
+	data, _ = side.MarshalBinary()
+	restoredSide := &OrderSide{}
+	if err := restoredSide.UnmarshalBinary(data); err != nil || restoredSide.Len() != 1 || restoredSide.Depth() != 1 {
+		t.Fatal("invalid restored side", err)
+	}
+
+	o := NewOrderWithOwner("o", "bob", Buy, decimal.New(1, 0), decimal.New(-15, -1), time.Now().UTC())
+	data, _ = o.MarshalBinary()
+	restoredOrder := &Order{}
+	if err := restoredOrder.UnmarshalBinary(data); err != nil || restoredOrder.String() != o.String() || restoredOrder.Owner() != "bob" {
+		t.Fatal("invalid restored order", err)
+	}
+}
+
+func BenchmarkSnapshotBinary(b *testing.B) {
+	ob := NewOrderBook()
+	for i := 0; i < 10000; i++ {
+		ob.ProcessLimitOrder(Buy, fmt.Sprint("b-", i), decimal.New(10, 0), decimal.New(int64(i%100+1), 0))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, _ := ob.MarshalBinary()
+		NewOrderBook().UnmarshalBinary(data)
+	}
+}
+
+func BenchmarkSnapshotJSON(b *testing.B) {
+	ob := NewOrderBook()
+	for i := 0; i < 10000; i++ {
+		ob.ProcessLimitOrder(Buy, fmt.Sprint("b-", i), decimal.New(10, 0), decimal.New(int64(i%100+1), 0))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, _ := json.Marshal(ob)
+		json.Unmarshal(data, NewOrderBook())
+	}
+}
@@ -0,0 +1,107 @@
+package orderbook
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// CancelAll removes all orders from the order book.
+// Orders are cancelled and returned in price-time order, asks first
+func (ob *OrderBook) CancelAll() (orders []*Order, rollback func()) {
+	ids := ob.sideOrderIDs(ob.asks, nil)
+	ids = append(ids, ob.sideOrderIDs(ob.bids, nil)...)
+	return ob.cancelOrders(ids)
+}
+
+// CancelAllSide removes all orders of the given side from the order book.
+// Orders are cancelled and returned in price-time order
+func (ob *OrderBook) CancelAllSide(side Side) (orders []*Order, rollback func()) {
+	if side == Buy {
+		return ob.cancelOrders(ob.sideOrderIDs(ob.bids, nil))
+	}
+	return ob.cancelOrders(ob.sideOrderIDs(ob.asks, nil))
+}
+
+// CancelAllOwner removes all orders of the given account (owner) from the order book.
+// Orders are cancelled and returned in time order
+func (ob *OrderBook) CancelAllOwner(owner string) (orders []*Order, rollback func()) {
+	owned := ob.owners[owner]
+	if len(owner) == 0 || len(owned) == 0 {
+		return
+	}
+
+	owns := make([]*Order, 0, len(owned))
+	for _, e := range owned {
+		owns = append(owns, e.Value.(*Order))
+	}
+	sort.Slice(owns, func(i, j int) bool {
+		if owns[i].Time().Equal(owns[j].Time()) {
+			return owns[i].ID() < owns[j].ID()
+		}
+		return owns[i].Time().Before(owns[j].Time())
+	})
+
+	ids := make([]string, len(owns))
+	for i, o := range owns {
+		ids[i] = o.ID()
+	}
+	return ob.cancelOrders(ids)
+}
+
+// CancelAllOutside removes all orders with price less than low or greater than high
+// from the order book. Orders are cancelled and returned in price-time order, asks first
+func (ob *OrderBook) CancelAllOutside(low, high decimal.Decimal) (orders []*Order, rollback func()) {
+	outside := func(price decimal.Decimal) bool {
+		return price.LessThan(low) || price.GreaterThan(high)
+	}
+	ids := ob.sideOrderIDs(ob.asks, outside)
+	ids = append(ids, ob.sideOrderIDs(ob.bids, outside)...)
+	return ob.cancelOrders(ids)
+}
+
+// sideOrderIDs returns IDs of the side orders in price-time order (from the best price),
+// only price levels accepted by filter are processed if filter is not nil
+func (ob *OrderBook) sideOrderIDs(os *OrderSide, filter func(decimal.Decimal) bool) (ids []string) {
+	level, next := os.MinPriceQueue(), os.GreaterThan
+	if os == ob.bids {
+		level, next = os.MaxPriceQueue(), os.LessThan
+	}
+
+	for level != nil {
+		if filter == nil || filter(level.Price()) {
+			for iter := level.Head(); iter != nil; iter = iter.Next() {
+				ids = append(ids, iter.Value.(*Order).ID())
+			}
+		}
+		level = next(level.Price())
+	}
+	return
+}
+
+// cancelOrders removes orders with given IDs, rollback places them back with their time priority.
+// Nothing is cancelled if cancelling is not allowed in the trading phase
+func (ob *OrderBook) cancelOrders(ids []string) (orders []*Order, rollback func()) {
+	if ob.checkPhase(OpCancel) != nil {
+		return
+	}
+
+	var removed []removedOrder
+	for _, id := range ids {
+		e, ok := ob.orders[id]
+		if !ok {
+			continue
+		}
+		next := ob.nextID(e)
+		o := ob.cancelOrder(id)
+		orders = append(orders, o)
+		removed = append(removed, removedOrder{order: o, next: next})
+	}
+
+	if len(orders) > 0 {
+		rollback = func() {
+			ob.restoreOrders(removed)
+		}
+	}
+	return
+}
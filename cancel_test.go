@@ -0,0 +1,124 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func addOwnerDepth(ob *OrderBook) {
+	ob.ProcessLimitOrderFor("mm", Buy, "mm-b90", decimal.New(1, 0), decimal.New(90, 0))
+	ob.ProcessLimitOrderFor("alice", Buy, "alice-b90", decimal.New(1, 0), decimal.New(90, 0))
+	ob.ProcessLimitOrderFor("mm", Buy, "mm-b80", decimal.New(1, 0), decimal.New(80, 0))
+	ob.ProcessLimitOrderFor("mm", Sell, "mm-s110", decimal.New(1, 0), decimal.New(110, 0))
+	ob.ProcessLimitOrderFor("alice", Sell, "alice-s100", decimal.New(1, 0), decimal.New(100, 0))
+	ob.ProcessLimitOrderFor("alice", Sell, "alice-s130", decimal.New(1, 0), decimal.New(130, 0))
+}
+
+func orderIDs(orders []*Order) (ids []string) {
+	for _, o := range orders {
+		ids = append(ids, o.ID())
+	}
+	return
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCancelAll(t *testing.T) {
+	ob := NewOrderBook()
+	addOwnerDepth(ob)
+
+	orders, rollback := ob.CancelAll()
+	want := []string{"alice-s100", "mm-s110", "alice-s130", "mm-b90", "alice-b90", "mm-b80"}
+	if !equalIDs(orderIDs(orders), want) {
+		t.Fatalf("invalid cancelled orders: %v", orderIDs(orders))
+	}
+
+	if depth := ob.Depth(0); len(depth.Bids) != 0 || len(depth.Asks) != 0 || len(ob.owners) != 0 {
+		t.Fatalf("book is not empty: %v", depth)
+	}
+
+	rollback()
+	if ob.asks.Len() != 3 || ob.bids.Len() != 3 || len(ob.owners["mm"]) != 3 {
+		t.Fatal("rollback is not restored orders")
+	}
+
+	if orders, rollback := NewOrderBook().CancelAll(); orders != nil || rollback != nil {
+		t.Fatal("cancelled orders of empty book")
+	}
+}
+
+func TestCancelAllSide(t *testing.T) {
+	ob := NewOrderBook()
+	addOwnerDepth(ob)
+
+	orders, _ := ob.CancelAllSide(Buy)
+	if !equalIDs(orderIDs(orders), []string{"mm-b90", "alice-b90", "mm-b80"}) || ob.bids.Len() != 0 || ob.asks.Len() != 3 {
+		t.Fatalf("invalid cancelled orders: %v", orderIDs(orders))
+	}
+
+	orders, _ = ob.CancelAllSide(Sell)
+	if !equalIDs(orderIDs(orders), []string{"alice-s100", "mm-s110", "alice-s130"}) || ob.asks.Len() != 0 {
+		t.Fatalf("invalid cancelled orders: %v", orderIDs(orders))
+	}
+}
+
+func TestCancelAllOwner(t *testing.T) {
+	ob := NewOrderBook()
+	addOwnerDepth(ob)
+
+	orders, rollback := ob.CancelAllOwner("mm")
+	if len(orders) != 3 || ob.Order("mm-b90") != nil || ob.Order("mm-b80") != nil || ob.Order("mm-s110") != nil {
+		t.Fatalf("invalid cancelled orders: %v", orderIDs(orders))
+	}
+
+	if ob.Order("alice-b90") == nil || ob.bids.Len() != 1 || ob.asks.Len() != 2 {
+		t.Fatal("cancelled order of another owner")
+	}
+
+	rollback()
+	if ob.Order("mm-b90") == nil || len(ob.owners["mm"]) != 3 {
+		t.Fatal("rollback is not restored orders")
+	}
+	if ob.bids.MaxPriceQueue().Head().Value.(*Order).ID() != "mm-b90" {
+		t.Fatal("rollback is not restored time priority")
+	}
+
+	// owner index follows matching
+	ob.ProcessMarketQuantityOrder(Buy, decimal.New(2, 0))
+	if _, ok := ob.owners["alice"]["alice-s100"]; ok {
+		t.Fatal("done order is still indexed")
+	}
+
+	if orders, _ := ob.CancelAllOwner("nobody"); len(orders) != 0 {
+		t.Fatal("cancelled orders of unknown owner")
+	}
+
+	if orders, _ := ob.CancelAllOwner(""); len(orders) != 0 {
+		t.Fatal("cancelled orders without owner")
+	}
+}
+
+func TestCancelAllOutside(t *testing.T) {
+	ob := NewOrderBook()
+	addOwnerDepth(ob)
+
+	orders, _ := ob.CancelAllOutside(decimal.New(85, 0), decimal.New(120, 0))
+	if !equalIDs(orderIDs(orders), []string{"alice-s130", "mm-b80"}) {
+		t.Fatalf("invalid cancelled orders: %v", orderIDs(orders))
+	}
+
+	if ob.asks.Len() != 2 || ob.bids.Len() != 2 {
+		t.Fatal("cancelled order inside the band")
+	}
+}
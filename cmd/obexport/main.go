@@ -0,0 +1,148 @@
+// Command obexport dumps the book to CSV or Parquet files for analytics tools. The book is loaded
+// from the snapshot and evolved by the replayed JSON lines command logs, then the directory gets
+//
+//	orders.csv  resting orders in price-time order
+//	trades.csv  fills of the replayed commands
+//	depth.csv   depth snapshots every -interval of the command times (if set)
+//
+//	obexport -snapshot start.json -format parquet -interval 1m -out dump/ day.jsonl
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/centny/orderbook/export"
+)
+
+// dump writes files of the book
+type dump struct {
+	book   *orderbook.OrderBook
+	trades export.Writer
+	depth  *export.DepthSampler
+}
+
+func (d *dump) replay(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		cmd := &orderbook.Command{}
+		if err := json.Unmarshal(data, cmd); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if d.depth != nil && !cmd.Time.IsZero() {
+			if err := d.depth.Observe(cmd.Time); err != nil {
+				return err
+			}
+		}
+		if err := export.WriteTrades(d.trades, d.book.Apply(cmd).Fills); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func create(dir, name string, format export.Format, columns []export.Column) (export.Writer, func() error) {
+	ext := ".csv"
+	if format == export.Parquet {
+		ext = ".parquet"
+	}
+	f, err := os.Create(filepath.Join(dir, name+ext))
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := export.NewWriter(f, format, columns)
+	return w, func() error {
+		if err := w.Close(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+}
+
+func main() {
+	snapshot := flag.String("snapshot", "", "JSON or binary snapshot of the book to start from")
+	formatName := flag.String("format", "csv", "file format: csv or parquet")
+	out := flag.String("out", ".", "output directory")
+	interval := flag.Duration("interval", 0, "interval of depth snapshots, no depth file if 0")
+	levels := flag.Int("levels", 10, "number of price levels of depth snapshots, all levels if 0")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [file.jsonl ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	format, err := export.ParseFormat(*formatName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	d := &dump{book: orderbook.NewOrderBook()}
+	if len(*snapshot) > 0 {
+		data, err := os.ReadFile(*snapshot)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if bytes.HasPrefix(data, []byte("OBSN")) {
+			err = d.book.UnmarshalBinary(data)
+		} else {
+			err = json.Unmarshal(data, d.book)
+		}
+		if err != nil {
+			log.Fatalf("%s: %v", *snapshot, err)
+		}
+	}
+
+	var closers []func() error
+	var closeTrades func() error
+	d.trades, closeTrades = create(*out, "trades", format, export.TradeColumns)
+	closers = append(closers, closeTrades)
+	if *interval > 0 {
+		w, closeDepth := create(*out, "depth", format, export.DepthColumns)
+		d.depth = export.NewDepthSampler(w, d.book, *interval, *levels)
+		closers = append(closers, closeDepth)
+	}
+
+	started := time.Now()
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = d.replay(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	orders, closeOrders := create(*out, "orders", format, export.OrderColumns)
+	closers = append(closers, closeOrders)
+	if err := export.WriteOrders(orders, d.book); err != nil {
+		log.Fatal(err)
+	}
+	for _, c := range closers {
+		if err := c(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	log.Printf("exported to %s in %s", *out, time.Since(started))
+}
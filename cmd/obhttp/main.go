@@ -0,0 +1,65 @@
+// Command obhttp serves in-memory OrderBook over HTTP (see package httpapi)
+// and optionally FIX 4.4 order entry (see package fix) and gRPC (see package grpcapi)
+//
+//	obhttp -addr :8080 -snapshot book.json -fix :9878 -fix-comp-id EXCH -symbol BTC-USD -grpc :9090
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/centny/orderbook"
+	"github.com/centny/orderbook/fix"
+	"github.com/centny/orderbook/grpcapi"
+	"github.com/centny/orderbook/httpapi"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	snapshot := flag.String("snapshot", "", "JSON snapshot of the book to start from")
+	fixAddr := flag.String("fix", "", "address to accept FIX sessions on, FIX is disabled if empty")
+	compID := flag.String("fix-comp-id", "EXCH", "FIX CompID of the gateway")
+	symbol := flag.String("symbol", "", "symbol of the book, FIX orders for other symbols are rejected")
+	grpcAddr := flag.String("grpc", "", "address to serve gRPC on, gRPC is disabled if empty")
+	flag.Parse()
+
+	book := orderbook.NewOrderBook()
+	if len(*snapshot) > 0 {
+		data, err := os.ReadFile(*snapshot)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := json.Unmarshal(data, book); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	server := httpapi.NewServer(book)
+	if len(*fixAddr) > 0 {
+		l, err := net.Listen("tcp", *fixAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("obhttp: accepting FIX sessions on %s", *fixAddr)
+		go func() { log.Fatal(fix.NewAcceptor(server, *compID, *symbol).Serve(l)) }()
+	}
+
+	if len(*grpcAddr) > 0 {
+		l, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		srv := grpc.NewServer()
+		grpcapi.RegisterOrderBookServer(srv, grpcapi.NewServer(server))
+		log.Printf("obhttp: serving gRPC on %s", *grpcAddr)
+		go func() { log.Fatal(srv.Serve(l)) }()
+	}
+
+	log.Printf("obhttp: listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
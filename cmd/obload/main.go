@@ -0,0 +1,74 @@
+// Command obload generates synthetic order flow against an in-memory OrderBook and reports
+// throughput and latency percentiles of matching. The generated commands can be written as
+// JSON lines log for cmd/obreplay.
+//
+//	obload -n 1000000 -cancel 0.6 -market 0.05 -size lognormal:2,1 -log flow.jsonl
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/centny/orderbook"
+	"github.com/centny/orderbook/loadgen"
+	"github.com/shopspring/decimal"
+)
+
+func main() {
+	config := loadgen.DefaultConfig()
+	n := flag.Int("n", 100000, "number of commands")
+	flag.Int64Var(&config.Seed, "seed", config.Seed, "random seed")
+	flag.Float64Var(&config.Rate, "rate", config.Rate, "mean arrivals per second")
+	mid := flag.String("mid", config.Mid.String(), "initial mid price")
+	tick := flag.String("tick", config.Tick.String(), "price increment")
+	flag.Float64Var(&config.Volatility, "volatility", config.Volatility, "standard deviation of the mid price step in ticks")
+	flag.Float64Var(&config.Depth, "depth", config.Depth, "mean distance of limit prices from the mid in ticks")
+	flag.Float64Var(&config.CancelRatio, "cancel", config.CancelRatio, "share of cancels among commands")
+	flag.Float64Var(&config.MarketShare, "market", config.MarketShare, "share of market orders among new orders")
+	size := flag.String("size", "lognormal:2,1", "order size distribution: fixed:N, uniform:MIN,MAX, exp:MEAN or lognormal:MU,SIGMA")
+	places := flag.Int("places", int(config.QuantityPlaces), "decimal places of quantities")
+	flag.IntVar(&config.Owners, "owners", config.Owners, "number of owners")
+	paced := flag.Bool("paced", false, "apply commands at their arrival times instead of as fast as possible")
+	logFile := flag.String("log", "", "write generated commands as JSON lines to the file")
+	asJSON := flag.Bool("json", false, "print report as JSON")
+	flag.Parse()
+
+	var err error
+	if config.Mid, err = decimal.NewFromString(*mid); err != nil {
+		log.Fatalf("invalid mid: %v", err)
+	}
+	if config.Tick, err = decimal.NewFromString(*tick); err != nil || config.Tick.Sign() <= 0 {
+		log.Fatalf("invalid tick: %s", *tick)
+	}
+	if config.Size, err = loadgen.ParseDistribution(*size); err != nil {
+		log.Fatal(err)
+	}
+	config.QuantityPlaces = int32(*places)
+
+	g, err := loadgen.NewGenerator(orderbook.NewOrderBook(), config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r := &loadgen.Runner{Generator: g, Paced: *paced}
+	if len(*logFile) > 0 {
+		f, err := os.Create(*logFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		r.Log = f
+	}
+
+	report, err := r.Run(*n)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(report)
+		return
+	}
+	fmt.Print(report)
+}
@@ -0,0 +1,246 @@
+// Command obreplay replays a request log against an OrderBook. The log is JSON lines of
+// orderbook.Command (the format of replication and the HTTP gateway), one command per line.
+//
+//	obreplay -snapshot start.json -expect end.json incident.jsonl
+//
+// Output is JSON lines: {"line":N,"fill":{...}} for every fill, {"line":N,"error":"..."} for every
+// rejected command, then final {"depth":{...}}, {"stats":{...}} and {"diff":[...]} if the expected
+// snapshot is set. Exit code is 1 if the book differs from the expected snapshot.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// replayer applies commands and collects statistics
+type replayer struct {
+	book        *orderbook.OrderBook
+	out         *json.Encoder
+	fills       bool
+	stopOnError bool
+
+	errors    int
+	latencies []time.Duration
+}
+
+type stats struct {
+	Commands  int     `json:"commands"`
+	Errors    int     `json:"errors"`
+	Elapsed   string  `json:"elapsed"`
+	PerSecond float64 `json:"perSecond"`
+	P50       string  `json:"p50"`
+	P99       string  `json:"p99"`
+	Max       string  `json:"max"`
+}
+
+func (r *replayer) replay(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		cmd := &orderbook.Command{}
+		if err := json.Unmarshal(data, cmd); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		started := time.Now()
+		result := r.book.Apply(cmd)
+		r.latencies = append(r.latencies, time.Since(started))
+
+		if result.Err != nil {
+			r.errors++
+			r.out.Encode(map[string]interface{}{"line": line, "seq": cmd.Seq, "error": result.Err.Error()})
+			if r.stopOnError {
+				return fmt.Errorf("line %d: %w", line, result.Err)
+			}
+			continue
+		}
+		if r.fills {
+			for _, f := range result.Fills {
+				r.out.Encode(map[string]interface{}{"line": line, "fill": f})
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func (r *replayer) stats() *stats {
+	s := &stats{Commands: len(r.latencies), Errors: r.errors}
+	if len(r.latencies) == 0 {
+		return s
+	}
+
+	sorted := make([]time.Duration, len(r.latencies))
+	copy(sorted, r.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var elapsed time.Duration
+	for _, d := range sorted {
+		elapsed += d
+	}
+	s.Elapsed = elapsed.String()
+	if elapsed > 0 {
+		s.PerSecond = float64(len(sorted)) / elapsed.Seconds()
+	}
+	s.P50 = sorted[len(sorted)/2].String()
+	s.P99 = sorted[len(sorted)*99/100].String()
+	s.Max = sorted[len(sorted)-1].String()
+	return s
+}
+
+// orders returns resting orders of the book by ID with their priority within the price level
+func orders(book *orderbook.OrderBook) map[string]int {
+	result := map[string]int{}
+	depth := book.Depth(0)
+	rank := func(side orderbook.Side, levels [][]decimal.Decimal) {
+		for _, level := range levels {
+			n := 0
+			for e := book.PriceLevel(side, level[0]).Head(); e != nil; e = e.Next() {
+				n++
+				result[e.Value.(*orderbook.Order).ID()] = n
+			}
+		}
+	}
+	rank(orderbook.Sell, depth.Asks)
+	rank(orderbook.Buy, depth.Bids)
+	return result
+}
+
+// diff returns differences of the actual book from the expected one
+func diff(expected, actual *orderbook.OrderBook) (lines []string) {
+	if expected.StateHash() == actual.StateHash() {
+		return nil
+	}
+
+	want, got := orders(expected), orders(actual)
+	ids := make([]string, 0, len(want)+len(got))
+	for id := range want {
+		ids = append(ids, id)
+	}
+	for id := range got {
+		if _, ok := want[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		w, g := expected.Order(id), actual.Order(id)
+		switch {
+		case g == nil:
+			lines = append(lines, fmt.Sprintf("order %s is missing", id))
+		case w == nil:
+			lines = append(lines, fmt.Sprintf("order %s is unexpected", id))
+		case w.Side() != g.Side():
+			lines = append(lines, fmt.Sprintf("order %s side is %s, expected %s", id, g.Side(), w.Side()))
+		case !w.Price().Equal(g.Price()):
+			lines = append(lines, fmt.Sprintf("order %s price is %s, expected %s", id, g.Price(), w.Price()))
+		case !w.Quantity().Equal(g.Quantity()):
+			lines = append(lines, fmt.Sprintf("order %s quantity is %s, expected %s", id, g.Quantity(), w.Quantity()))
+		case w.Owner() != g.Owner():
+			lines = append(lines, fmt.Sprintf("order %s owner is %q, expected %q", id, g.Owner(), w.Owner()))
+		case want[id] != got[id]:
+			lines = append(lines, fmt.Sprintf("order %s queue position is %d, expected %d", id, got[id], want[id]))
+		case !w.Time().Equal(g.Time()):
+			lines = append(lines, fmt.Sprintf("order %s time is %s, expected %s", id, g.Time(), w.Time()))
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "state hash differs")
+	}
+	return lines
+}
+
+// load reads JSON or binary snapshot of the book
+func load(name string) (*orderbook.OrderBook, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	book := orderbook.NewOrderBook()
+	if bytes.HasPrefix(data, []byte("OBSN")) {
+		err = book.UnmarshalBinary(data)
+	} else {
+		err = json.Unmarshal(data, book)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return book, nil
+}
+
+func main() {
+	snapshot := flag.String("snapshot", "", "JSON or binary snapshot of the book to start from")
+	expect := flag.String("expect", "", "JSON or binary snapshot of the expected book")
+	fills := flag.Bool("fills", true, "print fills")
+	depth := flag.Int("depth", 0, "number of price levels to print, all levels if 0")
+	stopOnError := flag.Bool("stop-on-error", false, "stop on the first rejected command")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [file.jsonl ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	r := &replayer{
+		book:        orderbook.NewOrderBook(),
+		out:         json.NewEncoder(os.Stdout),
+		fills:       *fills,
+		stopOnError: *stopOnError,
+	}
+	if len(*snapshot) > 0 {
+		book, err := load(*snapshot)
+		if err != nil {
+			log.Fatal(err)
+		}
+		r.book = book
+	}
+
+	if flag.NArg() == 0 {
+		if err := r.replay(os.Stdin); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = r.replay(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	r.out.Encode(map[string]interface{}{"depth": r.book.Depth(*depth)})
+	r.out.Encode(map[string]interface{}{"stats": r.stats()})
+
+	if len(*expect) > 0 {
+		expected, err := load(*expect)
+		if err != nil {
+			log.Fatal(err)
+		}
+		lines := diff(expected, r.book)
+		r.out.Encode(map[string]interface{}{"diff": lines})
+		if len(lines) > 0 {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+const requestLog = `
+{"seq":1,"type":"limit","time":"2021-01-01T10:00:00Z","id":"s-100","owner":"mm","side":"sell","quantity":"2","price":"100"}
+{"seq":2,"type":"limit","time":"2021-01-01T10:00:01Z","id":"s-110","owner":"mm","side":"sell","quantity":"2","price":"110"}
+{"seq":3,"type":"market","time":"2021-01-01T10:00:02Z","owner":"alice","side":"buy","quantity":"3"}
+
+{"seq":4,"type":"cancel","time":"2021-01-01T10:00:03Z","id":"none"}
+{"seq":5,"type":"limit","time":"2021-01-01T10:00:04Z","id":"b-90","owner":"alice","side":"buy","quantity":"1","price":"90"}
+`
+
+func TestReplay(t *testing.T) {
+	var out bytes.Buffer
+	r := &replayer{book: orderbook.NewOrderBook(), out: json.NewEncoder(&out), fills: true}
+	if err := r.replay(strings.NewReader(requestLog)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"line":4`) || !strings.Contains(lines[2], `"error":"orderbook: order does not exist"`) {
+		t.Fatalf("invalid output:\n%s", out.String())
+	}
+
+	s := r.stats()
+	if s.Commands != 5 || s.Errors != 1 || len(s.Max) == 0 {
+		t.Fatalf("invalid stats: %+v", s)
+	}
+
+	r.stopOnError = true
+	if err := r.replay(strings.NewReader(`{"type":"cancel","id":"none"}`)); err == nil {
+		t.Fatal("replay is not stopped on error")
+	}
+	if err := r.replay(strings.NewReader(`{"type":"unknown"}`)); err == nil {
+		t.Fatal("invalid line is replayed")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	r := &replayer{book: orderbook.NewOrderBook(), out: json.NewEncoder(&bytes.Buffer{})}
+	if err := r.replay(strings.NewReader(requestLog)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := json.Marshal(r.book)
+	expected := orderbook.NewOrderBook()
+	if err := json.Unmarshal(data, expected); err != nil {
+		t.Fatal(err)
+	}
+	if lines := diff(expected, r.book); lines != nil {
+		t.Fatalf("equal books differ: %v", lines)
+	}
+
+	expected.ProcessLimitOrder(orderbook.Buy, "b-80", decimal.New(1, 0), decimal.New(80, 0))
+	expected.CancelOrder("s-110")
+	expected.ProcessLimitOrder(orderbook.Sell, "s-110", decimal.New(5, 0), decimal.New(110, 0))
+	lines := diff(expected, r.book)
+	want := []string{"order b-80 is missing", "order s-110 quantity is 1, expected 5"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("invalid diff: %v", lines)
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+const help = `commands:
+  buy QTY [PRICE [ID]]     market (without price) or limit buy order
+  sell QTY [PRICE [ID]]    market (without price) or limit sell order
+  cancel ID                cancel order
+  replace ID QTY PRICE     amend order
+  cancel-all [OWNER]       cancel all orders (of the owner)
+  phase NAME               set trading phase: open, pre-open, halted, closed
+  load FILE                replay JSON lines command log
+  levels N                 number of price levels to show
+  help                     show this help
+  quit                     exit`
+
+// ANSI escape sequences
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	red         = "\x1b[31m"
+	green       = "\x1b[32m"
+	dim         = "\x1b[2m"
+	reset       = "\x1b[0m"
+)
+
+// console applies commands typed by the user to the book and renders the ladder
+type console struct {
+	book   *orderbook.OrderBook
+	tape   *orderbook.TradeTape
+	owner  string
+	levels int
+	color  bool
+
+	nextID int
+	status string
+}
+
+func newConsole(book *orderbook.OrderBook, color bool) *console {
+	return &console{
+		book:   book,
+		tape:   orderbook.NewTradeTape(10),
+		owner:  "console",
+		levels: 10,
+		color:  color,
+	}
+}
+
+func (c *console) paint(code, s string) string {
+	if !c.color {
+		return s
+	}
+	return code + s + reset
+}
+
+// apply applies the command and keeps its fills and summary
+func (c *console) apply(cmd *orderbook.Command) *orderbook.Result {
+	result := c.book.Apply(cmd)
+	for _, f := range result.Fills {
+		c.tape.Add(f)
+	}
+
+	switch {
+	case result.Err != nil:
+		c.status = c.paint(red, "error: "+result.Err.Error())
+	case len(result.Fills) > 0:
+		traded := decimal.Zero
+		for _, f := range result.Fills {
+			traded = traded.Add(f.Quantity)
+		}
+		c.status = fmt.Sprintf("%s: %d fills, %s traded", cmd.Type, len(result.Fills), traded)
+	case len(result.Cancelled) > 0:
+		c.status = fmt.Sprintf("%s: %d orders", cmd.Type, len(result.Cancelled))
+	default:
+		c.status = fmt.Sprintf("%s: ok", cmd.Type)
+	}
+	return result
+}
+
+// id returns the next unused order ID
+func (c *console) id() string {
+	for {
+		c.nextID++
+		id := "c" + strconv.Itoa(c.nextID)
+		if c.book.Order(id) == nil {
+			return id
+		}
+	}
+}
+
+// exec executes the command line, quit is true on quit command
+func (c *console) exec(line string) (quit bool) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return false
+	}
+
+	if err := c.run(args); err != nil {
+		c.status = c.paint(red, err.Error())
+	}
+	return args[0] == "quit" || args[0] == "exit"
+}
+
+func (c *console) run(args []string) error {
+	decimalArg := func(i int, name string) (decimal.Decimal, error) {
+		if i >= len(args) {
+			return decimal.Zero, fmt.Errorf("%s is missing", name)
+		}
+		d, err := decimal.NewFromString(args[i])
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("invalid %s: %s", name, args[i])
+		}
+		return d, nil
+	}
+
+	switch args[0] {
+	case "buy", "sell":
+		cmd := &orderbook.Command{Owner: c.owner, Side: orderbook.Buy, Type: orderbook.CommandMarket}
+		if args[0] == "sell" {
+			cmd.Side = orderbook.Sell
+		}
+		var err error
+		if cmd.Quantity, err = decimalArg(1, "quantity"); err != nil {
+			return err
+		}
+		if len(args) > 2 {
+			cmd.Type = orderbook.CommandLimit
+			if cmd.Price, err = decimalArg(2, "price"); err != nil {
+				return err
+			}
+			if len(args) > 3 {
+				cmd.ID = args[3]
+			} else {
+				cmd.ID = c.id()
+			}
+		}
+		c.apply(cmd)
+	case "cancel":
+		if len(args) < 2 {
+			return fmt.Errorf("order ID is missing")
+		}
+		c.apply(&orderbook.Command{Type: orderbook.CommandCancel, ID: args[1]})
+	case "replace":
+		if len(args) < 2 {
+			return fmt.Errorf("order ID is missing")
+		}
+		cmd := &orderbook.Command{Type: orderbook.CommandReplace, ID: args[1]}
+		var err error
+		if cmd.Quantity, err = decimalArg(2, "quantity"); err != nil {
+			return err
+		}
+		if cmd.Price, err = decimalArg(3, "price"); err != nil {
+			return err
+		}
+		c.apply(cmd)
+	case "cancel-all":
+		cmd := &orderbook.Command{Type: orderbook.CommandCancelAll}
+		if len(args) > 1 {
+			cmd.Owner = args[1]
+		}
+		c.apply(cmd)
+	case "phase":
+		if len(args) < 2 {
+			return fmt.Errorf("phase is missing")
+		}
+		var phase orderbook.Phase
+		if err := json.Unmarshal([]byte(strconv.Quote(args[1])), &phase); err != nil {
+			return fmt.Errorf("unknown phase: %s", args[1])
+		}
+		c.apply(&orderbook.Command{Type: orderbook.CommandPhase, Phase: phase})
+	case "load":
+		if len(args) < 2 {
+			return fmt.Errorf("file is missing")
+		}
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		n, err := c.replay(f, nil, 0)
+		if err != nil {
+			return err
+		}
+		c.status = fmt.Sprintf("load: %d commands", n)
+	case "levels":
+		n, err := strconv.Atoi(strings.Join(args[1:], ""))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of levels")
+		}
+		c.levels = n
+	case "help":
+		c.status = help
+	case "quit", "exit":
+	default:
+		return fmt.Errorf("unknown command %q, type help", args[0])
+	}
+	return nil
+}
+
+// replay applies JSON lines commands, the book is rendered to w after each command if w is set
+func (c *console) replay(r io.Reader, w io.Writer, delay time.Duration) (n int, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		cmd := &orderbook.Command{}
+		if err := json.Unmarshal([]byte(line), cmd); err != nil {
+			return n, fmt.Errorf("command %d: %w", n+1, err)
+		}
+		c.apply(cmd)
+		n++
+		if w != nil {
+			c.render(w)
+			time.Sleep(delay)
+		}
+	}
+	return n, scanner.Err()
+}
+
+// render draws the ladder (asks above bids), recent trades and the status line
+func (c *console) render(w io.Writer) {
+	var sb strings.Builder
+	if c.color {
+		sb.WriteString(clearScreen)
+	}
+
+	depth := c.book.Depth(c.levels)
+	max := decimal.Zero
+	for _, level := range append(depth.Asks, depth.Bids...) {
+		if level[1].GreaterThan(max) {
+			max = level[1]
+		}
+	}
+	bar := func(volume decimal.Decimal) string {
+		if max.Sign() == 0 {
+			return ""
+		}
+		return strings.Repeat("#", int(volume.Mul(decimal.New(30, 0)).Div(max).Ceil().IntPart()))
+	}
+	row := func(code string, side orderbook.Side, level []decimal.Decimal) {
+		orders := c.book.PriceLevel(side, level[0]).Len()
+		line := fmt.Sprintf("%14s %14s %6d  %s", level[0], level[1], orders, bar(level[1]))
+		sb.WriteString(c.paint(code, line) + "\n")
+	}
+
+	fmt.Fprintf(&sb, "%14s %14s %6s  phase: %s\n", "PRICE", "VOLUME", "ORDERS", c.book.Phase())
+	for i := len(depth.Asks) - 1; i >= 0; i-- {
+		row(red, orderbook.Sell, depth.Asks[i])
+	}
+	if spread, err := c.book.Spread(); err == nil {
+		mid, _ := c.book.MidPrice()
+		sb.WriteString(c.paint(dim, fmt.Sprintf("%14s spread %s, mid %s", "", spread, mid)) + "\n")
+	} else {
+		sb.WriteString(c.paint(dim, fmt.Sprintf("%14s %s", "", err)) + "\n")
+	}
+	for _, level := range depth.Bids {
+		row(green, orderbook.Buy, level)
+	}
+
+	sb.WriteString("\nrecent trades:\n")
+	trades := c.tape.Trades()
+	for i := len(trades) - 1; i >= 0; i-- {
+		f := trades[i]
+		code := green
+		if f.Side == orderbook.Sell {
+			code = red
+		}
+		line := fmt.Sprintf("  %s %4s %14s @ %s  %s <- %s", f.Time.Format("15:04:05.000"), f.Side, f.Quantity, f.Price, f.MakerID, f.TakerID)
+		sb.WriteString(c.paint(code, line) + "\n")
+	}
+
+	if len(c.status) > 0 {
+		sb.WriteString("\n" + c.status + "\n")
+	}
+	sb.WriteString("> ")
+	io.WriteString(w, sb.String())
+}
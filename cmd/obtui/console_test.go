@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+func TestConsoleExec(t *testing.T) {
+	c := newConsole(orderbook.NewOrderBook(), false)
+	for _, line := range []string{"sell 2 100 s1", "sell 1 101", "buy 3 99", ""} {
+		if c.exec(line) {
+			t.Fatalf("%q must not quit", line)
+		}
+	}
+
+	if o := c.book.Order("s1"); o == nil || !o.Quantity().Equal(decimal.New(2, 0)) {
+		t.Fatal("s1 must rest with quantity 2")
+	}
+	if c.book.Order("c1") == nil || c.book.Order("c2") == nil {
+		t.Fatal("orders without ID must get console IDs")
+	}
+	if o := c.book.Order("c1"); o.Owner() != "console" {
+		t.Fatalf("owner %q", o.Owner())
+	}
+
+	c.exec("buy 1")
+	if c.status != "market: 1 fills, 1 traded" || len(c.tape.Trades()) != 1 {
+		t.Fatalf("status %q", c.status)
+	}
+
+	c.exec("replace c2 2 98")
+	if o := c.book.Order("c2"); o == nil || !o.Price().Equal(decimal.New(98, 0)) {
+		t.Fatal("c2 must be replaced")
+	}
+
+	c.exec("cancel s1")
+	if c.book.Order("s1") != nil || c.status != "cancel: 1 orders" {
+		t.Fatalf("s1 must be cancelled, status %q", c.status)
+	}
+
+	c.exec("phase halted")
+	if c.book.Phase() != orderbook.Halted {
+		t.Fatalf("phase %s", c.book.Phase())
+	}
+	c.exec("buy 1 90")
+	if !strings.HasPrefix(c.status, "error: ") {
+		t.Fatalf("status %q", c.status)
+	}
+
+	for line, status := range map[string]string{
+		"buy":          "quantity is missing",
+		"sell 1 x":     "invalid price: x",
+		"cancel":       "order ID is missing",
+		"replace c2 1": "price is missing",
+		"phase bogus":  "unknown phase: bogus",
+		"levels 0":     "invalid number of levels",
+		"bogus":        `unknown command "bogus", type help`,
+	} {
+		c.exec(line)
+		if c.status != status {
+			t.Errorf("%q: status %q, expected %q", line, c.status, status)
+		}
+	}
+
+	if !c.exec("quit") {
+		t.Fatal("quit must quit")
+	}
+}
+
+func TestConsoleReplay(t *testing.T) {
+	c := newConsole(orderbook.NewOrderBook(), false)
+	log := `{"type":"limit","id":"a","side":"sell","quantity":"1","price":"10"}
+
+{"type":"market","side":"buy","quantity":"1"}
+`
+	var out strings.Builder
+	n, err := c.replay(strings.NewReader(log), &out, 0)
+	if err != nil || n != 2 {
+		t.Fatalf("replayed %d commands, error %v", n, err)
+	}
+	if strings.Count(out.String(), "> ") != 2 || len(c.tape.Trades()) != 1 {
+		t.Fatalf("book must be rendered after every command:\n%s", out.String())
+	}
+
+	if _, err := c.replay(strings.NewReader("{"), nil, 0); err == nil {
+		t.Fatal("invalid command must fail")
+	}
+}
+
+func TestConsoleRender(t *testing.T) {
+	c := newConsole(orderbook.NewOrderBook(), false)
+	c.exec("sell 1 101")
+	c.exec("sell 2 102")
+	c.exec("buy 4 99")
+	c.exec("buy 1 101")
+
+	var out strings.Builder
+	c.render(&out)
+	lines := strings.Split(out.String(), "\n")
+
+	expected := []string{
+		"PRICE",
+		"102              2      1  ###############",
+		"spread 3, mid 100.5",
+		"99              4      1  ##############################",
+		"",
+		"recent trades:",
+		"buy              1 @ 101  c1 <- c4",
+	}
+	for i, s := range expected {
+		if !strings.Contains(lines[i], s) {
+			t.Errorf("line %d %q, expected %q", i, lines[i], s)
+		}
+	}
+	if strings.Contains(out.String(), "\x1b[") {
+		t.Fatal("colors must be disabled")
+	}
+
+	c.color = true
+	out.Reset()
+	c.render(&out)
+	if !strings.HasPrefix(out.String(), clearScreen) || !strings.Contains(out.String(), red) || !strings.Contains(out.String(), green) {
+		t.Fatal("colored output is expected")
+	}
+}
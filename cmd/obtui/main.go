@@ -0,0 +1,71 @@
+// Command obtui is an interactive terminal console of an in-memory OrderBook: it renders the
+// colored two-sided ladder with recent trades and applies commands typed by the user
+// (type help for the list). The book can be started from a snapshot and a replayed log,
+// the replay is animated with -delay.
+//
+//	obtui -snapshot book.json -replay incident.jsonl -delay 200ms
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/centny/orderbook"
+)
+
+func main() {
+	snapshot := flag.String("snapshot", "", "JSON or binary snapshot of the book to start from")
+	replay := flag.String("replay", "", "JSON lines command log to replay before the console")
+	delay := flag.Duration("delay", 0, "delay between replayed commands, the book is rendered after each of them if set")
+	levels := flag.Int("levels", 10, "number of price levels to show")
+	noColor := flag.Bool("no-color", false, "disable colors and screen clearing")
+	flag.Parse()
+
+	book := orderbook.NewOrderBook()
+	if len(*snapshot) > 0 {
+		data, err := os.ReadFile(*snapshot)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if bytes.HasPrefix(data, []byte("OBSN")) {
+			err = book.UnmarshalBinary(data)
+		} else {
+			err = json.Unmarshal(data, book)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	c := newConsole(book, !*noColor)
+	c.levels = *levels
+	if len(*replay) > 0 {
+		f, err := os.Open(*replay)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var out io.Writer
+		if *delay > 0 {
+			out = os.Stdout
+		}
+		_, err = c.replay(f, out, *delay)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	c.render(os.Stdout)
+	input := bufio.NewScanner(os.Stdin)
+	for input.Scan() {
+		if c.exec(input.Text()) {
+			return
+		}
+		c.render(os.Stdout)
+	}
+}
@@ -0,0 +1,160 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CommandType is the operation of the Command
+type CommandType int
+
+// Commands applied to the OrderBook
+const (
+	CommandLimit       CommandType = iota // ProcessLimitOrderFor(Owner, Side, ID, Quantity, Price)
+	CommandMarket                         // ProcessMarketQuantityOrderFor(Owner, Side, Quantity)
+	CommandMarketPrice                    // ProcessMarketPriceBuyFor(Owner, Price, Places)
+	CommandCancel                         // CancelOrder(ID)
+	CommandCancelAll                      // CancelAllOwner(Owner) or CancelAll() if Owner is empty
+	CommandPhase                          // SetPhase(Phase)
+	CommandReplace                        // ReplaceOrder(ID, Quantity, Price)
+)
+
+var commandTypes = []string{"limit", "market", "market-price", "cancel", "cancel-all", "phase", "replace"}
+
+// String implements fmt.Stringer interface
+func (ct CommandType) String() string {
+	if ct < 0 || int(ct) >= len(commandTypes) {
+		return "unknown"
+	}
+	return commandTypes[ct]
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (ct CommandType) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + ct.String() + `"`), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (ct *CommandType) UnmarshalJSON(data []byte) error {
+	for i, name := range commandTypes {
+		if string(data) == `"`+name+`"` {
+			*ct = CommandType(i)
+			return nil
+		}
+	}
+
+	return &json.UnsupportedValueError{
+		Value: reflect.New(reflect.TypeOf(data)),
+		Str:   string(data),
+	}
+}
+
+// Command stores serializable request to the OrderBook. Commands with the same time
+// applied in the same order to books with the same state produce the same results,
+// so command log can be replayed or replicated
+type Command struct {
+	Seq      uint64          `json:"seq,omitempty"`
+	Type     CommandType     `json:"type"`
+	Time     time.Time       `json:"time"` // book clock is used if time is zero
+	ID       string          `json:"id,omitempty"`
+	Owner    string          `json:"owner,omitempty"`
+	Side     Side            `json:"side"`
+	Quantity decimal.Decimal `json:"quantity"`
+	Price    decimal.Decimal `json:"price"` // funds for CommandMarketPrice
+	Places   int32           `json:"places,omitempty"`
+	Phase    Phase           `json:"phase"`
+}
+
+// Result stores results of the applied Command
+type Result struct {
+	Done                     []*Order
+	Partial                  *Order
+	PartialQuantityProcessed decimal.Decimal
+	Left                     decimal.Decimal // quantity (or funds) left of market order
+	Cancelled                []*Order
+	Fills                    []*Fill
+	Rollback                 func()
+	Err                      error
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (r *Result) MarshalJSON() ([]byte, error) {
+	obj := struct {
+		Done                     []*Order        `json:"done,omitempty"`
+		Partial                  *Order          `json:"partial,omitempty"`
+		PartialQuantityProcessed decimal.Decimal `json:"partialQuantityProcessed"`
+		Left                     decimal.Decimal `json:"left"`
+		Cancelled                []*Order        `json:"cancelled,omitempty"`
+		Fills                    []*Fill         `json:"fills,omitempty"`
+		Error                    string          `json:"error,omitempty"`
+	}{
+		Done:                     r.Done,
+		Partial:                  r.Partial,
+		PartialQuantityProcessed: r.PartialQuantityProcessed,
+		Left:                     r.Left,
+		Cancelled:                r.Cancelled,
+		Fills:                    r.Fills,
+	}
+	if r.Err != nil {
+		obj.Error = r.Err.Error()
+	}
+	return json.Marshal(&obj)
+}
+
+// Apply applies the command to the book, time of the command is used as the book clock
+func (ob *OrderBook) Apply(cmd *Command) (result *Result) {
+	result = &Result{
+		PartialQuantityProcessed: decimal.Zero,
+		Left:                     decimal.Zero,
+	}
+
+	ob.at = cmd.Time
+	defer func() { ob.at = time.Time{} }()
+
+	switch cmd.Type {
+	case CommandLimit:
+		result.Done, result.Partial, result.PartialQuantityProcessed, result.Rollback, result.Err =
+			ob.ProcessLimitOrderFor(cmd.Owner, cmd.Side, cmd.ID, cmd.Quantity, cmd.Price)
+		result.Fills = ob.Fills()
+	case CommandMarket:
+		result.Done, result.Partial, result.PartialQuantityProcessed, result.Left, result.Rollback, result.Err =
+			ob.ProcessMarketQuantityOrderFor(cmd.Owner, cmd.Side, cmd.Quantity)
+		result.Fills = ob.Fills()
+	case CommandMarketPrice:
+		result.Done, result.Partial, result.PartialQuantityProcessed, result.Left, result.Rollback, result.Err =
+			ob.ProcessMarketPriceBuyFor(cmd.Owner, cmd.Price, cmd.Places)
+		result.Fills = ob.Fills()
+	case CommandCancel:
+		var order *Order
+		if order, result.Rollback = ob.CancelOrder(cmd.ID); order != nil {
+			result.Cancelled = []*Order{order}
+		} else if result.Err = ob.checkPhase(OpCancel); result.Err == nil {
+			result.Err = ErrOrderNotExists
+		}
+	case CommandCancelAll:
+		if result.Err = ob.checkPhase(OpCancel); result.Err != nil {
+			break
+		}
+		if len(cmd.Owner) > 0 {
+			result.Cancelled, result.Rollback = ob.CancelAllOwner(cmd.Owner)
+		} else {
+			result.Cancelled, result.Rollback = ob.CancelAll()
+		}
+	case CommandPhase:
+		ob.SetPhase(cmd.Phase)
+	case CommandReplace:
+		order := ob.Order(cmd.ID)
+		result.Done, result.Partial, result.PartialQuantityProcessed, result.Rollback, result.Err =
+			ob.ReplaceOrder(cmd.ID, cmd.Quantity, cmd.Price)
+		result.Fills = ob.Fills()
+		if result.Err == nil {
+			result.Cancelled = []*Order{order}
+		}
+	default:
+		result.Err = ErrUnknownCommand
+	}
+	return
+}
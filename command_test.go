@@ -0,0 +1,95 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestApplyCommands(t *testing.T) {
+	at := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	ob := NewOrderBook()
+	addDepth(ob, "", decimal.New(2, 0))
+
+	result := ob.Apply(&Command{Type: CommandLimit, Time: at, ID: "b-100", Owner: "alice", Side: Buy, Quantity: decimal.New(3, 0), Price: decimal.New(100, 0)})
+	if result.Err != nil || len(result.Fills) != 1 || !result.PartialQuantityProcessed.Equal(decimal.New(2, 0)) {
+		t.Fatalf("invalid limit result: %+v", result)
+	}
+
+	if o := ob.Order("b-100"); o == nil || !o.Time().Equal(at) || o.Owner() != "alice" {
+		t.Fatal("command time is not used")
+	}
+
+	result = ob.Apply(&Command{Type: CommandMarket, Side: Sell, Quantity: decimal.New(2, 0)})
+	if result.Err != nil || len(result.Done) != 1 || !result.Left.IsZero() {
+		t.Fatalf("invalid market result: %+v", result)
+	}
+
+	result = ob.Apply(&Command{Type: CommandMarketPrice, Price: decimal.New(110, 0), Places: 8})
+	if result.Err != nil || len(result.Fills) != 1 || !result.Fills[0].Quantity.Equal(decimal.New(1, 0)) {
+		t.Fatalf("invalid market price result: %+v", result)
+	}
+
+	if result = ob.Apply(&Command{Type: CommandCancel, ID: "sell-120"}); len(result.Cancelled) != 1 {
+		t.Fatalf("invalid cancel result: %+v", result)
+	}
+
+	if result = ob.Apply(&Command{Type: CommandCancel, ID: "sell-120"}); result.Err != ErrOrderNotExists {
+		t.Fatalf("invalid cancel result: %+v", result)
+	}
+
+	ob.Apply(&Command{Type: CommandPhase, Phase: Halted})
+	if ob.Phase() != Halted {
+		t.Fatal("phase is not changed")
+	}
+
+	if result = ob.Apply(&Command{Type: CommandCancelAll}); len(result.Cancelled) != 8 {
+		t.Fatalf("invalid cancel all result: %+v", result)
+	}
+
+	if result = ob.Apply(&Command{Type: CommandType(100)}); result.Err != ErrUnknownCommand {
+		t.Fatal("unknown command is applied")
+	}
+
+	data, _ := json.Marshal(result)
+	if !strings.Contains(string(data), ErrUnknownCommand.Error()) {
+		t.Fatalf("error is not marshaled: %s", data)
+	}
+}
+
+func TestCommandJSON(t *testing.T) {
+	cmd := &Command{Seq: 7, Type: CommandMarketPrice, Owner: "bob", Price: decimal.New(5, 0), Places: 2, Phase: PreOpen}
+	data, _ := json.Marshal(cmd)
+
+	restored := &Command{}
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Seq != 7 || restored.Type != CommandMarketPrice || restored.Owner != "bob" || restored.Places != 2 || restored.Phase != PreOpen {
+		t.Fatalf("invalid restored command: %s", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"type":"fake"}`), restored); err == nil {
+		t.Fatal("can unmarshal unsupported value")
+	}
+}
+
+func TestApplyReplace(t *testing.T) {
+	ob := NewOrderBook()
+	addOwnerDepth(ob)
+
+	result := ob.Apply(&Command{Type: CommandReplace, ID: "mm-b80", Quantity: decimal.New(1, 0), Price: decimal.New(100, 0)})
+	if result.Err != nil || len(result.Cancelled) != 1 || !result.Cancelled[0].Price().Equal(decimal.New(80, 0)) ||
+		len(result.Fills) != 1 || ob.Order("mm-b80") != nil {
+		t.Fatalf("invalid result: %+v", result)
+	}
+
+	result = ob.Apply(&Command{Type: CommandReplace, ID: "mm-b80", Quantity: decimal.New(1, 0), Price: decimal.New(100, 0)})
+	if result.Err != ErrOrderNotExists || result.Cancelled != nil {
+		t.Fatalf("invalid result: %+v", result)
+	}
+}
@@ -0,0 +1,72 @@
+package orderbook
+
+import (
+	"encoding/json"
+
+	"github.com/shopspring/decimal"
+)
+
+// DepthLevel stores aggregated price level
+type DepthLevel struct {
+	Price      decimal.Decimal `json:"price"`
+	Volume     decimal.Decimal `json:"volume"`
+	Cumulative decimal.Decimal `json:"cumulative"` // volume from the best price up to the level
+}
+
+// AggregatedDepth stores price levels grouped to price buckets
+type AggregatedDepth struct {
+	Step decimal.Decimal `json:"step"`
+	Bids []DepthLevel    `json:"bids"`
+	Asks []DepthLevel    `json:"asks"`
+}
+
+func (d *AggregatedDepth) String() string {
+	data, _ := json.Marshal(d)
+	return string(data)
+}
+
+// AggregatedDepth returns price levels grouped to buckets of the step size (e.g. 0.01, 0.1, 1, 10).
+// Bid prices are rounded down and ask prices are rounded up to the step, so bucket price is never
+// better than prices of the orders in it. max limits amount of buckets on each side, 0 means no limit
+func (ob *OrderBook) AggregatedDepth(step decimal.Decimal, max int) (depth *AggregatedDepth, err error) {
+	if step.Sign() <= 0 {
+		return nil, ErrInvalidStep
+	}
+
+	depth = &AggregatedDepth{Step: step}
+	depth.Asks = aggregateLevels(ob.asks.MinPriceQueue(), ob.asks.GreaterThan, func(price decimal.Decimal) decimal.Decimal {
+		return price.Div(step).Ceil().Mul(step)
+	}, max)
+	depth.Bids = aggregateLevels(ob.bids.MaxPriceQueue(), ob.bids.LessThan, func(price decimal.Decimal) decimal.Decimal {
+		return price.Div(step).Floor().Mul(step)
+	}, max)
+	return
+}
+
+func aggregateLevels(level *OrderQueue, next func(decimal.Decimal) *OrderQueue, bucket func(decimal.Decimal) decimal.Decimal, max int) (levels []DepthLevel) {
+	cumulative := decimal.Zero
+	for level != nil {
+		price := bucket(level.Price())
+		if n := len(levels); n == 0 || !levels[n-1].Price.Equal(price) {
+			if max > 0 && n >= max {
+				break
+			}
+			levels = append(levels, DepthLevel{Price: price, Volume: decimal.Zero})
+		}
+
+		last := &levels[len(levels)-1]
+		cumulative = cumulative.Add(level.Volume())
+		last.Volume = last.Volume.Add(level.Volume())
+		last.Cumulative = cumulative
+		level = next(level.Price())
+	}
+	return
+}
+
+// PriceLevel returns price level of the side with the given price, nil if there are no orders
+func (ob *OrderBook) PriceLevel(side Side, price decimal.Decimal) *OrderQueue {
+	if side == Buy {
+		return ob.bids.prices[price.String()]
+	}
+	return ob.asks.prices[price.String()]
+}
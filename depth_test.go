@@ -0,0 +1,63 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestAggregatedDepth(t *testing.T) {
+	ob := NewOrderBook()
+	ob.ProcessLimitOrder(Buy, "b-1", decimal.New(1, 0), decimal.RequireFromString("99.95"))
+	ob.ProcessLimitOrder(Buy, "b-2", decimal.New(2, 0), decimal.RequireFromString("99.5"))
+	ob.ProcessLimitOrder(Buy, "b-3", decimal.New(3, 0), decimal.RequireFromString("98.1"))
+	ob.ProcessLimitOrder(Sell, "s-1", decimal.New(1, 0), decimal.RequireFromString("100.05"))
+	ob.ProcessLimitOrder(Sell, "s-2", decimal.New(2, 0), decimal.RequireFromString("100.5"))
+	ob.ProcessLimitOrder(Sell, "s-3", decimal.New(3, 0), decimal.RequireFromString("102"))
+
+	depth, err := ob.AggregatedDepth(decimal.New(1, 0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(levels []DepthLevel, want [][3]string) {
+		t.Helper()
+		if len(levels) != len(want) {
+			t.Fatalf("invalid levels: %s", depth)
+		}
+		for i, l := range levels {
+			if l.Price.String() != want[i][0] || l.Volume.String() != want[i][1] || l.Cumulative.String() != want[i][2] {
+				t.Fatalf("invalid level %d: %s", i, depth)
+			}
+		}
+	}
+
+	check(depth.Bids, [][3]string{{"99", "3", "3"}, {"98", "3", "6"}})
+	check(depth.Asks, [][3]string{{"101", "3", "3"}, {"102", "3", "6"}})
+
+	depth, _ = ob.AggregatedDepth(decimal.New(1, 1), 1)
+	check(depth.Bids, [][3]string{{"90", "6", "6"}})
+	check(depth.Asks, [][3]string{{"110", "6", "6"}})
+
+	depth, _ = ob.AggregatedDepth(decimal.New(1, -1), 2)
+	check(depth.Bids, [][3]string{{"99.9", "1", "1"}, {"99.5", "2", "3"}})
+	check(depth.Asks, [][3]string{{"100.1", "1", "1"}, {"100.5", "2", "3"}})
+
+	if _, err := ob.AggregatedDepth(decimal.Zero, 0); err != ErrInvalidStep {
+		t.Fatal("can aggregate with zero step")
+	}
+}
+
+func TestPriceLevel(t *testing.T) {
+	ob := NewOrderBook()
+	addDepth(ob, "", decimal.New(2, 0))
+	ob.ProcessLimitOrder(Buy, "b-90", decimal.New(1, 0), decimal.RequireFromString("90.0"))
+
+	if level := ob.PriceLevel(Buy, decimal.New(90, 0)); level == nil || level.Len() != 2 || !level.Volume().Equal(decimal.New(3, 0)) {
+		t.Fatalf("invalid level: %s", level)
+	}
+
+	if ob.PriceLevel(Sell, decimal.New(90, 0)) != nil || ob.PriceLevel(Buy, decimal.New(95, 0)) != nil {
+		t.Fatal("can get empty level")
+	}
+}
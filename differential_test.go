@@ -0,0 +1,148 @@
+package orderbook
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// compareBooks returns description of the first difference of the book from the reference:
+// fills of the last operation, last price, depth and orders of every price level
+func compareBooks(ob *OrderBook, ref *refBook) error {
+	fills := ob.Fills()
+	if len(fills) != len(ref.fills) {
+		return fmt.Errorf("%d fills, reference has %d", len(fills), len(ref.fills))
+	}
+	for i, f := range fills {
+		r := ref.fills[i]
+		if f.TakerID != r.takerID || f.TakerOwner != r.takerOwner || f.MakerID != r.makerID || f.MakerOwner != r.makerOwner ||
+			f.Side != r.side || !f.Price.Equal(r.price) || !f.Quantity.Equal(r.quantity) {
+			return fmt.Errorf("fill %d is %s %s/%s %s@%s, reference is %s %s/%s %s@%s", i,
+				f.Side, f.TakerID, f.MakerID, f.Quantity, f.Price, r.side, r.takerID, r.makerID, r.quantity, r.price)
+		}
+	}
+
+	if !ob.LastPrice().Equal(ref.lastPrice) {
+		return fmt.Errorf("last price is %s, reference is %s", ob.LastPrice(), ref.lastPrice)
+	}
+
+	depth := ob.Depth(0)
+	for _, side := range []struct {
+		side   Side
+		levels [][]decimal.Decimal
+	}{{Sell, depth.Asks}, {Buy, depth.Bids}} {
+		levels := ref.depth(side.side)
+		if len(side.levels) != len(levels) {
+			return fmt.Errorf("%s side has %d levels, reference has %d", side.side, len(side.levels), len(levels))
+		}
+		for i, level := range side.levels {
+			if !level[0].Equal(levels[i][0]) || !level[1].Equal(levels[i][1]) {
+				return fmt.Errorf("%s level %d is %s@%s, reference is %s@%s", side.side, i, level[1], level[0], levels[i][1], levels[i][0])
+			}
+		}
+	}
+
+	// time priority of the orders
+	for _, orders := range [][]refOrder{ref.asks, ref.bids} {
+		for i := 0; i < len(orders); {
+			level := ob.PriceLevel(orders[i].side, orders[i].price)
+			for el := level.Head(); el != nil; el, i = el.Next(), i+1 {
+				o, r := el.Value.(*Order), orders[i]
+				if o.ID() != r.id || o.Owner() != r.owner || !o.Quantity().Equal(r.quantity) {
+					return fmt.Errorf("order %d of %s level %s is %s %s, reference is %s %s", i, o.Side(), o.Price(), o.ID(), o.Quantity(), r.id, r.quantity)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// runDifferential applies the operations to the book and the reference book and compares them
+// after every operation, rollbacks of the book are compared with the saved reference states
+func runDifferential(t testing.TB, ops []operation) {
+	t.Helper()
+	ob := NewOrderBook()
+	ref := &refBook{}
+	type saved struct {
+		rollback func()
+		ref      *refBook
+	}
+	var stack []saved
+
+	for i, op := range ops {
+		before := ref.clone()
+		var rollback func()
+		var err, refErr error
+		var left, refLeft decimal.Decimal
+
+		switch op.kind {
+		case opLimit:
+			_, _, _, rollback, err = ob.ProcessLimitOrderFor(op.owner, op.side, op.id, op.quantity, op.price)
+			refErr = ref.limit(op.owner, op.side, op.id, op.quantity, op.price)
+		case opMarket:
+			_, _, _, left, rollback, err = ob.ProcessMarketQuantityOrderFor(op.owner, op.side, op.quantity)
+			refLeft, refErr = ref.market(op.owner, op.side, op.quantity)
+		case opMarketPrice:
+			_, _, _, left, rollback, err = ob.ProcessMarketPriceBuyFor(op.owner, op.price.Mul(op.quantity), 2)
+			refLeft, refErr = ref.marketPrice(op.owner, op.price.Mul(op.quantity), 2)
+		case opCancel:
+			var order *Order
+			order, rollback = ob.CancelOrder(op.id)
+			if ref.cancel(op.id) != (order != nil) {
+				t.Fatalf("operation %d %s: cancelled %v", i, op, order)
+			}
+			ob.fills, ref.fills = nil, nil
+		case opReplace:
+			_, _, _, rollback, err = ob.ReplaceOrder(op.id, op.quantity, op.price)
+			refErr = ref.replace(op.id, op.quantity, op.price)
+		case opCancelOwner:
+			_, rollback = ob.CancelAllOwner(op.owner)
+			ref.cancelOwner(op.owner)
+			ob.fills, ref.fills = nil, nil
+		case opUndo:
+			for n := 0; n < op.n && len(stack) > 0; n++ {
+				s := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				s.rollback()
+				ref = s.ref
+			}
+			ob.fills, ref.fills = nil, nil
+		case opCommit:
+			stack = stack[:0]
+			ob.fills, ref.fills = nil, nil
+		}
+
+		if err != refErr {
+			t.Fatalf("operation %d %s: error %v, reference %v", i, op, err, refErr)
+		}
+		if !left.Equal(refLeft) {
+			t.Fatalf("operation %d %s: %s left, reference %s", i, op, left, refLeft)
+		}
+		if err := compareBooks(ob, ref); err != nil {
+			t.Fatalf("operation %d %s: %v", i, op, err)
+		}
+		if rollback != nil {
+			stack = append(stack, saved{rollback: rollback, ref: before})
+		}
+	}
+}
+
+func TestDifferential(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	data := make([]byte, 4*300)
+	for i := 0; i < 300; i++ {
+		r.Read(data)
+		runDifferential(t, decodeOperations(data))
+	}
+}
+
+func FuzzDifferential(f *testing.F) {
+	f.Add([]byte{opLimit, 0, 5, 3, opLimit, 3, 2, 3, opMarket, 0, 4, 0, opUndo, 0, 0, 0})
+	f.Add([]byte{opLimit, 1, 1, 10, opLimit, 3, 9, 10, opLimit, 0, 20, 8, opReplace, 1, 3, 12, opUndo, 1, 1, 0, opCancelOwner, 0, 0, 0})
+	f.Add([]byte{opLimit, 0, 7, 5, opMarketPrice, 1, 15, 9, opCancel, 0, 0, 0, opLimit, 1, 3, 5, opCommit, 0, 0, 0, opUndo, 0, 3, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		runDifferential(t, decodeOperations(data))
+	})
+}
@@ -9,4 +9,12 @@ var (
 	ErrOrderExists          = errors.New("orderbook: order already exists")
 	ErrOrderNotExists       = errors.New("orderbook: order does not exist")
 	ErrInsufficientQuantity = errors.New("orderbook: insufficient quantity to calculate price")
+	ErrNoLiquidity          = errors.New("orderbook: no orders on the side")
+	ErrInvalidStep          = errors.New("orderbook: invalid price step")
+	ErrPriceBand            = errors.New("orderbook: order would trade outside of the price band")
+	ErrHalted               = errors.New("orderbook: trading is halted")
+	ErrNotAllowed           = errors.New("orderbook: operation is not allowed in the trading phase")
+	ErrInvalidSnapshot      = errors.New("orderbook: invalid snapshot")
+	ErrInvalidState         = errors.New("orderbook: invalid state")
+	ErrUnknownCommand       = errors.New("orderbook: unknown command")
 )
@@ -0,0 +1,121 @@
+package export
+
+import (
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// Columns of the exported tables
+var (
+	OrderColumns = []Column{
+		{Name: "side", Type: String},
+		{Name: "price", Type: Decimal},
+		{Name: "rank", Type: Int64}, // 1-based position within the price level
+		{Name: "id", Type: String},
+		{Name: "owner", Type: String},
+		{Name: "quantity", Type: Decimal},
+		{Name: "time", Type: Time},
+	}
+	DepthColumns = []Column{
+		{Name: "time", Type: Time},
+		{Name: "side", Type: String},
+		{Name: "level", Type: Int64}, // 1-based level from the best price
+		{Name: "price", Type: Decimal},
+		{Name: "volume", Type: Decimal},
+		{Name: "orders", Type: Int64},
+	}
+	TradeColumns = []Column{
+		{Name: "time", Type: Time},
+		{Name: "side", Type: String}, // side of the taker
+		{Name: "price", Type: Decimal},
+		{Name: "quantity", Type: Decimal},
+		{Name: "makerId", Type: String},
+		{Name: "makerOwner", Type: String},
+		{Name: "takerId", Type: String},
+		{Name: "takerOwner", Type: String},
+		{Name: "makerFee", Type: Decimal},
+		{Name: "takerFee", Type: Decimal},
+	}
+)
+
+// WriteOrders writes row of OrderColumns for every resting order, asks from the best price then
+// bids from the best price, orders of the price level in time priority
+func WriteOrders(w Writer, book *orderbook.OrderBook) error {
+	depth := book.Depth(0)
+	for _, side := range []struct {
+		side   orderbook.Side
+		levels [][]decimal.Decimal
+	}{{orderbook.Sell, depth.Asks}, {orderbook.Buy, depth.Bids}} {
+		for _, level := range side.levels {
+			rank := int64(0)
+			for e := book.PriceLevel(side.side, level[0]).Head(); e != nil; e = e.Next() {
+				rank++
+				o := e.Value.(*orderbook.Order)
+				if err := w.Write(o.Side().String(), o.Price(), rank, o.ID(), o.Owner(), o.Quantity(), o.Time()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// WriteDepth writes rows of DepthColumns for the levels of both sides at the time, all levels if
+// levels is 0
+func WriteDepth(w Writer, book *orderbook.OrderBook, levels int, at time.Time) error {
+	depth := book.Depth(levels)
+	for _, side := range []struct {
+		side   orderbook.Side
+		levels [][]decimal.Decimal
+	}{{orderbook.Sell, depth.Asks}, {orderbook.Buy, depth.Bids}} {
+		for i, level := range side.levels {
+			orders := int64(book.PriceLevel(side.side, level[0]).Len())
+			if err := w.Write(at, side.side.String(), int64(i+1), level[0], level[1], orders); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteTrades writes row of TradeColumns for every fill
+func WriteTrades(w Writer, fills []*orderbook.Fill) error {
+	for _, f := range fills {
+		if err := w.Write(f.Time, f.Side.String(), f.Price, f.Quantity, f.MakerID, f.MakerOwner, f.TakerID, f.TakerOwner, f.MakerFee, f.TakerFee); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DepthSampler writes depth snapshots of the book periodically by the book time, e.g. while a
+// log is replayed
+type DepthSampler struct {
+	w        Writer
+	book     *orderbook.OrderBook
+	interval time.Duration
+	levels   int
+	next     time.Time
+}
+
+// NewDepthSampler creates sampler of the levels of the book every interval
+func NewDepthSampler(w Writer, book *orderbook.OrderBook, interval time.Duration, levels int) *DepthSampler {
+	return &DepthSampler{w: w, book: book, interval: interval, levels: levels}
+}
+
+// Observe writes snapshot of the book if the time reached the next interval, it must be called
+// before the command of the time is applied so the snapshot is the book at the interval boundary
+func (s *DepthSampler) Observe(at time.Time) error {
+	if s.next.IsZero() {
+		s.next = at.Truncate(s.interval)
+	}
+	for !at.Before(s.next) {
+		if err := WriteDepth(s.w, s.book, s.levels, s.next); err != nil {
+			return err
+		}
+		s.next = s.next.Add(s.interval)
+	}
+	return nil
+}
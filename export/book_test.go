@@ -0,0 +1,115 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+func testBook() *orderbook.OrderBook {
+	book := orderbook.NewOrderBook()
+	at := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	for i, o := range []struct {
+		id, owner string
+		side      orderbook.Side
+		quantity  int64
+		price     int64
+	}{
+		{"b1", "x", orderbook.Buy, 5, 99},
+		{"b2", "y", orderbook.Buy, 3, 99},
+		{"b3", "x", orderbook.Buy, 1, 98},
+		{"s1", "y", orderbook.Sell, 2, 102},
+		{"s2", "x", orderbook.Sell, 4, 101},
+	} {
+		book.Apply(&orderbook.Command{Type: orderbook.CommandLimit, Time: at.Add(time.Duration(i) * time.Second), ID: o.id, Owner: o.owner, Side: o.side, Quantity: decimal.New(o.quantity, 0), Price: decimal.New(o.price, 0)})
+	}
+	return book
+}
+
+func TestWriteOrders(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf, OrderColumns)
+	if err := WriteOrders(w, testBook()); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	expected := `side,price,rank,id,owner,quantity,time
+sell,101,1,s2,x,4,2024-01-02T09:30:04Z
+sell,102,1,s1,y,2,2024-01-02T09:30:03Z
+buy,99,1,b1,x,5,2024-01-02T09:30:00Z
+buy,99,2,b2,y,3,2024-01-02T09:30:01Z
+buy,98,1,b3,x,1,2024-01-02T09:30:02Z
+`
+	if buf.String() != expected {
+		t.Fatalf("orders:\n%s", buf.String())
+	}
+}
+
+func TestWriteTrades(t *testing.T) {
+	book := testBook()
+	result := book.Apply(&orderbook.Command{Type: orderbook.CommandMarket, Time: time.Date(2024, 1, 2, 9, 31, 0, 0, time.UTC), Owner: "z", Side: orderbook.Sell, Quantity: decimal.New(6, 0)})
+
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf, TradeColumns)
+	if err := WriteTrades(w, result.Fills); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	expected := `time,side,price,quantity,makerId,makerOwner,takerId,takerOwner,makerFee,takerFee
+2024-01-02T09:31:00Z,sell,99,5,b1,x,,z,0,0
+2024-01-02T09:31:00Z,sell,99,1,b2,y,,z,0,0
+`
+	if buf.String() != expected {
+		t.Fatalf("trades:\n%s", buf.String())
+	}
+}
+
+func TestDepthSampler(t *testing.T) {
+	book := testBook()
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf, DepthColumns)
+	s := NewDepthSampler(w, book, time.Minute, 1)
+
+	at := time.Date(2024, 1, 2, 9, 31, 30, 0, time.UTC)
+	for _, cmd := range []*orderbook.Command{
+		{Type: orderbook.CommandCancel, Time: at, ID: "s2"},
+		{Type: orderbook.CommandCancel, Time: at.Add(20 * time.Second), ID: "b1"},
+		{Type: orderbook.CommandCancel, Time: at.Add(150 * time.Second), ID: "b2"},
+	} {
+		if err := s.Observe(cmd.Time); err != nil {
+			t.Fatal(err)
+		}
+		book.Apply(cmd)
+	}
+	w.Close()
+
+	expected := `time,side,level,price,volume,orders
+2024-01-02T09:31:00Z,sell,1,101,4,1
+2024-01-02T09:31:00Z,buy,1,99,8,2
+2024-01-02T09:32:00Z,sell,1,102,2,1
+2024-01-02T09:32:00Z,buy,1,99,3,1
+2024-01-02T09:33:00Z,sell,1,102,2,1
+2024-01-02T09:33:00Z,buy,1,99,3,1
+2024-01-02T09:34:00Z,sell,1,102,2,1
+2024-01-02T09:34:00Z,buy,1,99,3,1
+`
+	if buf.String() != expected {
+		t.Fatalf("depth:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	w = NewCSVWriter(&buf, DepthColumns)
+	if err := WriteDepth(w, book, 0, at); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if strings.Count(buf.String(), "\n") != 3 {
+		t.Fatalf("depth:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,342 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultRowGroupSize is the default number of rows of the Parquet row group
+const DefaultRowGroupSize = 100000
+
+var parquetMagic = []byte("PAR1")
+
+// parquet physical and converted types, see parquet.thrift
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetDecimal         = 5
+	parquetTimestampMicros = 10
+)
+
+// ParquetWriter writes Parquet file of required columns: strings are UTF8 byte arrays, decimals
+// are int64 DECIMAL(18, Scale), times are int64 TIMESTAMP_MICROS. Pages are PLAIN encoded and
+// uncompressed, every row group has a single page per column
+type ParquetWriter struct {
+	w       io.Writer
+	columns []Column
+
+	RowGroupSize int
+
+	offset    int64
+	pages     []*bytes.Buffer // values of the current row group
+	rows      int64           // rows of the current row group
+	total     int64
+	rowGroups [][]byte // encoded RowGroup structs
+	err       error
+}
+
+// NewParquetWriter creates writer of Parquet file
+func NewParquetWriter(w io.Writer, columns []Column) *ParquetWriter {
+	columns = append([]Column(nil), columns...)
+	pw := &ParquetWriter{w: w, columns: columns, RowGroupSize: DefaultRowGroupSize}
+	for i := range columns {
+		if columns[i].Type == Decimal && columns[i].Scale == 0 {
+			pw.columns[i].Scale = DefaultScale
+		}
+		pw.pages = append(pw.pages, &bytes.Buffer{})
+	}
+	return pw
+}
+
+// Write implements Writer interface
+func (w *ParquetWriter) Write(row ...interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := check(w.columns, row); err != nil {
+		return err
+	}
+
+	// decimals are scaled before any value is written, so the rejected row leaves pages intact
+	scaled := make([]int64, len(row))
+	for i, v := range row {
+		if d, ok := v.(decimal.Decimal); ok {
+			d = d.Shift(w.columns[i].Scale)
+			if !d.Equal(d.Truncate(0)) || d.Abs().GreaterThanOrEqual(decimal.New(1, 18)) {
+				return ErrPrecision
+			}
+			scaled[i] = d.IntPart()
+		}
+	}
+
+	var n [8]byte
+	for i, v := range row {
+		page := w.pages[i]
+		switch v := v.(type) {
+		case string:
+			binary.LittleEndian.PutUint32(n[:4], uint32(len(v)))
+			page.Write(n[:4])
+			page.WriteString(v)
+		case int64:
+			binary.LittleEndian.PutUint64(n[:], uint64(v))
+			page.Write(n[:])
+		case decimal.Decimal:
+			binary.LittleEndian.PutUint64(n[:], uint64(scaled[i]))
+			page.Write(n[:])
+		case time.Time:
+			binary.LittleEndian.PutUint64(n[:], uint64(v.UnixNano()/int64(time.Microsecond)))
+			page.Write(n[:])
+		}
+	}
+
+	w.rows++
+	if w.RowGroupSize > 0 && w.rows >= int64(w.RowGroupSize) {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *ParquetWriter) write(data []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	_, w.err = w.w.Write(data)
+	w.offset += int64(len(data))
+	return w.err
+}
+
+// begin writes the magic number at the start of the file
+func (w *ParquetWriter) begin() error {
+	if w.offset > 0 {
+		return w.err
+	}
+	return w.write(parquetMagic)
+}
+
+// flush writes the current row group
+func (w *ParquetWriter) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	if err := w.begin(); err != nil {
+		return err
+	}
+
+	var chunks [][]byte
+	var groupSize int64
+	for i, c := range w.columns {
+		values := w.pages[i].Bytes()
+
+		page := &thrift{}
+		page.i32(1, 0) // DATA_PAGE
+		page.i32(2, int32(len(values)))
+		page.i32(3, int32(len(values)))
+		page.beginStruct(5) // DataPageHeader
+		page.i32(1, int32(w.rows))
+		page.i32(2, 0) // PLAIN
+		page.i32(3, 3) // RLE
+		page.i32(4, 3) // RLE
+		page.end()
+		page.end()
+
+		pageOffset := w.offset
+		if w.write(page.Bytes()) != nil || w.write(values) != nil {
+			return w.err
+		}
+		size := int64(page.Len() + len(values))
+		groupSize += size
+
+		chunk := &thrift{}
+		chunk.i64(2, pageOffset)
+		chunk.beginStruct(3) // ColumnMetaData
+		chunk.i32(1, physicalType(c))
+		chunk.i32List(2, 0) // PLAIN
+		chunk.stringList(3, c.Name)
+		chunk.i32(4, 0) // UNCOMPRESSED
+		chunk.i64(5, w.rows)
+		chunk.i64(6, size)
+		chunk.i64(7, size)
+		chunk.i64(9, pageOffset)
+		chunk.end()
+		chunk.end()
+		chunks = append(chunks, chunk.Bytes())
+
+		w.pages[i].Reset()
+	}
+
+	group := &thrift{}
+	group.structList(1, chunks)
+	group.i64(2, groupSize)
+	group.i64(3, w.rows)
+	group.end()
+	w.rowGroups = append(w.rowGroups, group.Bytes())
+
+	w.total += w.rows
+	w.rows = 0
+	return nil
+}
+
+func physicalType(c Column) int32 {
+	if c.Type == String {
+		return parquetByteArray
+	}
+	return parquetInt64
+}
+
+// Close writes the last row group and the footer
+func (w *ParquetWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if err := w.begin(); err != nil {
+		return err
+	}
+
+	var schema [][]byte
+	root := &thrift{}
+	root.str(4, "schema")
+	root.i32(5, int32(len(w.columns)))
+	root.end()
+	schema = append(schema, root.Bytes())
+	for _, c := range w.columns {
+		e := &thrift{}
+		e.i32(1, physicalType(c))
+		e.i32(3, 0) // REQUIRED
+		e.str(4, c.Name)
+		switch c.Type {
+		case String:
+			e.i32(6, parquetUTF8)
+		case Decimal:
+			e.i32(6, parquetDecimal)
+			e.i32(7, c.Scale)
+			e.i32(8, 18)
+		case Time:
+			e.i32(6, parquetTimestampMicros)
+		}
+		e.end()
+		schema = append(schema, e.Bytes())
+	}
+
+	meta := &thrift{}
+	meta.i32(1, 1)
+	meta.structList(2, schema)
+	meta.i64(3, w.total)
+	meta.structList(4, w.rowGroups)
+	meta.str(6, "github.com/centny/orderbook/export")
+	meta.end()
+
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(meta.Len()))
+	if w.write(meta.Bytes()) != nil || w.write(n[:]) != nil {
+		return w.err
+	}
+	return w.write(parquetMagic)
+}
+
+// thrift encodes structs with the compact protocol
+type thrift struct {
+	bytes.Buffer
+	last []int16 // last field ID of the nested structs
+}
+
+// compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+func (t *thrift) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	t.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func (t *thrift) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thrift) field(id int16, typ byte) {
+	last := int16(0)
+	if len(t.last) > 0 {
+		last = t.last[len(t.last)-1]
+		t.last[len(t.last)-1] = id
+	} else {
+		t.last = append(t.last, id)
+	}
+	if delta := id - last; delta > 0 && delta <= 15 {
+		t.WriteByte(byte(delta)<<4 | typ)
+		return
+	}
+	t.WriteByte(typ)
+	t.zigzag(int64(id))
+}
+
+func (t *thrift) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thrift) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thrift) str(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(v)))
+	t.WriteString(v)
+}
+
+func (t *thrift) list(id int16, size int, typ byte) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.WriteByte(byte(size)<<4 | typ)
+		return
+	}
+	t.WriteByte(0xf0 | typ)
+	t.varint(uint64(size))
+}
+
+func (t *thrift) i32List(id int16, values ...int32) {
+	t.list(id, len(values), thriftI32)
+	for _, v := range values {
+		t.zigzag(int64(v))
+	}
+}
+
+func (t *thrift) stringList(id int16, values ...string) {
+	t.list(id, len(values), thriftBinary)
+	for _, v := range values {
+		t.varint(uint64(len(v)))
+		t.WriteString(v)
+	}
+}
+
+// structList writes list of encoded structs
+func (t *thrift) structList(id int16, values [][]byte) {
+	t.list(id, len(values), thriftStruct)
+	for _, v := range values {
+		t.Write(v)
+	}
+}
+
+func (t *thrift) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.last = append(t.last, 0)
+}
+
+// end writes stop of the struct
+func (t *thrift) end() {
+	t.WriteByte(0)
+	if len(t.last) > 0 {
+		t.last = t.last[:len(t.last)-1]
+	}
+}
@@ -0,0 +1,184 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// readThrift decodes compact protocol struct into map of field IDs, lists are []interface{}
+func readThrift(t *testing.T, r *bytes.Reader) map[int16]interface{} {
+	t.Helper()
+	fields := map[int16]interface{}{}
+	last := int16(0)
+	for {
+		b, _ := r.ReadByte()
+		if b == 0 {
+			return fields
+		}
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			v, _ := binary.ReadVarint(r)
+			id = int16(v)
+		}
+		last = id
+		fields[id] = readThriftValue(t, r, b&0x0f)
+	}
+}
+
+func readThriftValue(t *testing.T, r *bytes.Reader, typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		v, _ := binary.ReadVarint(r)
+		return v
+	case thriftBinary:
+		n, _ := binary.ReadUvarint(r)
+		data := make([]byte, n)
+		r.Read(data)
+		return string(data)
+	case thriftList:
+		b, _ := r.ReadByte()
+		size := int(b >> 4)
+		if size == 15 {
+			n, _ := binary.ReadUvarint(r)
+			size = int(n)
+		}
+		var values []interface{}
+		for i := 0; i < size; i++ {
+			values = append(values, readThriftValue(t, r, b&0x0f))
+		}
+		return values
+	case thriftStruct:
+		return readThrift(t, r)
+	}
+	t.Fatalf("unexpected thrift type %d", typ)
+	return nil
+}
+
+func TestParquetWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewParquetWriter(&buf, testColumns)
+	w.RowGroupSize = 2
+	at := time.Date(2024, 1, 2, 9, 30, 0, 1500, time.UTC)
+	for i := int64(0); i < 3; i++ {
+		if err := w.Write("row", i, decimal.New(-15, -1), at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, parquetMagic) || !bytes.HasSuffix(data, parquetMagic) {
+		t.Fatal("magic is missing")
+	}
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := readThrift(t, bytes.NewReader(data[len(data)-8-size:len(data)-8]))
+
+	if meta[3].(int64) != 3 {
+		t.Fatalf("rows %v", meta[3])
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != 5 || schema[0].(map[int16]interface{})[5].(int64) != 4 {
+		t.Fatalf("schema %v", schema)
+	}
+	if price := schema[3].(map[int16]interface{}); price[4] != "price" || price[6].(int64) != parquetDecimal || price[7].(int64) != DefaultScale {
+		t.Fatalf("price column %v", price)
+	}
+
+	groups := meta[4].([]interface{})
+	if len(groups) != 2 || groups[1].(map[int16]interface{})[3].(int64) != 1 {
+		t.Fatalf("row groups %v", groups)
+	}
+
+	// values of the columns of the first row group
+	columns := groups[0].(map[int16]interface{})[1].([]interface{})
+	page := func(i int) []byte {
+		column := columns[i].(map[int16]interface{})[3].(map[int16]interface{})
+		r := bytes.NewReader(data[column[9].(int64):])
+		header := readThrift(t, r)
+		values := make([]byte, header[3].(int64))
+		r.Read(values)
+		return values
+	}
+	if v := page(0); !bytes.Equal(v, []byte("\x03\x00\x00\x00row\x03\x00\x00\x00row")) {
+		t.Fatalf("name values %q", v)
+	}
+	if v := page(1); binary.LittleEndian.Uint64(v[8:]) != 1 {
+		t.Fatalf("count values %v", v)
+	}
+	if v := page(2); int64(binary.LittleEndian.Uint64(v)) != -150000000 {
+		t.Fatalf("price values %v", v)
+	}
+	if v := page(3); int64(binary.LittleEndian.Uint64(v)) != at.UnixNano()/1000 {
+		t.Fatalf("time values %v", v)
+	}
+}
+
+func TestParquetWriterPrecision(t *testing.T) {
+	w := NewParquetWriter(&bytes.Buffer{}, []Column{{Name: "price", Type: Decimal, Scale: 2}})
+	if err := w.Write(decimal.New(1234, -2)); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []decimal.Decimal{decimal.New(1, -3), decimal.New(1, 17)} {
+		if err := w.Write(d); !errors.Is(err, ErrPrecision) {
+			t.Errorf("%s: %v", d, err)
+		}
+	}
+}
+
+func TestParquetWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewParquetWriter(&buf, testColumns).Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if !bytes.HasPrefix(data, parquetMagic) || size+12 != len(data) {
+		t.Fatalf("file %q", data)
+	}
+}
+
+// TestParquetWriterFixture compares the output with testdata/orders.parquet, the fixture is
+// read by github.com/parquet-go/parquet-go as rows:
+//
+//	[alice 1 10025000000 1704187800000001]
+//	[bob -2 -150000000 1704187801000001]
+//	[ 3 0 1704187860000001]
+func TestParquetWriterFixture(t *testing.T) {
+	want, err := os.ReadFile("testdata/orders.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w := NewParquetWriter(&buf, testColumns)
+	w.RowGroupSize = 2
+	at := time.Date(2024, 1, 2, 9, 30, 0, 1500, time.UTC)
+	if err := w.Write("alice", int64(1), decimal.New(10025, -2), at); err != nil {
+		t.Fatal(err)
+	}
+	// rejected row does not change the pages
+	if err := w.Write("bad", int64(2), decimal.New(1, -9), at); !errors.Is(err, ErrPrecision) {
+		t.Fatal(err)
+	}
+	if err := w.Write("bob", int64(-2), decimal.New(-15, -1), at.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("", int64(3), decimal.Zero, at.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("file differs from the fixture:\n%q\n%q", buf.Bytes(), want)
+	}
+}
@@ -0,0 +1,152 @@
+// Package export dumps the book, depth snapshots and the trade stream to CSV and Parquet files
+// for analytics tools.
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Errors of the writers
+var (
+	ErrInvalidRow = errors.New("export: invalid row")
+	ErrPrecision  = errors.New("export: decimal does not fit the scale")
+)
+
+// ColumnType is the type of the column values
+type ColumnType int
+
+// Column types and Go types of their values
+const (
+	String  ColumnType = iota // string
+	Int64                     // int64
+	Decimal                   // decimal.Decimal
+	Time                      // time.Time
+)
+
+// DefaultScale is the default number of decimal places of Decimal columns in Parquet files
+const DefaultScale = 8
+
+// Column of the table
+type Column struct {
+	Name  string
+	Type  ColumnType
+	Scale int32 // decimal places of Decimal column in Parquet files, values are exact in CSV
+}
+
+// Writer writes rows of the table, Close must be called to complete the file
+type Writer interface {
+	Write(row ...interface{}) error
+	Close() error
+}
+
+// Format of the file
+type Format int
+
+// Supported formats
+const (
+	CSV Format = iota
+	Parquet
+)
+
+// ParseFormat parses csv or parquet
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "csv":
+		return CSV, nil
+	case "parquet":
+		return Parquet, nil
+	}
+	return CSV, fmt.Errorf("export: unknown format %q", s)
+}
+
+// NewWriter creates writer of the format
+func NewWriter(w io.Writer, format Format, columns []Column) Writer {
+	if format == Parquet {
+		return NewParquetWriter(w, columns)
+	}
+	return NewCSVWriter(w, columns)
+}
+
+// check validates types of the row values
+func check(columns []Column, row []interface{}) error {
+	if len(row) != len(columns) {
+		return fmt.Errorf("%w: %d values, expected %d", ErrInvalidRow, len(row), len(columns))
+	}
+	for i, c := range columns {
+		ok := false
+		switch row[i].(type) {
+		case string:
+			ok = c.Type == String
+		case int64:
+			ok = c.Type == Int64
+		case decimal.Decimal:
+			ok = c.Type == Decimal
+		case time.Time:
+			ok = c.Type == Time
+		}
+		if !ok {
+			return fmt.Errorf("%w: value %v of column %s", ErrInvalidRow, row[i], c.Name)
+		}
+	}
+	return nil
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []Column
+	header  bool
+	record  []string
+}
+
+// NewCSVWriter creates writer of CSV file with header, times are RFC 3339
+func NewCSVWriter(w io.Writer, columns []Column) Writer {
+	return &csvWriter{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+}
+
+func (w *csvWriter) Write(row ...interface{}) error {
+	if err := check(w.columns, row); err != nil {
+		return err
+	}
+	if !w.header {
+		for i, c := range w.columns {
+			w.record[i] = c.Name
+		}
+		if err := w.w.Write(w.record); err != nil {
+			return err
+		}
+		w.header = true
+	}
+
+	for i, v := range row {
+		switch v := v.(type) {
+		case string:
+			w.record[i] = v
+		case int64:
+			w.record[i] = strconv.FormatInt(v, 10)
+		case decimal.Decimal:
+			w.record[i] = v.String()
+		case time.Time:
+			w.record[i] = v.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return w.w.Write(w.record)
+}
+
+func (w *csvWriter) Close() error {
+	if !w.header {
+		for i, c := range w.columns {
+			w.record[i] = c.Name
+		}
+		w.w.Write(w.record)
+		w.header = true
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var testColumns = []Column{
+	{Name: "name", Type: String},
+	{Name: "count", Type: Int64},
+	{Name: "price", Type: Decimal},
+	{Name: "time", Type: Time},
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf, testColumns)
+	at := time.Date(2024, 1, 2, 9, 30, 0, 1, time.FixedZone("CET", 3600))
+	if err := w.Write("a,b", int64(-1), decimal.New(12345, -4), at); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("c", int64(2), decimal.New(1, 0), at); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "name,count,price,time\n\"a,b\",-1,1.2345,2024-01-02T08:30:00.000000001Z\nc,2,1,2024-01-02T08:30:00.000000001Z\n"
+	if buf.String() != expected {
+		t.Fatalf("csv:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	w = NewCSVWriter(&buf, testColumns)
+	if err := w.Close(); err != nil || buf.String() != "name,count,price,time\n" {
+		t.Fatalf("empty csv %q, %v", buf.String(), err)
+	}
+}
+
+func TestWriterCheck(t *testing.T) {
+	for _, w := range []Writer{NewCSVWriter(&bytes.Buffer{}, testColumns), NewParquetWriter(&bytes.Buffer{}, testColumns)} {
+		if err := w.Write("a", int64(1), decimal.Zero); !errors.Is(err, ErrInvalidRow) {
+			t.Errorf("%T: missing value: %v", w, err)
+		}
+		if err := w.Write("a", 1, decimal.Zero, time.Time{}); !errors.Is(err, ErrInvalidRow) {
+			t.Errorf("%T: int value: %v", w, err)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("parquet"); err != nil || f != Parquet {
+		t.Fatal(f, err)
+	}
+	if f, err := ParseFormat("csv"); err != nil || f != CSV {
+		t.Fatal(f, err)
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Fatal("unknown format must fail")
+	}
+	if _, ok := NewWriter(&bytes.Buffer{}, Parquet, testColumns).(*ParquetWriter); !ok {
+		t.Fatal("parquet writer is expected")
+	}
+}
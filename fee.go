@@ -0,0 +1,117 @@
+package orderbook
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// FeeModel calculates fees for every fill produced by the OrderBook
+type FeeModel interface {
+	// Fees returns maker and taker fees of the fill, negative fee is a rebate
+	Fees(fill *Fill) (makerFee, takerFee decimal.Decimal)
+}
+
+// FeeReverter is implemented by fee models with state changed by fills (e.g. traded volume),
+// Revert is called for every fill of the rolled back order
+type FeeReverter interface {
+	Revert(fill *Fill)
+}
+
+// FeeTier stores fee rates applied to accounts with traded volume
+// (notional) greater or equal to Volume
+type FeeTier struct {
+	Volume decimal.Decimal `json:"volume"`
+	Maker  decimal.Decimal `json:"maker"` // negative rate is a rebate
+	Taker  decimal.Decimal `json:"taker"`
+}
+
+// FeeSchedule implements FeeModel and FeeReverter with volume tiered rates.
+// Fee is calculated as fill notional multiplied by the rate of the tier the account is in
+// before the fill, then the notional is added to the account volume. Orders without owner
+// are charged by the default tiers, their volume is not tracked
+type FeeSchedule struct {
+	tiers    []FeeTier
+	accounts map[string][]FeeTier
+	volumes  map[string]decimal.Decimal
+}
+
+// NewFeeSchedule creates FeeSchedule with default tiers applied to every account
+func NewFeeSchedule(tiers ...FeeTier) *FeeSchedule {
+	return &FeeSchedule{
+		tiers:    sortTiers(tiers),
+		accounts: map[string][]FeeTier{},
+		volumes:  map[string]decimal.Decimal{},
+	}
+}
+
+func sortTiers(tiers []FeeTier) []FeeTier {
+	sorted := make([]FeeTier, len(tiers))
+	copy(sorted, tiers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Volume.LessThan(sorted[j].Volume)
+	})
+	return sorted
+}
+
+// SetAccountTiers sets up tiers of the given account instead of default ones
+func (fs *FeeSchedule) SetAccountTiers(account string, tiers ...FeeTier) {
+	fs.accounts[account] = sortTiers(tiers)
+}
+
+// SetVolume sets up traded volume of the account (e.g. 30 days volume)
+func (fs *FeeSchedule) SetVolume(account string, volume decimal.Decimal) {
+	fs.volumes[account] = volume
+}
+
+// Volume returns traded volume of the account
+func (fs *FeeSchedule) Volume(account string) decimal.Decimal {
+	return fs.volumes[account]
+}
+
+// Tier returns current tier of the account, ok is false if there is no suitable tier
+func (fs *FeeSchedule) Tier(account string) (tier FeeTier, ok bool) {
+	tiers, found := fs.accounts[account]
+	if !found {
+		tiers = fs.tiers
+	}
+
+	volume := fs.volumes[account]
+	for _, t := range tiers {
+		if volume.LessThan(t.Volume) {
+			break
+		}
+		tier, ok = t, true
+	}
+	return
+}
+
+// Fees implements FeeModel interface
+func (fs *FeeSchedule) Fees(fill *Fill) (makerFee, takerFee decimal.Decimal) {
+	notional := fill.Notional()
+	makerFee, takerFee = decimal.Zero, decimal.Zero
+
+	if tier, ok := fs.Tier(fill.MakerOwner); ok {
+		makerFee = notional.Mul(tier.Maker)
+	}
+	if tier, ok := fs.Tier(fill.TakerOwner); ok {
+		takerFee = notional.Mul(tier.Taker)
+	}
+
+	fs.addVolume(fill.MakerOwner, notional)
+	fs.addVolume(fill.TakerOwner, notional)
+	return
+}
+
+// Revert implements FeeReverter interface, notional of the fill is subtracted from volumes
+func (fs *FeeSchedule) Revert(fill *Fill) {
+	notional := fill.Notional()
+	fs.addVolume(fill.MakerOwner, notional.Neg())
+	fs.addVolume(fill.TakerOwner, notional.Neg())
+}
+
+func (fs *FeeSchedule) addVolume(account string, notional decimal.Decimal) {
+	if len(account) > 0 {
+		fs.volumes[account] = fs.volumes[account].Add(notional)
+	}
+}
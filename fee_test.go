@@ -0,0 +1,105 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFeeScheduleTiers(t *testing.T) {
+	fs := NewFeeSchedule(
+		FeeTier{Volume: decimal.New(1000, 0), Maker: decimal.New(-1, -4), Taker: decimal.New(5, -4)},
+		FeeTier{Volume: decimal.Zero, Maker: decimal.New(1, -3), Taker: decimal.New(2, -3)},
+	)
+	fs.SetAccountTiers("vip", FeeTier{Volume: decimal.Zero, Maker: decimal.New(-2, -4), Taker: decimal.New(1, -4)})
+
+	if tier, ok := fs.Tier("alice"); !ok || !tier.Taker.Equal(decimal.New(2, -3)) {
+		t.Fatalf("invalid default tier: %v", tier)
+	}
+
+	fs.SetVolume("alice", decimal.New(1000, 0))
+	if tier, ok := fs.Tier("alice"); !ok || !tier.Maker.Equal(decimal.New(-1, -4)) {
+		t.Fatalf("invalid volume tier: %v", tier)
+	}
+
+	if tier, ok := fs.Tier("vip"); !ok || !tier.Maker.Equal(decimal.New(-2, -4)) {
+		t.Fatalf("invalid account tier: %v", tier)
+	}
+
+	empty := NewFeeSchedule(FeeTier{Volume: decimal.New(10, 0)})
+	if _, ok := empty.Tier("bob"); ok {
+		t.Fatal("tier found below minimal volume")
+	}
+}
+
+func TestFillFees(t *testing.T) {
+	fs := NewFeeSchedule(FeeTier{Volume: decimal.Zero, Maker: decimal.New(-1, -3), Taker: decimal.New(2, -3)})
+	fs.SetAccountTiers("mm", FeeTier{Volume: decimal.Zero, Maker: decimal.New(-2, -3), Taker: decimal.New(1, -3)})
+
+	ob := NewOrderBook()
+	ob.SetFeeModel(fs)
+	ob.ProcessLimitOrderFor("mm", Sell, "s-100", decimal.New(2, 0), decimal.New(100, 0))
+	ob.ProcessLimitOrderFor("alice", Sell, "s-110", decimal.New(2, 0), decimal.New(110, 0))
+	if len(ob.Fills()) != 0 {
+		t.Fatal("fills of resting order")
+	}
+
+	_, _, _, _, _, err := ob.ProcessMarketQuantityOrderFor("bob", Buy, decimal.New(3, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fills := ob.Fills()
+	if len(fills) != 2 {
+		t.Fatalf("invalid fills: %v", fills)
+	}
+
+	if fills[0].MakerID != "s-100" || fills[0].MakerOwner != "mm" || fills[0].TakerOwner != "bob" ||
+		!fills[0].Quantity.Equal(decimal.New(2, 0)) || !fills[0].MakerFee.Equal(decimal.New(-4, -1)) ||
+		!fills[0].TakerFee.Equal(decimal.New(4, -1)) {
+		t.Fatalf("invalid full fill: %v", fills[0])
+	}
+
+	if fills[1].MakerID != "s-110" || !fills[1].Quantity.Equal(decimal.New(1, 0)) ||
+		!fills[1].MakerFee.Equal(decimal.New(-11, -2)) || !fills[1].TakerFee.Equal(decimal.New(22, -2)) {
+		t.Fatalf("invalid partial fill: %v", fills[1])
+	}
+
+	if !fs.Volume("bob").Equal(decimal.New(310, 0)) {
+		t.Fatalf("invalid taker volume: %s", fs.Volume("bob"))
+	}
+
+	if o := ob.Order("s-110"); o == nil || o.Owner() != "alice" {
+		t.Fatal("owner is lost after partial fill")
+	}
+}
+
+func TestFeeScheduleRollback(t *testing.T) {
+	fs := NewFeeSchedule(FeeTier{Volume: decimal.Zero, Maker: decimal.New(-1, -3), Taker: decimal.New(2, -3)})
+	ob := NewOrderBook()
+	ob.SetFeeModel(fs)
+	ob.ProcessLimitOrderFor("mm", Sell, "s-100", decimal.New(2, 0), decimal.New(100, 0))
+	ob.ProcessLimitOrderFor("mm", Sell, "s-110", decimal.New(2, 0), decimal.New(110, 0))
+
+	_, _, _, _, rollback, err := ob.ProcessMarketQuantityOrderFor("bob", Buy, decimal.New(3, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fs.Volume("bob").Equal(decimal.New(310, 0)) || !fs.Volume("mm").Equal(decimal.New(310, 0)) {
+		t.Fatalf("invalid volumes: %s %s", fs.Volume("bob"), fs.Volume("mm"))
+	}
+
+	rollback()
+	if !fs.Volume("bob").IsZero() || !fs.Volume("mm").IsZero() {
+		t.Fatalf("rollback is not reverted volumes: %s %s", fs.Volume("bob"), fs.Volume("mm"))
+	}
+	if len(ob.Fills()) != 0 {
+		t.Fatalf("rolled back fills: %v", ob.Fills())
+	}
+
+	// volume of orders without owner is not tracked
+	ob.ProcessMarketQuantityOrder(Buy, decimal.New(1, 0))
+	if len(ob.Fills()) != 1 || !ob.Fills()[0].TakerFee.Equal(decimal.New(2, -1)) || !fs.Volume("").IsZero() {
+		t.Fatalf("invalid anonymous fill: %v %s", ob.Fills(), fs.Volume(""))
+	}
+}
@@ -0,0 +1,37 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Fill stores information about single trade between incoming (taker) order
+// and resting (maker) order, the trade is always executed with maker price
+type Fill struct {
+	TakerID    string          `json:"takerId"`
+	TakerOwner string          `json:"takerOwner,omitempty"`
+	MakerID    string          `json:"makerId"`
+	MakerOwner string          `json:"makerOwner,omitempty"`
+	Side       Side            `json:"side"` // side of the taker order
+	Price      decimal.Decimal `json:"price"`
+	Quantity   decimal.Decimal `json:"quantity"`
+	MakerFee   decimal.Decimal `json:"makerFee"` // negative value is a rebate
+	TakerFee   decimal.Decimal `json:"takerFee"` // negative value is a rebate
+	Time       time.Time       `json:"time"`
+}
+
+// FillHandler receives fills produced by the OrderBook
+type FillHandler func(fill *Fill)
+
+// Notional returns traded amount in quote currency (price * quantity)
+func (f *Fill) Notional() decimal.Decimal {
+	return f.Price.Mul(f.Quantity)
+}
+
+// String implements fmt.Stringer interface
+func (f *Fill) String() string {
+	data, _ := json.Marshal(f)
+	return string(data)
+}
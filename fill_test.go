@@ -0,0 +1,39 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestLimitOrderFills(t *testing.T) {
+	ob := NewOrderBook()
+	addDepth(ob, "", decimal.New(2, 0))
+
+	ob.ProcessLimitOrderFor("bob", Buy, "b-120", decimal.New(5, 0), decimal.New(120, 0))
+	fills := ob.Fills()
+	if len(fills) != 3 {
+		t.Fatalf("invalid fills: %v", fills)
+	}
+
+	total := decimal.Zero
+	for _, f := range fills {
+		if f.TakerID != "b-120" || f.TakerOwner != "bob" || f.Side != Buy || f.Price.GreaterThan(decimal.New(120, 0)) {
+			t.Fatalf("invalid fill: %v", f)
+		}
+		total = total.Add(f.Quantity)
+	}
+
+	if !total.Equal(decimal.New(5, 0)) || !fills[2].Notional().Equal(decimal.New(120, 0)) {
+		t.Fatalf("invalid filled quantity: %s", total)
+	}
+
+	ob.ProcessMarketPriceBuy(decimal.New(120, 0), 8)
+	if fills := ob.Fills(); len(fills) != 1 || !fills[0].Quantity.Equal(decimal.New(1, 0)) || fills[0].TakerID != "" {
+		t.Fatalf("invalid market price fills: %v", fills)
+	}
+
+	if _, _, _, _, err := ob.ProcessLimitOrder(Buy, "b-121", decimal.Zero, decimal.New(120, 0)); err == nil || len(ob.Fills()) != 0 {
+		t.Fatal("fills of rejected order")
+	}
+}
@@ -0,0 +1,600 @@
+package fix
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// Executor applies commands to the book, for instance *orderbook.OrderBook or *httpapi.Server
+type Executor interface {
+	Apply(cmd *orderbook.Command) *orderbook.Result
+}
+
+// LogonTimeout limits time of waiting for Logon message of the new connection
+var LogonTimeout = 10 * time.Second
+
+// Acceptor accepts FIX sessions and executes their orders. Commands are applied to
+// the executor one by one, so the executor is not required to be safe for concurrent use
+// unless it is shared with other gateways.
+//
+// Fills of the resting orders are reported to their sessions from the book fills. If the
+// executor has OnFill method (like *orderbook.OrderBook and *httpapi.Server), fills by the
+// orders of other gateways are reported too, otherwise only fills by the acceptor orders
+type Acceptor struct {
+	compID string
+	symbol string
+	exec   Executor
+	hooked bool // fills are received from OnFill of the executor
+
+	mu       sync.Mutex
+	sessions map[string]*session // by counterparty CompID
+	orders   map[string]*order   // active orders by book order ID
+	clOrdIDs map[clOrdKey]string // book order ID by owner and ClOrdID
+	execID   uint64
+
+	fmu         sync.Mutex
+	pending     []*orderbook.Fill // fills to report to the makers
+	dispatching bool
+}
+
+// clOrdKey identifies the order by the owner CompID and ClOrdID
+type clOrdKey struct {
+	owner   string
+	clOrdID string
+}
+
+// order tracks execution of the order placed through the acceptor
+type order struct {
+	id       string
+	owner    string
+	clOrdID  string
+	symbol   string
+	side     orderbook.Side
+	ordType  string
+	quantity decimal.Decimal // OrderQty
+	price    decimal.Decimal
+	cum      decimal.Decimal
+	notional decimal.Decimal
+	status   string
+}
+
+// Order statuses and execution types
+const (
+	statusNew       = "0"
+	statusPartial   = "1"
+	statusFilled    = "2"
+	statusCancelled = "4"
+	statusReplaced  = "5"
+	statusRejected  = "8"
+	execTrade       = "F"
+)
+
+// NewAcceptor creates acceptor with the given CompID for the book of the symbol
+func NewAcceptor(exec Executor, compID, symbol string) *Acceptor {
+	a := &Acceptor{
+		compID:   compID,
+		symbol:   symbol,
+		exec:     exec,
+		sessions: map[string]*session{},
+		orders:   map[string]*order{},
+		clOrdIDs: map[clOrdKey]string{},
+	}
+	if n, ok := exec.(interface{ OnFill(orderbook.FillHandler) }); ok {
+		n.OnFill(a.handleFill)
+		a.hooked = true
+	}
+	return a
+}
+
+// Serve accepts connections on the listener and serves them. Serve returns when the listener is closed
+func (a *Acceptor) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go a.ServeConn(conn)
+	}
+}
+
+// ServeConn serves FIX session over the connection until logout or connection error,
+// the connection is closed on return. Error is nil after the logout exchange
+func (a *Acceptor) ServeConn(conn net.Conn) error {
+	defer conn.Close()
+
+	s, err := a.logon(conn)
+	if err != nil {
+		return err
+	}
+	defer a.unregister(s)
+
+	for {
+		m, err := s.receive()
+		if err == ErrLoggedOut {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch m.Type() {
+		case MsgNewOrderSingle:
+			err = a.newOrder(s, m)
+		case MsgOrderCancelRequest:
+			err = a.cancelOrder(s, m)
+		case MsgOrderCancelReplaceRequest:
+			err = a.replaceOrder(s, m)
+		case MsgHeartbeat, MsgTestRequest, MsgResendRequest, MsgSequenceReset, MsgReject:
+		default:
+			err = s.reject(m, TagMsgType, 11, "unsupported MsgType")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (a *Acceptor) logon(conn net.Conn) (*session, error) {
+	conn.SetReadDeadline(time.Now().Add(LogonTimeout))
+	r := bufio.NewReader(conn)
+	m, err := ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newSession(conn, a.compID, m.Get(TagSenderCompID))
+	s.r = r
+	if m.Type() != MsgLogon {
+		return nil, errors.New("fix: first message is not Logon")
+	}
+	// CompID is the prefix of the book order IDs separated by ':'
+	if m.Get(TagTargetCompID) != a.compID || len(s.targetCompID) == 0 || strings.Contains(s.targetCompID, ":") {
+		s.logout("invalid CompID")
+		return nil, errors.New("fix: invalid CompID")
+	}
+	seq, err := m.Int(TagMsgSeqNum)
+	if err != nil || seq != 1 {
+		s.logout("MsgSeqNum of Logon must be 1")
+		return nil, errors.New("fix: invalid Logon MsgSeqNum")
+	}
+	heartBtInt, err := m.Int(TagHeartBtInt)
+	if err != nil || heartBtInt <= 0 {
+		s.logout("invalid HeartBtInt")
+		return nil, errors.New("fix: invalid HeartBtInt")
+	}
+
+	a.mu.Lock()
+	_, exists := a.sessions[s.targetCompID]
+	if !exists {
+		a.sessions[s.targetCompID] = s
+	}
+	a.mu.Unlock()
+	if exists {
+		s.logout("session is already logged on")
+		return nil, errors.New("fix: session is already logged on")
+	}
+
+	s.inSeq = seq + 1
+	reply := NewMessage(MsgLogon).Set(TagEncryptMethod, "0").SetInt(TagHeartBtInt, heartBtInt)
+	if err := s.send(reply); err != nil {
+		a.unregister(s)
+		return nil, err
+	}
+	s.start(time.Duration(heartBtInt) * time.Second)
+	return s, nil
+}
+
+func (a *Acceptor) unregister(s *session) {
+	a.mu.Lock()
+	if a.sessions[s.targetCompID] == s {
+		delete(a.sessions, s.targetCompID)
+	}
+	a.mu.Unlock()
+	s.close()
+}
+
+// required rejects the message if any of the tags is missing
+func required(s *session, m *Message, tags ...int) (bool, error) {
+	for _, tag := range tags {
+		if !m.Has(tag) {
+			return false, s.reject(m, tag, 1, "required tag missing")
+		}
+	}
+	return true, nil
+}
+
+func parseSide(value string) (orderbook.Side, bool) {
+	switch value {
+	case "1":
+		return orderbook.Buy, true
+	case "2":
+		return orderbook.Sell, true
+	}
+	return orderbook.Buy, false
+}
+
+func formatSide(side orderbook.Side) string {
+	if side == orderbook.Buy {
+		return "1"
+	}
+	return "2"
+}
+
+func (a *Acceptor) newOrder(s *session, m *Message) error {
+	if ok, err := required(s, m, TagClOrdID, TagSymbol, TagSide, TagOrderQty, TagOrdType); !ok {
+		return err
+	}
+
+	o := &order{
+		owner:    s.targetCompID,
+		clOrdID:  m.Get(TagClOrdID),
+		symbol:   m.Get(TagSymbol),
+		ordType:  m.Get(TagOrdType),
+		price:    decimal.Zero,
+		cum:      decimal.Zero,
+		notional: decimal.Zero,
+	}
+	o.id = o.owner + ":" + o.clOrdID
+
+	var ok bool
+	o.side, ok = parseSide(m.Get(TagSide))
+	if !ok {
+		return s.reject(m, TagSide, 5, "unsupported Side")
+	}
+	qty, err := m.Decimal(TagOrderQty)
+	if err != nil {
+		return s.reject(m, TagOrderQty, 6, "incorrect OrderQty")
+	}
+	o.quantity = qty
+
+	cmd := &orderbook.Command{Owner: o.owner, Side: o.side, Quantity: o.quantity}
+	switch o.ordType {
+	case "1":
+		cmd.Type = orderbook.CommandMarket
+	case "2":
+		if ok, err := required(s, m, TagPrice); !ok {
+			return err
+		}
+		if o.price, err = m.Decimal(TagPrice); err != nil {
+			return s.reject(m, TagPrice, 6, "incorrect Price")
+		}
+		cmd.Type = orderbook.CommandLimit
+		cmd.ID = o.id
+		cmd.Price = o.price
+	default:
+		return s.reject(m, TagOrdType, 5, "unsupported OrdType")
+	}
+
+	a.mu.Lock()
+	a.placeOrder(s, o, cmd)
+	a.mu.Unlock()
+	return s.flush()
+}
+
+// placeOrder applies the order command and queues its reports, acceptor lock is held
+func (a *Acceptor) placeOrder(s *session, o *order, cmd *orderbook.Command) {
+	if len(a.symbol) > 0 && o.symbol != a.symbol {
+		a.rejectOrder(s, o, 1, "unknown symbol")
+		return
+	}
+	if _, exists := a.clOrdIDs[clOrdKey{o.owner, o.clOrdID}]; exists {
+		a.rejectOrder(s, o, 6, orderbook.ErrOrderExists.Error())
+		return
+	}
+
+	result := a.apply(cmd)
+	if result.Err != nil {
+		reason := 0
+		if result.Err == orderbook.ErrOrderExists {
+			reason = 6
+		}
+		a.rejectOrder(s, o, reason, result.Err.Error())
+		return
+	}
+
+	o.status = statusNew
+	s.queue(a.report(o, statusNew, nil))
+	if o.ordType == "2" {
+		a.orders[o.id] = o
+		a.clOrdIDs[clOrdKey{o.owner, o.clOrdID}] = o.id
+	}
+	a.fills(s, o, result.Fills)
+
+	if o.ordType == "1" && o.status != statusFilled {
+		// market order is not rested, rest of the quantity is cancelled
+		o.status = statusCancelled
+		s.queue(a.report(o, statusCancelled, nil))
+	}
+}
+
+func (a *Acceptor) cancelOrder(s *session, m *Message) error {
+	if ok, err := required(s, m, TagOrigClOrdID, TagClOrdID); !ok {
+		return err
+	}
+
+	a.mu.Lock()
+	a.cancel(s, m)
+	a.mu.Unlock()
+	return s.flush()
+}
+
+// cancel applies the cancel request and queues its report, acceptor lock is held
+func (a *Acceptor) cancel(s *session, m *Message) {
+	o := a.orders[a.clOrdIDs[clOrdKey{s.targetCompID, m.Get(TagOrigClOrdID)}]]
+	if o == nil {
+		a.rejectCancel(s, m, nil, "1", 1, orderbook.ErrOrderNotExists.Error())
+		return
+	}
+
+	result := a.apply(&orderbook.Command{Type: orderbook.CommandCancel, ID: o.id})
+	// fills before the cancel are reported ahead of it with the right CumQty
+	a.settle(o)
+	if result.Err != nil {
+		a.rejectCancel(s, m, o, "1", 0, result.Err.Error())
+		return
+	}
+
+	a.remove(o)
+	origClOrdID := o.clOrdID
+	o.clOrdID = m.Get(TagClOrdID)
+	o.status = statusCancelled
+	s.queue(a.report(o, statusCancelled, func(r *Message) {
+		r.Set(TagOrigClOrdID, origClOrdID)
+	}))
+}
+
+func (a *Acceptor) replaceOrder(s *session, m *Message) error {
+	if ok, err := required(s, m, TagOrigClOrdID, TagClOrdID, TagOrderQty, TagPrice); !ok {
+		return err
+	}
+	qty, err := m.Decimal(TagOrderQty)
+	if err != nil {
+		return s.reject(m, TagOrderQty, 6, "incorrect OrderQty")
+	}
+	price, err := m.Decimal(TagPrice)
+	if err != nil {
+		return s.reject(m, TagPrice, 6, "incorrect Price")
+	}
+
+	a.mu.Lock()
+	a.replace(s, m, qty, price)
+	a.mu.Unlock()
+	return s.flush()
+}
+
+// replace applies the replace request and queues its reports, acceptor lock is held
+func (a *Acceptor) replace(s *session, m *Message, qty, price decimal.Decimal) {
+	o := a.orders[a.clOrdIDs[clOrdKey{s.targetCompID, m.Get(TagOrigClOrdID)}]]
+	if o == nil {
+		a.rejectCancel(s, m, nil, "2", 1, orderbook.ErrOrderNotExists.Error())
+		return
+	}
+	if _, exists := a.clOrdIDs[clOrdKey{s.targetCompID, m.Get(TagClOrdID)}]; exists {
+		a.rejectCancel(s, m, o, "2", 6, "duplicate ClOrdID")
+		return
+	}
+	// leaves quantity is computed from CumQty, so it includes all fills of the order
+	a.settle(o)
+	if !qty.GreaterThan(o.cum) {
+		a.rejectCancel(s, m, o, "2", 99, "OrderQty is not greater than CumQty")
+		return
+	}
+
+	result := a.apply(&orderbook.Command{Type: orderbook.CommandReplace, ID: o.id, Quantity: qty.Sub(o.cum), Price: price})
+	if result.Err != nil {
+		a.rejectCancel(s, m, o, "2", 0, result.Err.Error())
+		return
+	}
+
+	origClOrdID := o.clOrdID
+	delete(a.clOrdIDs, clOrdKey{o.owner, origClOrdID})
+	o.clOrdID = m.Get(TagClOrdID)
+	a.clOrdIDs[clOrdKey{o.owner, o.clOrdID}] = o.id
+	o.quantity = qty
+	o.price = price
+	s.queue(a.report(o, statusReplaced, func(r *Message) {
+		r.Set(TagOrigClOrdID, origClOrdID)
+	}))
+	a.fills(s, o, result.Fills)
+}
+
+// apply applies the command to the executor, fills are queued for the makers if the
+// executor does not notify about them
+func (a *Acceptor) apply(cmd *orderbook.Command) *orderbook.Result {
+	result := a.exec.Apply(cmd)
+	if !a.hooked {
+		for _, f := range result.Fills {
+			a.handleFill(f)
+		}
+	}
+	return result
+}
+
+// fills queues reports of the taker trades, makers are reported by dispatch
+func (a *Acceptor) fills(s *session, taker *order, fills []*orderbook.Fill) {
+	for _, f := range fills {
+		taker.fill(f)
+		s.queue(a.report(taker, execTrade, lastFill(f)))
+		if taker.status == statusFilled {
+			a.remove(taker)
+		}
+	}
+}
+
+// handleFill implements orderbook.FillHandler, it is called inside Apply of the executor,
+// so the fill is reported to the maker session later by dispatch
+func (a *Acceptor) handleFill(f *orderbook.Fill) {
+	a.fmu.Lock()
+	defer a.fmu.Unlock()
+
+	a.pending = append(a.pending, f)
+	if !a.dispatching {
+		a.dispatching = true
+		go a.dispatch()
+	}
+}
+
+// dispatch reports the pending fills to the sessions of the makers until there are no fills
+func (a *Acceptor) dispatch() {
+	for {
+		// pending fills are taken under the acceptor lock, so settle sees every fill
+		// that is not reported yet
+		a.mu.Lock()
+		a.fmu.Lock()
+		fills := a.pending
+		a.pending = nil
+		if len(fills) == 0 {
+			a.dispatching = false
+			a.fmu.Unlock()
+			a.mu.Unlock()
+			return
+		}
+		a.fmu.Unlock()
+
+		sessions := map[*session]struct{}{}
+		for _, f := range fills {
+			if ms := a.makerFill(f); ms != nil {
+				sessions[ms] = struct{}{}
+			}
+		}
+		a.mu.Unlock()
+
+		for ms := range sessions {
+			// maker session errors are handled by its own connection loop
+			ms.flush()
+		}
+	}
+}
+
+// settle reports the pending fills of the resting order before they are dispatched,
+// reports are flushed with the session of the owner, acceptor lock is held
+func (a *Acceptor) settle(o *order) {
+	a.fmu.Lock()
+	var fills []*orderbook.Fill
+	rest := a.pending[:0]
+	for _, f := range a.pending {
+		if f.MakerID == o.id {
+			fills = append(fills, f)
+		} else {
+			rest = append(rest, f)
+		}
+	}
+	a.pending = rest
+	a.fmu.Unlock()
+
+	for _, f := range fills {
+		a.makerFill(f)
+	}
+}
+
+// makerFill updates the maker of the fill and queues its report, it returns the session
+// of the maker if the report is queued, acceptor lock is held
+func (a *Acceptor) makerFill(f *orderbook.Fill) *session {
+	maker := a.orders[f.MakerID]
+	if maker == nil {
+		return nil
+	}
+	maker.fill(f)
+	if maker.status == statusFilled {
+		a.remove(maker)
+	}
+	ms := a.sessions[maker.owner]
+	if ms != nil {
+		ms.queue(a.report(maker, execTrade, lastFill(f)))
+	}
+	return ms
+}
+
+func lastFill(f *orderbook.Fill) func(*Message) {
+	return func(r *Message) {
+		r.SetDecimal(TagLastQty, f.Quantity)
+		r.SetDecimal(TagLastPx, f.Price)
+	}
+}
+
+func (o *order) fill(f *orderbook.Fill) {
+	o.cum = o.cum.Add(f.Quantity)
+	o.notional = o.notional.Add(f.Notional())
+	if o.cum.GreaterThanOrEqual(o.quantity) {
+		o.status = statusFilled
+	} else {
+		o.status = statusPartial
+	}
+}
+
+func (a *Acceptor) remove(o *order) {
+	delete(a.orders, o.id)
+	if a.clOrdIDs[clOrdKey{o.owner, o.clOrdID}] == o.id {
+		delete(a.clOrdIDs, clOrdKey{o.owner, o.clOrdID})
+	}
+}
+
+// report returns ExecutionReport with the order state, fn sets additional fields
+func (a *Acceptor) report(o *order, execType string, fn func(*Message)) *Message {
+	a.execID++
+	avgPx := decimal.Zero
+	if o.cum.Sign() > 0 {
+		avgPx = o.notional.Div(o.cum)
+	}
+	leaves := o.quantity.Sub(o.cum)
+	if o.status == statusCancelled || o.status == statusRejected {
+		leaves = decimal.Zero
+	}
+
+	r := NewMessage(MsgExecutionReport).
+		Set(TagOrderID, o.id).
+		Set(TagClOrdID, o.clOrdID).
+		Set(TagExecID, strconv.FormatUint(a.execID, 10)).
+		Set(TagExecType, execType).
+		Set(TagOrdStatus, o.status).
+		Set(TagSymbol, o.symbol).
+		Set(TagSide, formatSide(o.side)).
+		Set(TagOrdType, o.ordType).
+		SetDecimal(TagOrderQty, o.quantity).
+		SetDecimal(TagLeavesQty, leaves).
+		SetDecimal(TagCumQty, o.cum).
+		SetDecimal(TagAvgPx, avgPx).
+		Set(TagTransactTime, time.Now().UTC().Format(TimeFormat))
+	if o.ordType == "2" {
+		r.SetDecimal(TagPrice, o.price)
+	}
+	if fn != nil {
+		fn(r)
+	}
+	return r
+}
+
+func (a *Acceptor) rejectOrder(s *session, o *order, reason int, text string) {
+	o.status = statusRejected
+	if len(o.id) == 0 || a.orders[o.id] != nil {
+		o.id = "NONE"
+	}
+	s.queue(a.report(o, statusRejected, func(r *Message) {
+		r.SetInt(TagOrdRejReason, reason)
+		r.Set(TagText, text)
+	}))
+}
+
+func (a *Acceptor) rejectCancel(s *session, m *Message, o *order, responseTo string, reason int, text string) {
+	r := NewMessage(MsgOrderCancelReject).
+		Set(TagOrderID, "NONE").
+		Set(TagClOrdID, m.Get(TagClOrdID)).
+		Set(TagOrigClOrdID, m.Get(TagOrigClOrdID)).
+		Set(TagOrdStatus, statusRejected).
+		Set(TagCxlRejResponseTo, responseTo).
+		SetInt(TagCxlRejReason, reason).
+		Set(TagText, text)
+	if o != nil {
+		r.Set(TagOrderID, o.id)
+		r.Set(TagOrdStatus, o.status)
+	}
+	s.queue(r)
+}
@@ -0,0 +1,347 @@
+package fix
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/centny/orderbook/httpapi"
+	"github.com/shopspring/decimal"
+)
+
+func startAcceptor(t *testing.T) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAcceptor(orderbook.NewOrderBook(), "EXCH", "BTC-USD")
+	go a.Serve(l)
+	return l
+}
+
+func logon(t *testing.T, l net.Listener, compID string) *Client {
+	t.Helper()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(conn, compID, "EXCH")
+	if err := c.Logon(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// expect returns the next message skipping heartbeats and checks its type and fields
+func expect(t *testing.T, c *Client, msgType string, fields ...Field) *Message {
+	t.Helper()
+	for {
+		m, err := c.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Type() == MsgHeartbeat && msgType != MsgHeartbeat {
+			continue
+		}
+		if m.Type() != msgType {
+			t.Fatalf("unexpected message: %s", m)
+		}
+		for _, f := range fields {
+			if m.Get(f.Tag) != f.Value {
+				t.Fatalf("tag %d is %q, want %q: %s", f.Tag, m.Get(f.Tag), f.Value, m)
+			}
+		}
+		return m
+	}
+}
+
+func limit(clOrdID, side, qty, price string) *Message {
+	return NewMessage(MsgNewOrderSingle).
+		Set(TagClOrdID, clOrdID).
+		Set(TagSymbol, "BTC-USD").
+		Set(TagSide, side).
+		Set(TagOrderQty, qty).
+		Set(TagOrdType, "2").
+		Set(TagPrice, price)
+}
+
+func TestAcceptorOrders(t *testing.T) {
+	l := startAcceptor(t)
+	defer l.Close()
+
+	mm := logon(t, l, "MM")
+	defer mm.Close()
+	alice := logon(t, l, "ALICE")
+	defer alice.Close()
+
+	mm.Send(limit("m1", "2", "2", "100"))
+	expect(t, mm, MsgExecutionReport, Field{TagExecType, "0"}, Field{TagOrdStatus, "0"}, Field{TagOrderID, "MM:m1"}, Field{TagLeavesQty, "2"})
+
+	alice.Send(limit("a1", "1", "3", "100"))
+	expect(t, alice, MsgExecutionReport, Field{TagExecType, "0"}, Field{TagClOrdID, "a1"})
+	expect(t, alice, MsgExecutionReport, Field{TagExecType, "F"}, Field{TagOrdStatus, "1"},
+		Field{TagLastQty, "2"}, Field{TagLastPx, "100"}, Field{TagCumQty, "2"}, Field{TagLeavesQty, "1"}, Field{TagAvgPx, "100"})
+	expect(t, mm, MsgExecutionReport, Field{TagExecType, "F"}, Field{TagOrdStatus, "2"},
+		Field{TagClOrdID, "m1"}, Field{TagCumQty, "2"}, Field{TagLeavesQty, "0"})
+
+	replace := NewMessage(MsgOrderCancelReplaceRequest).
+		Set(TagOrigClOrdID, "a1").
+		Set(TagClOrdID, "a2").
+		Set(TagOrderQty, "4").
+		Set(TagPrice, "99")
+	alice.Send(replace)
+	expect(t, alice, MsgExecutionReport, Field{TagExecType, "5"}, Field{TagOrdStatus, "1"},
+		Field{TagClOrdID, "a2"}, Field{TagOrigClOrdID, "a1"}, Field{TagPrice, "99"}, Field{TagLeavesQty, "2"})
+
+	alice.Send(NewMessage(MsgOrderCancelRequest).Set(TagOrigClOrdID, "a1").Set(TagClOrdID, "a3"))
+	expect(t, alice, MsgOrderCancelReject, Field{TagCxlRejResponseTo, "1"}, Field{TagCxlRejReason, "1"})
+
+	alice.Send(NewMessage(MsgOrderCancelRequest).Set(TagOrigClOrdID, "a2").Set(TagClOrdID, "a3"))
+	expect(t, alice, MsgExecutionReport, Field{TagExecType, "4"}, Field{TagOrdStatus, "4"},
+		Field{TagClOrdID, "a3"}, Field{TagOrigClOrdID, "a2"}, Field{TagCumQty, "2"}, Field{TagLeavesQty, "0"})
+
+	mm.Send(limit("m2", "2", "1", "101"))
+	expect(t, mm, MsgExecutionReport, Field{TagExecType, "0"})
+	market := NewMessage(MsgNewOrderSingle).
+		Set(TagClOrdID, "a4").
+		Set(TagSymbol, "BTC-USD").
+		Set(TagSide, "1").
+		Set(TagOrderQty, "2").
+		Set(TagOrdType, "1")
+	alice.Send(market)
+	expect(t, alice, MsgExecutionReport, Field{TagExecType, "0"})
+	expect(t, alice, MsgExecutionReport, Field{TagExecType, "F"}, Field{TagLastPx, "101"}, Field{TagCumQty, "1"})
+	expect(t, alice, MsgExecutionReport, Field{TagExecType, "4"}, Field{TagOrdStatus, "4"}, Field{TagCumQty, "1"})
+	expect(t, mm, MsgExecutionReport, Field{TagExecType, "F"}, Field{TagClOrdID, "m2"}, Field{TagOrdStatus, "2"})
+
+	if err := alice.Logout(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcceptorRejects(t *testing.T) {
+	l := startAcceptor(t)
+	defer l.Close()
+
+	c := logon(t, l, "ALICE")
+	defer c.Close()
+
+	c.Send(limit("a1", "1", "1", "100"))
+	expect(t, c, MsgExecutionReport, Field{TagExecType, "0"})
+	c.Send(limit("a1", "1", "1", "100"))
+	expect(t, c, MsgExecutionReport, Field{TagExecType, "8"}, Field{TagOrdRejReason, "6"}, Field{TagOrderID, "NONE"})
+	c.Send(limit("a2", "1", "0", "100"))
+	expect(t, c, MsgExecutionReport, Field{TagExecType, "8"}, Field{TagText, orderbook.ErrInvalidQuantity.Error()})
+	c.Send(limit("a3", "1", "1", "100").Set(TagSymbol, "ETH-USD"))
+	expect(t, c, MsgExecutionReport, Field{TagExecType, "8"}, Field{TagOrdRejReason, "1"})
+
+	c.Send(NewMessage(MsgNewOrderSingle).Set(TagClOrdID, "a4"))
+	expect(t, c, MsgReject, Field{TagRefTagID, "55"}, Field{TagSessionRejectReason, "1"})
+	c.Send(limit("a5", "3", "1", "100"))
+	expect(t, c, MsgReject, Field{TagRefTagID, "54"})
+	c.Send(NewMessage("AE"))
+	expect(t, c, MsgReject, Field{TagSessionRejectReason, "11"})
+
+	replace := NewMessage(MsgOrderCancelReplaceRequest).
+		Set(TagOrigClOrdID, "a1").
+		Set(TagClOrdID, "a6").
+		Set(TagOrderQty, "0").
+		Set(TagPrice, "100")
+	c.Send(replace)
+	expect(t, c, MsgOrderCancelReject, Field{TagCxlRejResponseTo, "2"}, Field{TagOrdStatus, "0"})
+
+	// second session with the same CompID is logged out
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dup := NewClient(conn, "ALICE", "EXCH")
+	if err := dup.Logon(time.Second); err != ErrLoggedOut {
+		t.Fatalf("duplicate session is logged on: %v", err)
+	}
+	dup.Close()
+}
+
+func TestAcceptorSession(t *testing.T) {
+	l := startAcceptor(t)
+	defer l.Close()
+
+	c := logon(t, l, "ALICE")
+	defer c.Close()
+
+	c.Send(NewMessage(MsgTestRequest).Set(TagTestReqID, "ping"))
+	expect(t, c, MsgHeartbeat, Field{TagTestReqID, "ping"})
+
+	c.Send(NewMessage(MsgResendRequest).SetInt(TagBeginSeqNo, 1).SetInt(TagEndSeqNo, 0))
+	expect(t, c, MsgSequenceReset, Field{TagGapFillFlag, "Y"}, Field{TagMsgSeqNum, "1"}, Field{TagNewSeqNo, "3"})
+
+	// idle session gets heartbeat from the acceptor
+	m, err := c.Receive()
+	if err != nil || m.Type() != MsgHeartbeat {
+		t.Fatalf("heartbeat is not received: %v %v", m, err)
+	}
+
+	// gap in the incoming sequence is requested to be resent
+	c.s.mu.Lock()
+	next := c.s.outSeq + 1
+	c.s.outSeq += 5
+	c.s.mu.Unlock()
+	c.Send(NewMessage(MsgHeartbeat))
+	expect(t, c, MsgResendRequest, Field{TagBeginSeqNo, strconv.Itoa(next)})
+	c.Send(NewMessage(MsgSequenceReset).
+		SetInt(TagMsgSeqNum, next).
+		Set(TagPossDupFlag, "Y").
+		Set(TagGapFillFlag, "Y").
+		SetInt(TagNewSeqNo, next+5))
+
+	if err := c.Logout(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcceptorSequenceGap(t *testing.T) {
+	l := startAcceptor(t)
+	defer l.Close()
+
+	c := logon(t, l, "ALICE")
+	defer c.Close()
+
+	// a2 is ahead of the sequence, it is queued until a1 is resent
+	c.Send(limit("a2", "1", "1", "99").SetInt(TagMsgSeqNum, 3))
+	expect(t, c, MsgResendRequest, Field{TagBeginSeqNo, "2"}, Field{TagEndSeqNo, "0"})
+	c.Send(limit("a3", "1", "1", "98").SetInt(TagMsgSeqNum, 4))
+	c.Send(limit("a1", "1", "1", "100").SetInt(TagMsgSeqNum, 2).Set(TagPossDupFlag, "Y"))
+	expect(t, c, MsgExecutionReport, Field{TagExecType, "0"}, Field{TagClOrdID, "a1"})
+	expect(t, c, MsgExecutionReport, Field{TagExecType, "0"}, Field{TagClOrdID, "a2"})
+	expect(t, c, MsgExecutionReport, Field{TagExecType, "0"}, Field{TagClOrdID, "a3"})
+
+	// resent duplicates of the processed messages are dropped
+	c.Send(limit("a2", "1", "1", "99").SetInt(TagMsgSeqNum, 3).Set(TagPossDupFlag, "Y"))
+
+	// gap filled by SequenceReset releases the queued message
+	c.Send(limit("a4", "1", "1", "97").SetInt(TagMsgSeqNum, 7))
+	expect(t, c, MsgResendRequest, Field{TagBeginSeqNo, "5"})
+	c.Send(NewMessage(MsgSequenceReset).
+		SetInt(TagMsgSeqNum, 5).
+		Set(TagPossDupFlag, "Y").
+		Set(TagGapFillFlag, "Y").
+		SetInt(TagNewSeqNo, 7))
+	expect(t, c, MsgExecutionReport, Field{TagExecType, "0"}, Field{TagClOrdID, "a4"})
+
+	c.s.mu.Lock()
+	c.s.outSeq = 7
+	c.s.mu.Unlock()
+	if err := c.Logout(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcceptorGatewayFills(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	server := httpapi.NewServer(orderbook.NewOrderBook())
+	go NewAcceptor(server, "EXCH", "BTC-USD").Serve(l)
+
+	mm := logon(t, l, "MM")
+	defer mm.Close()
+	mm.Send(limit("m1", "2", "2", "100"))
+	expect(t, mm, MsgExecutionReport, Field{TagExecType, "0"}, Field{TagClOrdID, "m1"})
+
+	// taker of the other gateway fills the FIX order
+	result := server.Apply(&orderbook.Command{Type: orderbook.CommandMarket, Owner: "bob", Side: orderbook.Buy, Quantity: decimal.New(1, 0)})
+	if result.Err != nil || len(result.Fills) != 1 {
+		t.Fatalf("invalid result: %+v", result)
+	}
+	expect(t, mm, MsgExecutionReport, Field{TagExecType, "F"}, Field{TagOrdStatus, "1"},
+		Field{TagClOrdID, "m1"}, Field{TagLastQty, "1"}, Field{TagCumQty, "1"}, Field{TagLeavesQty, "1"})
+
+	// order state is current, so the replace keeps the filled quantity
+	mm.Send(NewMessage(MsgOrderCancelReplaceRequest).
+		Set(TagOrigClOrdID, "m1").
+		Set(TagClOrdID, "m2").
+		Set(TagOrderQty, "3").
+		Set(TagPrice, "101"))
+	expect(t, mm, MsgExecutionReport, Field{TagExecType, "5"}, Field{TagCumQty, "1"}, Field{TagLeavesQty, "2"})
+	var o *orderbook.Order
+	server.View(func(book *orderbook.OrderBook) { o = book.Order("MM:m1") })
+	if o == nil || !o.Quantity().Equal(decimal.New(2, 0)) {
+		t.Fatalf("invalid order: %v", o)
+	}
+
+	// CompID with the order ID separator is rejected
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(conn, "MM:m1", "EXCH")
+	if err := c.Logon(time.Second); err != ErrLoggedOut {
+		t.Fatalf("invalid CompID is logged on: %v", err)
+	}
+	c.Close()
+}
+
+func TestAcceptorPendingFills(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	server := httpapi.NewServer(orderbook.NewOrderBook())
+	a := NewAcceptor(server, "EXCH", "BTC-USD")
+	go a.Serve(l)
+
+	mm := logon(t, l, "MM")
+	defer mm.Close()
+	mm.Send(limit("m1", "2", "10", "100"))
+	expect(t, mm, MsgExecutionReport, Field{TagExecType, "0"}, Field{TagClOrdID, "m1"})
+
+	// dispatch is held back, so the fills are pending when the requests arrive
+	a.fmu.Lock()
+	a.dispatching = true
+	a.fmu.Unlock()
+	defer func() {
+		a.fmu.Lock()
+		a.dispatching = false
+		a.fmu.Unlock()
+	}()
+
+	buy := func(qty int64) {
+		t.Helper()
+		result := server.Apply(&orderbook.Command{Type: orderbook.CommandMarket, Owner: "bob", Side: orderbook.Buy, Quantity: decimal.New(qty, 0)})
+		if result.Err != nil || len(result.Fills) != 1 {
+			t.Fatalf("invalid result: %+v", result)
+		}
+	}
+
+	buy(4)
+	mm.Send(NewMessage(MsgOrderCancelReplaceRequest).
+		Set(TagOrigClOrdID, "m1").
+		Set(TagClOrdID, "m2").
+		Set(TagOrderQty, "10").
+		Set(TagPrice, "100"))
+	expect(t, mm, MsgExecutionReport, Field{TagExecType, "F"}, Field{TagClOrdID, "m1"}, Field{TagCumQty, "4"}, Field{TagLeavesQty, "6"})
+	expect(t, mm, MsgExecutionReport, Field{TagExecType, "5"}, Field{TagClOrdID, "m2"}, Field{TagCumQty, "4"}, Field{TagLeavesQty, "6"})
+	var o *orderbook.Order
+	server.View(func(book *orderbook.OrderBook) { o = book.Order("MM:m1") })
+	if o == nil || !o.Quantity().Equal(decimal.New(6, 0)) {
+		t.Fatalf("invalid order: %v", o)
+	}
+
+	buy(1)
+	mm.Send(NewMessage(MsgOrderCancelRequest).Set(TagOrigClOrdID, "m2").Set(TagClOrdID, "m3"))
+	expect(t, mm, MsgExecutionReport, Field{TagExecType, "F"}, Field{TagClOrdID, "m2"}, Field{TagCumQty, "5"}, Field{TagLeavesQty, "5"})
+	expect(t, mm, MsgExecutionReport, Field{TagExecType, "4"}, Field{TagClOrdID, "m3"}, Field{TagCumQty, "5"}, Field{TagLeavesQty, "0"})
+
+	a.fmu.Lock()
+	pending := len(a.pending)
+	a.fmu.Unlock()
+	if pending != 0 {
+		t.Fatalf("fills are pending: %d", pending)
+	}
+}
@@ -0,0 +1,75 @@
+package fix
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// Client is the initiator side of FIX session, it is used to test the gateway and by tools
+type Client struct {
+	s *session
+}
+
+// NewClient creates client over the connection
+func NewClient(conn net.Conn, senderCompID, targetCompID string) *Client {
+	return &Client{s: newSession(conn, senderCompID, targetCompID)}
+}
+
+// Logon sends Logon with the heartbeat interval and waits for the reply
+func (c *Client) Logon(heartBtInt time.Duration) error {
+	seconds := int(heartBtInt / time.Second)
+	if seconds <= 0 {
+		return errors.New("fix: heartbeat interval is less than second")
+	}
+
+	logon := NewMessage(MsgLogon).Set(TagEncryptMethod, "0").SetInt(TagHeartBtInt, seconds)
+	if err := c.s.send(logon); err != nil {
+		return err
+	}
+
+	c.s.conn.SetReadDeadline(time.Now().Add(LogonTimeout))
+	m, err := c.s.receive()
+	if err != nil {
+		return err
+	}
+	if m.Type() != MsgLogon {
+		return errors.New("fix: Logon is not confirmed")
+	}
+	c.s.start(heartBtInt)
+	return nil
+}
+
+// Send sends the message, header fields are set by the session
+func (c *Client) Send(m *Message) error {
+	return c.s.send(m)
+}
+
+// Receive returns the next message. Session level messages are handled by the client
+// and returned too. Error is ErrLoggedOut after the logout exchange
+func (c *Client) Receive() (*Message, error) {
+	return c.s.receive()
+}
+
+// Logout sends Logout and waits for the confirmation, messages received before it are dropped.
+// Connection is closed on return
+func (c *Client) Logout() error {
+	defer c.Close()
+
+	if err := c.s.logout(""); err != nil {
+		return err
+	}
+	for {
+		if _, err := c.s.receive(); err != nil {
+			if err == ErrLoggedOut {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Close closes the connection without logout
+func (c *Client) Close() error {
+	return c.s.close()
+}
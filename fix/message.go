@@ -0,0 +1,311 @@
+// Package fix implements FIX 4.4 order entry gateway of the OrderBook.
+//
+// Acceptor accepts FIX sessions (Logon, Heartbeat, TestRequest, ResendRequest, SequenceReset,
+// Logout) and translates application messages onto OrderBook commands:
+//
+//	NewOrderSingle (D)            - limit (OrdType=2) or market (OrdType=1) order
+//	OrderCancelRequest (F)        - CancelOrder
+//	OrderCancelReplaceRequest (G) - ReplaceOrder
+//
+// Results are reported with ExecutionReport (8) and OrderCancelReject (9) messages, fills of
+// resting orders are reported to the sessions of their owners. SenderCompID of the session is
+// the owner (account) of its orders. Sessions are not persisted: sequence numbers start from 1
+// on every logon and resend requests are answered with gap fill.
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+// BeginString of the supported protocol version
+const BeginString = "FIX.4.4"
+
+// MaxBodyLength limits size of the incoming message
+var MaxBodyLength = 64 * 1024
+
+// FIX errors
+var (
+	ErrInvalidMessage = errors.New("fix: invalid message")
+	ErrLoggedOut      = errors.New("fix: session is logged out")
+)
+
+// FIX tags used by the gateway
+const (
+	TagAccount             = 1
+	TagAvgPx               = 6
+	TagBeginSeqNo          = 7
+	TagBeginString         = 8
+	TagBodyLength          = 9
+	TagCheckSum            = 10
+	TagClOrdID             = 11
+	TagCumQty              = 14
+	TagEndSeqNo            = 16
+	TagExecID              = 17
+	TagLastPx              = 31
+	TagLastQty             = 32
+	TagMsgSeqNum           = 34
+	TagMsgType             = 35
+	TagNewSeqNo            = 36
+	TagOrderID             = 37
+	TagOrderQty            = 38
+	TagOrdStatus           = 39
+	TagOrdType             = 40
+	TagOrigClOrdID         = 41
+	TagPossDupFlag         = 43
+	TagPrice               = 44
+	TagRefSeqNum           = 45
+	TagSenderCompID        = 49
+	TagSendingTime         = 52
+	TagSide                = 54
+	TagSymbol              = 55
+	TagTargetCompID        = 56
+	TagText                = 58
+	TagTransactTime        = 60
+	TagEncryptMethod       = 98
+	TagCxlRejReason        = 102
+	TagOrdRejReason        = 103
+	TagHeartBtInt          = 108
+	TagTestReqID           = 112
+	TagGapFillFlag         = 123
+	TagResetSeqNumFlag     = 141
+	TagExecType            = 150
+	TagLeavesQty           = 151
+	TagRefTagID            = 371
+	TagSessionRejectReason = 373
+	TagCxlRejResponseTo    = 434
+)
+
+// Message types
+const (
+	MsgHeartbeat                 = "0"
+	MsgTestRequest               = "1"
+	MsgResendRequest             = "2"
+	MsgReject                    = "3"
+	MsgSequenceReset             = "4"
+	MsgLogout                    = "5"
+	MsgExecutionReport           = "8"
+	MsgOrderCancelReject         = "9"
+	MsgLogon                     = "A"
+	MsgNewOrderSingle            = "D"
+	MsgOrderCancelRequest        = "F"
+	MsgOrderCancelReplaceRequest = "G"
+)
+
+// header tags are encoded right after MsgType
+var headerTags = []int{TagSenderCompID, TagTargetCompID, TagMsgSeqNum, TagPossDupFlag, TagSendingTime}
+
+// Field is tag=value pair of the message
+type Field struct {
+	Tag   int
+	Value string
+}
+
+// Message is FIX message without BeginString, BodyLength and CheckSum fields,
+// they are added on encoding and checked on decoding
+type Message struct {
+	Fields []Field
+}
+
+// NewMessage creates message of the given type
+func NewMessage(msgType string) *Message {
+	return &Message{Fields: []Field{{TagMsgType, msgType}}}
+}
+
+// Type returns MsgType of the message
+func (m *Message) Type() string {
+	return m.Get(TagMsgType)
+}
+
+// Has reports whether the message contains the tag
+func (m *Message) Has(tag int) bool {
+	for _, f := range m.Fields {
+		if f.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns value of the first field with the tag or empty string
+func (m *Message) Get(tag int) string {
+	for _, f := range m.Fields {
+		if f.Tag == tag {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Set sets value of the field with the tag, field is appended if it is missing
+func (m *Message) Set(tag int, value string) *Message {
+	for i := range m.Fields {
+		if m.Fields[i].Tag == tag {
+			m.Fields[i].Value = value
+			return m
+		}
+	}
+	m.Fields = append(m.Fields, Field{tag, value})
+	return m
+}
+
+// SetInt sets integer value of the field
+func (m *Message) SetInt(tag, value int) *Message {
+	return m.Set(tag, strconv.Itoa(value))
+}
+
+// SetDecimal sets decimal value of the field
+func (m *Message) SetDecimal(tag int, value decimal.Decimal) *Message {
+	return m.Set(tag, value.String())
+}
+
+// Int returns integer value of the field
+func (m *Message) Int(tag int) (int, error) {
+	v, err := strconv.Atoi(m.Get(tag))
+	if err != nil {
+		return 0, fmt.Errorf("%w: tag %d is not integer", ErrInvalidMessage, tag)
+	}
+	return v, nil
+}
+
+// Decimal returns decimal value of the field
+func (m *Message) Decimal(tag int) (decimal.Decimal, error) {
+	v, err := decimal.NewFromString(m.Get(tag))
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("%w: tag %d is not decimal", ErrInvalidMessage, tag)
+	}
+	return v, nil
+}
+
+// String returns the message fields separated with "|"
+func (m *Message) String() string {
+	return string(bytes.ReplaceAll(m.Bytes(), []byte{1}, []byte{'|'}))
+}
+
+// Bytes encodes the message: MsgType and header fields go first, BodyLength and CheckSum are calculated
+func (m *Message) Bytes() []byte {
+	var body bytes.Buffer
+	writeField := func(f Field) {
+		body.WriteString(strconv.Itoa(f.Tag))
+		body.WriteByte('=')
+		body.WriteString(f.Value)
+		body.WriteByte(1)
+	}
+
+	writeField(Field{TagMsgType, m.Type()})
+	for _, tag := range headerTags {
+		if m.Has(tag) {
+			writeField(Field{tag, m.Get(tag)})
+		}
+	}
+	for _, f := range m.Fields {
+		if f.Tag != TagMsgType && !isHeader(f.Tag) {
+			writeField(f)
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "8=%s\x019=%d\x01", BeginString, body.Len())
+	out.Write(body.Bytes())
+	fmt.Fprintf(&out, "10=%03d\x01", checksum(out.Bytes()))
+	return out.Bytes()
+}
+
+func isHeader(tag int) bool {
+	for _, t := range headerTags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func checksum(data []byte) int {
+	sum := 0
+	for _, b := range data {
+		sum += int(b)
+	}
+	return sum % 256
+}
+
+// ReadMessage reads and checks the next message
+func ReadMessage(r *bufio.Reader) (*Message, error) {
+	var raw bytes.Buffer
+	readField := func() (Field, error) {
+		data, err := r.ReadBytes(1)
+		if err != nil {
+			if err == io.EOF && len(data) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return Field{}, err
+		}
+		raw.Write(data)
+		return parseField(data[:len(data)-1])
+	}
+
+	begin, err := readField()
+	if err != nil {
+		return nil, err
+	}
+	if begin.Tag != TagBeginString || begin.Value != BeginString {
+		return nil, fmt.Errorf("%w: unsupported begin string %q", ErrInvalidMessage, begin.Value)
+	}
+
+	length, err := readField()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(length.Value)
+	if length.Tag != TagBodyLength || err != nil || n <= 0 || n > MaxBodyLength {
+		return nil, fmt.Errorf("%w: invalid body length %q", ErrInvalidMessage, length.Value)
+	}
+
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	raw.Write(body)
+	sum := checksum(raw.Bytes())
+
+	trailer, err := readField()
+	if err != nil {
+		return nil, err
+	}
+	if trailer.Tag != TagCheckSum || trailer.Value != fmt.Sprintf("%03d", sum) {
+		return nil, fmt.Errorf("%w: invalid checksum", ErrInvalidMessage)
+	}
+
+	if len(body) == 0 || body[len(body)-1] != 1 {
+		return nil, fmt.Errorf("%w: body is not terminated", ErrInvalidMessage)
+	}
+	m := &Message{}
+	for _, data := range bytes.Split(body[:len(body)-1], []byte{1}) {
+		f, err := parseField(data)
+		if err != nil {
+			return nil, err
+		}
+		m.Fields = append(m.Fields, f)
+	}
+	if len(m.Fields) == 0 || m.Fields[0].Tag != TagMsgType {
+		return nil, fmt.Errorf("%w: MsgType is not the first body field", ErrInvalidMessage)
+	}
+	return m, nil
+}
+
+func parseField(data []byte) (Field, error) {
+	i := bytes.IndexByte(data, '=')
+	if i <= 0 {
+		return Field{}, fmt.Errorf("%w: invalid field %q", ErrInvalidMessage, data)
+	}
+	tag, err := strconv.Atoi(string(data[:i]))
+	if err != nil || tag <= 0 {
+		return Field{}, fmt.Errorf("%w: invalid tag %q", ErrInvalidMessage, data[:i])
+	}
+	return Field{tag, string(data[i+1:])}, nil
+}
@@ -0,0 +1,52 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	m := NewMessage(MsgNewOrderSingle).
+		Set(TagClOrdID, "1").
+		Set(TagSide, "1").
+		Set(TagMsgSeqNum, "7").
+		Set(TagSenderCompID, "ALICE")
+
+	data := m.Bytes()
+	want := "8=FIX.4.4|9=29|35=D|49=ALICE|34=7|11=1|54=1|10="
+	if got := strings.ReplaceAll(string(data), "\x01", "|"); !strings.HasPrefix(got, want) {
+		t.Fatalf("invalid encoding: %s", got)
+	}
+
+	restored, err := ReadMessage(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Type() != MsgNewOrderSingle || restored.Get(TagClOrdID) != "1" || restored.Get(TagSenderCompID) != "ALICE" {
+		t.Fatalf("invalid message: %s", restored)
+	}
+	if seq, err := restored.Int(TagMsgSeqNum); err != nil || seq != 7 {
+		t.Fatalf("invalid MsgSeqNum: %d %v", seq, err)
+	}
+	if _, err := restored.Decimal(TagPrice); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("missing tag is parsed: %v", err)
+	}
+}
+
+func TestReadMessageErrors(t *testing.T) {
+	valid := string(NewMessage(MsgHeartbeat).Bytes())
+	for _, data := range []string{
+		strings.Replace(valid, "FIX.4.4", "FIX.4.2", 1),
+		strings.Replace(valid, "9=5", "9=x", 1),
+		strings.Replace(valid, "35=0", "35=1", 1),
+		strings.Replace(valid, "8=", "=", 1),
+		"8=FIX.4.4\x019=5\x0155=1\x0110=" + strings.Split(valid, "10=")[1],
+	} {
+		if _, err := ReadMessage(bufio.NewReader(strings.NewReader(data))); !errors.Is(err, ErrInvalidMessage) {
+			t.Fatalf("invalid message is read: %q (%v)", data, err)
+		}
+	}
+}
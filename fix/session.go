@@ -0,0 +1,286 @@
+package fix
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TimeFormat is the format of UTCTimestamp fields
+const TimeFormat = "20060102-15:04:05.000"
+
+// WriteTimeout limits time of sending one message
+var WriteTimeout = 10 * time.Second
+
+// MaxQueued limits number of messages queued until the gap in the incoming sequence is
+// filled, the session is logged out if it is exceeded
+var MaxQueued = 1024
+
+// session implements FIX session layer over the connection: sequence numbers, heartbeats,
+// test requests, resend requests (answered with gap fill) and logout
+type session struct {
+	conn         net.Conn
+	r            *bufio.Reader
+	senderCompID string // our CompID
+	targetCompID string // counterparty CompID
+	heartBtInt   time.Duration
+
+	mu       sync.Mutex // guards conn writes and fields below
+	outSeq   int
+	lastSent time.Time
+
+	inSeq      int              // expected sequence number of the next incoming message
+	queued     map[int]*Message // messages received ahead of inSeq by sequence number
+	testReqID  string
+	loggingOut bool
+
+	qmu     sync.Mutex // guards outbox
+	outbox  []*Message // messages queued by the acceptor
+	flushMu sync.Mutex // serializes flush, so queued messages are sent in order
+
+	done chan struct{}
+	once sync.Once
+}
+
+func newSession(conn net.Conn, senderCompID, targetCompID string) *session {
+	return &session{
+		conn:         conn,
+		r:            bufio.NewReader(conn),
+		senderCompID: senderCompID,
+		targetCompID: targetCompID,
+		inSeq:        1,
+		queued:       map[int]*Message{},
+		done:         make(chan struct{}),
+	}
+}
+
+// send sets header fields of the message and writes it
+func (s *session) send(m *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !m.Has(TagMsgSeqNum) {
+		s.outSeq++
+		m.SetInt(TagMsgSeqNum, s.outSeq)
+	}
+	m.Set(TagSenderCompID, s.senderCompID)
+	m.Set(TagTargetCompID, s.targetCompID)
+	m.Set(TagSendingTime, time.Now().UTC().Format(TimeFormat))
+
+	s.conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	if _, err := s.conn.Write(m.Bytes()); err != nil {
+		return err
+	}
+	s.lastSent = time.Now()
+	return nil
+}
+
+// queue appends the message to the outbox, it is sent by flush
+func (s *session) queue(m *Message) {
+	s.qmu.Lock()
+	s.outbox = append(s.outbox, m)
+	s.qmu.Unlock()
+}
+
+// flush sends the queued messages in the order of queue calls
+func (s *session) flush() error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	for {
+		s.qmu.Lock()
+		if len(s.outbox) == 0 {
+			s.qmu.Unlock()
+			return nil
+		}
+		m := s.outbox[0]
+		s.outbox = s.outbox[1:]
+		s.qmu.Unlock()
+
+		if err := s.send(m); err != nil {
+			return err
+		}
+	}
+}
+
+// start starts sending heartbeats with the negotiated interval
+func (s *session) start(heartBtInt time.Duration) {
+	s.heartBtInt = heartBtInt
+	go func() {
+		ticker := time.NewTicker(heartBtInt / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				idle := time.Since(s.lastSent)
+				s.mu.Unlock()
+				if idle >= heartBtInt {
+					s.send(NewMessage(MsgHeartbeat))
+				}
+			}
+		}
+	}()
+}
+
+func (s *session) close() error {
+	s.once.Do(func() { close(s.done) })
+	return s.conn.Close()
+}
+
+// logout sends Logout message, the session is closed when counterparty confirms it
+func (s *session) logout(text string) error {
+	s.mu.Lock()
+	s.loggingOut = true
+	s.mu.Unlock()
+
+	m := NewMessage(MsgLogout)
+	if len(text) > 0 {
+		m.Set(TagText, text)
+	}
+	return s.send(m)
+}
+
+func (s *session) reject(ref *Message, tag, reason int, text string) error {
+	m := NewMessage(MsgReject).Set(TagRefSeqNum, ref.Get(TagMsgSeqNum))
+	if tag > 0 {
+		m.SetInt(TagRefTagID, tag)
+	}
+	m.SetInt(TagSessionRejectReason, reason)
+	return s.send(m.Set(TagText, text))
+}
+
+// receive reads the next message and handles session level messages, every message is
+// returned to the caller after handling. Messages received ahead of the expected sequence
+// number are queued, the resend of the gap is requested and the queued messages are returned
+// in order once the gap is filled. Error is ErrLoggedOut after the logout exchange
+func (s *session) receive() (*Message, error) {
+	for {
+		if m := s.queued[s.inSeq]; m != nil {
+			delete(s.queued, s.inSeq)
+			return s.handle(m)
+		}
+
+		if s.heartBtInt > 0 {
+			s.conn.SetReadDeadline(time.Now().Add(s.heartBtInt + s.heartBtInt/2))
+		}
+
+		m, err := ReadMessage(s.r)
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() && len(s.testReqID) == 0 {
+			s.testReqID = strconv.FormatInt(time.Now().UnixNano(), 10)
+			if err := s.send(NewMessage(MsgTestRequest).Set(TagTestReqID, s.testReqID)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.testReqID = ""
+
+		if m.Get(TagSenderCompID) != s.targetCompID || m.Get(TagTargetCompID) != s.senderCompID {
+			s.logout("invalid CompID")
+			return nil, fmt.Errorf("%w: invalid CompID", ErrInvalidMessage)
+		}
+
+		seq, err := m.Int(TagMsgSeqNum)
+		if err != nil {
+			s.logout("MsgSeqNum is missing")
+			return nil, err
+		}
+
+		if m.Type() == MsgSequenceReset {
+			newSeq, err := m.Int(TagNewSeqNo)
+			if err != nil || newSeq < s.inSeq {
+				s.reject(m, TagNewSeqNo, 5, "invalid NewSeqNo")
+				continue
+			}
+			s.inSeq = newSeq
+			// queued messages of the skipped range are never delivered
+			for seq := range s.queued {
+				if seq < s.inSeq {
+					delete(s.queued, seq)
+				}
+			}
+			return m, nil
+		}
+
+		switch {
+		case seq < s.inSeq && m.Get(TagPossDupFlag) == "Y":
+			continue
+		case seq < s.inSeq:
+			text := fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", s.inSeq, seq)
+			s.logout(text)
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMessage, text)
+		case seq > s.inSeq:
+			if len(s.queued) >= MaxQueued {
+				text := "too many messages out of sequence"
+				s.logout(text)
+				return nil, fmt.Errorf("%w: %s", ErrInvalidMessage, text)
+			}
+			if len(s.queued) == 0 {
+				// resend of the whole gap is requested once, later messages are queued as well
+				resend := NewMessage(MsgResendRequest).SetInt(TagBeginSeqNo, s.inSeq).SetInt(TagEndSeqNo, 0)
+				if err := s.send(resend); err != nil {
+					return nil, err
+				}
+			}
+			s.queued[seq] = m
+			continue
+		}
+		return s.handle(m)
+	}
+}
+
+// handle processes the message with the expected sequence number
+func (s *session) handle(m *Message) (*Message, error) {
+	s.inSeq++
+
+	var err error
+	switch m.Type() {
+	case MsgTestRequest:
+		err = s.send(NewMessage(MsgHeartbeat).Set(TagTestReqID, m.Get(TagTestReqID)))
+	case MsgResendRequest:
+		err = s.gapFill(m)
+	case MsgLogout:
+		s.mu.Lock()
+		loggingOut := s.loggingOut
+		s.mu.Unlock()
+		if !loggingOut {
+			s.send(NewMessage(MsgLogout))
+		}
+		return m, ErrLoggedOut
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// gapFill answers resend request: sent messages are not stored, so the whole range is skipped
+func (s *session) gapFill(m *Message) error {
+	begin, err := m.Int(TagBeginSeqNo)
+	if err != nil {
+		return s.reject(m, TagBeginSeqNo, 1, "BeginSeqNo is missing")
+	}
+
+	s.mu.Lock()
+	next := s.outSeq + 1
+	s.mu.Unlock()
+	if begin >= next {
+		return nil
+	}
+
+	return s.send(NewMessage(MsgSequenceReset).
+		SetInt(TagMsgSeqNum, begin).
+		Set(TagPossDupFlag, "Y").
+		Set(TagGapFillFlag, "Y").
+		SetInt(TagNewSeqNo, next))
+}
@@ -0,0 +1,92 @@
+package orderbook
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// fuzzBook returns the book with orders on both sides, several orders per level
+func fuzzBook(t testing.TB) *OrderBook {
+	ob := NewOrderBook()
+	for i, o := range []struct {
+		side  Side
+		id    string
+		price int64
+	}{{Sell, "a1", 101}, {Sell, "a2", 101}, {Sell, "a3", 102}, {Buy, "b1", 99}, {Buy, "b2", 98}, {Buy, "b3", 98}} {
+		if _, _, _, _, err := ob.ProcessLimitOrderFor("u", o.side, o.id, decimal.New(int64(i+1), -1), decimal.New(o.price, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ob
+}
+
+func FuzzOperations(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{opLimit, 0, 5, 3, opLimit, 3, 2, 3, opMarket, 0, 4, 0, opUndo, 0, 0, 0})
+	f.Add([]byte{opLimit, 1, 1, 10, opLimit, 3, 9, 10, opLimit, 0, 20, 8, opReplace, 1, 3, 12, opUndo, 1, 1, 0, opCancelOwner, 0, 0, 0})
+	f.Add([]byte{opLimit, 0, 7, 5, opMarketPrice, 1, 15, 9, opCancel, 0, 0, 0, opLimit, 1, 3, 5, opCommit, 0, 0, 0, opUndo, 0, 3, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		runOperations(t, decodeOperations(data))
+	})
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	data, err := fuzzBook(f).MarshalBinary()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data[len(snapshotMagic)+1 : len(data)-crc32.Size])
+	f.Add([]byte{})
+
+	// payload is wrapped with the header and checksum so the decoder of the sides is reached
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		data := append([]byte(snapshotMagic), snapshotVersion)
+		data = append(data, payload...)
+		var sum [crc32.Size]byte
+		binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(data))
+		data = append(data, sum[:]...)
+
+		ob := NewOrderBook()
+		if err := ob.UnmarshalBinary(data); err != nil {
+			return
+		}
+		if err := ob.validate(); err != nil {
+			t.Fatalf("decoded book is inconsistent: %v", err)
+		}
+
+		encoded, err := ob.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := NewOrderBook()
+		if err := decoded.UnmarshalBinary(encoded); err != nil {
+			t.Fatalf("encoded book is not decoded: %v", err)
+		}
+		if decoded.StateHash() != ob.StateHash() {
+			t.Fatal("book is changed by encoding")
+		}
+	})
+}
+
+func FuzzUnmarshalJSON(f *testing.F) {
+	data, err := json.Marshal(fuzzBook(f))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	f.Add([]byte(`{"asks":[],"bids":[]}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ob := NewOrderBook()
+		if err := json.Unmarshal(data, ob); err != nil {
+			return
+		}
+		if err := ob.validate(); err != nil {
+			t.Fatalf("decoded book is inconsistent: %v", err)
+		}
+	})
+}
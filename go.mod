@@ -1,8 +1,19 @@
 module github.com/centny/orderbook
 
-go 1.16
+go 1.18
 
 require (
 	github.com/emirpasic/gods v1.12.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/shopspring/decimal v1.2.0
+	google.golang.org/grpc v1.56.3
+	google.golang.org/protobuf v1.31.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	golang.org/x/net v0.9.0 // indirect
+	golang.org/x/sys v0.7.0 // indirect
+	golang.org/x/text v0.9.0 // indirect
+	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1 // indirect
 )
@@ -0,0 +1,336 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v23.4.0
+// source: orderbook.proto
+
+// Order entry and market data API of the OrderBook, decimal numbers are strings
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	OrderBook_PlaceOrder_FullMethodName       = "/orderbook.v1.OrderBook/PlaceOrder"
+	OrderBook_CancelOrder_FullMethodName      = "/orderbook.v1.OrderBook/CancelOrder"
+	OrderBook_ReplaceOrder_FullMethodName     = "/orderbook.v1.OrderBook/ReplaceOrder"
+	OrderBook_GetOrder_FullMethodName         = "/orderbook.v1.OrderBook/GetOrder"
+	OrderBook_GetDepth_FullMethodName         = "/orderbook.v1.OrderBook/GetDepth"
+	OrderBook_StreamMarketData_FullMethodName = "/orderbook.v1.OrderBook/StreamMarketData"
+)
+
+// OrderBookClient is the client API for OrderBook service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OrderBookClient interface {
+	// PlaceOrder processes limit or market order
+	PlaceOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*OrderResult, error)
+	// CancelOrder removes the resting order
+	CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*OrderResult, error)
+	// ReplaceOrder amends price and quantity of the resting order
+	ReplaceOrder(ctx context.Context, in *ReplaceOrderRequest, opts ...grpc.CallOption) (*OrderResult, error)
+	// GetOrder returns the resting order
+	GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*Order, error)
+	// GetDepth returns price levels
+	GetDepth(ctx context.Context, in *GetDepthRequest, opts ...grpc.CallOption) (*Depth, error)
+	// StreamMarketData sends snapshot of price levels and updates of the changed levels with trades
+	StreamMarketData(ctx context.Context, in *StreamMarketDataRequest, opts ...grpc.CallOption) (OrderBook_StreamMarketDataClient, error)
+}
+
+type orderBookClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOrderBookClient(cc grpc.ClientConnInterface) OrderBookClient {
+	return &orderBookClient{cc}
+}
+
+func (c *orderBookClient) PlaceOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*OrderResult, error) {
+	out := new(OrderResult)
+	err := c.cc.Invoke(ctx, OrderBook_PlaceOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderBookClient) CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*OrderResult, error) {
+	out := new(OrderResult)
+	err := c.cc.Invoke(ctx, OrderBook_CancelOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderBookClient) ReplaceOrder(ctx context.Context, in *ReplaceOrderRequest, opts ...grpc.CallOption) (*OrderResult, error) {
+	out := new(OrderResult)
+	err := c.cc.Invoke(ctx, OrderBook_ReplaceOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderBookClient) GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*Order, error) {
+	out := new(Order)
+	err := c.cc.Invoke(ctx, OrderBook_GetOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderBookClient) GetDepth(ctx context.Context, in *GetDepthRequest, opts ...grpc.CallOption) (*Depth, error) {
+	out := new(Depth)
+	err := c.cc.Invoke(ctx, OrderBook_GetDepth_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderBookClient) StreamMarketData(ctx context.Context, in *StreamMarketDataRequest, opts ...grpc.CallOption) (OrderBook_StreamMarketDataClient, error) {
+	stream, err := c.cc.NewStream(ctx, &OrderBook_ServiceDesc.Streams[0], OrderBook_StreamMarketData_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &orderBookStreamMarketDataClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type OrderBook_StreamMarketDataClient interface {
+	Recv() (*MarketDataUpdate, error)
+	grpc.ClientStream
+}
+
+type orderBookStreamMarketDataClient struct {
+	grpc.ClientStream
+}
+
+func (x *orderBookStreamMarketDataClient) Recv() (*MarketDataUpdate, error) {
+	m := new(MarketDataUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OrderBookServer is the server API for OrderBook service.
+// All implementations must embed UnimplementedOrderBookServer
+// for forward compatibility
+type OrderBookServer interface {
+	// PlaceOrder processes limit or market order
+	PlaceOrder(context.Context, *PlaceOrderRequest) (*OrderResult, error)
+	// CancelOrder removes the resting order
+	CancelOrder(context.Context, *CancelOrderRequest) (*OrderResult, error)
+	// ReplaceOrder amends price and quantity of the resting order
+	ReplaceOrder(context.Context, *ReplaceOrderRequest) (*OrderResult, error)
+	// GetOrder returns the resting order
+	GetOrder(context.Context, *GetOrderRequest) (*Order, error)
+	// GetDepth returns price levels
+	GetDepth(context.Context, *GetDepthRequest) (*Depth, error)
+	// StreamMarketData sends snapshot of price levels and updates of the changed levels with trades
+	StreamMarketData(*StreamMarketDataRequest, OrderBook_StreamMarketDataServer) error
+	mustEmbedUnimplementedOrderBookServer()
+}
+
+// UnimplementedOrderBookServer must be embedded to have forward compatible implementations.
+type UnimplementedOrderBookServer struct {
+}
+
+func (UnimplementedOrderBookServer) PlaceOrder(context.Context, *PlaceOrderRequest) (*OrderResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PlaceOrder not implemented")
+}
+func (UnimplementedOrderBookServer) CancelOrder(context.Context, *CancelOrderRequest) (*OrderResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrder not implemented")
+}
+func (UnimplementedOrderBookServer) ReplaceOrder(context.Context, *ReplaceOrderRequest) (*OrderResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplaceOrder not implemented")
+}
+func (UnimplementedOrderBookServer) GetOrder(context.Context, *GetOrderRequest) (*Order, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrder not implemented")
+}
+func (UnimplementedOrderBookServer) GetDepth(context.Context, *GetDepthRequest) (*Depth, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDepth not implemented")
+}
+func (UnimplementedOrderBookServer) StreamMarketData(*StreamMarketDataRequest, OrderBook_StreamMarketDataServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMarketData not implemented")
+}
+func (UnimplementedOrderBookServer) mustEmbedUnimplementedOrderBookServer() {}
+
+// UnsafeOrderBookServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OrderBookServer will
+// result in compilation errors.
+type UnsafeOrderBookServer interface {
+	mustEmbedUnimplementedOrderBookServer()
+}
+
+func RegisterOrderBookServer(s grpc.ServiceRegistrar, srv OrderBookServer) {
+	s.RegisterService(&OrderBook_ServiceDesc, srv)
+}
+
+func _OrderBook_PlaceOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlaceOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderBookServer).PlaceOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderBook_PlaceOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderBookServer).PlaceOrder(ctx, req.(*PlaceOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderBook_CancelOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderBookServer).CancelOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderBook_CancelOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderBookServer).CancelOrder(ctx, req.(*CancelOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderBook_ReplaceOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplaceOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderBookServer).ReplaceOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderBook_ReplaceOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderBookServer).ReplaceOrder(ctx, req.(*ReplaceOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderBook_GetOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderBookServer).GetOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderBook_GetOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderBookServer).GetOrder(ctx, req.(*GetOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderBook_GetDepth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDepthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderBookServer).GetDepth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderBook_GetDepth_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderBookServer).GetDepth(ctx, req.(*GetDepthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderBook_StreamMarketData_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamMarketDataRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OrderBookServer).StreamMarketData(m, &orderBookStreamMarketDataServer{stream})
+}
+
+type OrderBook_StreamMarketDataServer interface {
+	Send(*MarketDataUpdate) error
+	grpc.ServerStream
+}
+
+type orderBookStreamMarketDataServer struct {
+	grpc.ServerStream
+}
+
+func (x *orderBookStreamMarketDataServer) Send(m *MarketDataUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// OrderBook_ServiceDesc is the grpc.ServiceDesc for OrderBook service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OrderBook_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "orderbook.v1.OrderBook",
+	HandlerType: (*OrderBookServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PlaceOrder",
+			Handler:    _OrderBook_PlaceOrder_Handler,
+		},
+		{
+			MethodName: "CancelOrder",
+			Handler:    _OrderBook_CancelOrder_Handler,
+		},
+		{
+			MethodName: "ReplaceOrder",
+			Handler:    _OrderBook_ReplaceOrder_Handler,
+		},
+		{
+			MethodName: "GetOrder",
+			Handler:    _OrderBook_GetOrder_Handler,
+		},
+		{
+			MethodName: "GetDepth",
+			Handler:    _OrderBook_GetDepth_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMarketData",
+			Handler:       _OrderBook_StreamMarketData_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "orderbook.proto",
+}
@@ -0,0 +1,251 @@
+// Package grpcapi exposes the OrderBook over gRPC (see orderbook.proto): unary order entry
+// and server-streaming market data. Server shares the book and the market data feed of
+// httpapi.Server, so orders of all gateways are serialized and streamed together.
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative orderbook.proto
+
+import (
+	"context"
+	"errors"
+
+	"github.com/centny/orderbook"
+	"github.com/centny/orderbook/httpapi"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements OrderBookServer
+type Server struct {
+	UnimplementedOrderBookServer
+	gw *httpapi.Server
+}
+
+// NewServer creates Server over the book of the gateway
+func NewServer(gw *httpapi.Server) *Server {
+	return &Server{gw: gw}
+}
+
+// errorStatus converts the book error to gRPC status
+func errorStatus(err error) error {
+	code := codes.InvalidArgument
+	switch {
+	case errors.Is(err, orderbook.ErrOrderNotExists):
+		code = codes.NotFound
+	case errors.Is(err, orderbook.ErrOrderExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, orderbook.ErrHalted),
+		errors.Is(err, orderbook.ErrNotAllowed),
+		errors.Is(err, orderbook.ErrPriceBand):
+		code = codes.FailedPrecondition
+	}
+	return status.Error(code, err.Error())
+}
+
+func parseDecimal(name, value string) (decimal.Decimal, error) {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, status.Errorf(codes.InvalidArgument, "invalid %s: %q", name, value)
+	}
+	return d, nil
+}
+
+func parseSide(side Side) (orderbook.Side, error) {
+	switch side {
+	case Side_SIDE_BUY:
+		return orderbook.Buy, nil
+	case Side_SIDE_SELL:
+		return orderbook.Sell, nil
+	}
+	return orderbook.Buy, status.Error(codes.InvalidArgument, "side is not specified")
+}
+
+func apply(gw *httpapi.Server, cmd *orderbook.Command) (*OrderResult, error) {
+	result := gw.Apply(cmd)
+	if result.Err != nil {
+		return nil, errorStatus(result.Err)
+	}
+	return NewOrderResult(result), nil
+}
+
+// PlaceOrder processes limit or market order
+func (s *Server) PlaceOrder(ctx context.Context, req *PlaceOrderRequest) (*OrderResult, error) {
+	side, err := parseSide(req.Side)
+	if err != nil {
+		return nil, err
+	}
+	quantity, err := parseDecimal("quantity", req.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := &orderbook.Command{Owner: req.Owner, Side: side, Quantity: quantity}
+	switch req.Type {
+	case OrderType_ORDER_TYPE_LIMIT:
+		cmd.Type = orderbook.CommandLimit
+		cmd.ID = req.Id
+		if cmd.Price, err = parseDecimal("price", req.Price); err != nil {
+			return nil, err
+		}
+	case OrderType_ORDER_TYPE_MARKET:
+		cmd.Type = orderbook.CommandMarket
+	default:
+		return nil, status.Error(codes.InvalidArgument, "order type is not specified")
+	}
+	return apply(s.gw, cmd)
+}
+
+// CancelOrder removes the resting order
+func (s *Server) CancelOrder(ctx context.Context, req *CancelOrderRequest) (*OrderResult, error) {
+	return apply(s.gw, &orderbook.Command{Type: orderbook.CommandCancel, ID: req.Id})
+}
+
+// ReplaceOrder amends price and quantity of the resting order
+func (s *Server) ReplaceOrder(ctx context.Context, req *ReplaceOrderRequest) (*OrderResult, error) {
+	quantity, err := parseDecimal("quantity", req.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	price, err := parseDecimal("price", req.Price)
+	if err != nil {
+		return nil, err
+	}
+	return apply(s.gw, &orderbook.Command{Type: orderbook.CommandReplace, ID: req.Id, Quantity: quantity, Price: price})
+}
+
+// GetOrder returns the resting order
+func (s *Server) GetOrder(ctx context.Context, req *GetOrderRequest) (*Order, error) {
+	var order *orderbook.Order
+	s.gw.View(func(book *orderbook.OrderBook) { order = book.Order(req.Id) })
+	if order == nil {
+		return nil, errorStatus(orderbook.ErrOrderNotExists)
+	}
+	return NewOrder(order), nil
+}
+
+// GetDepth returns price levels
+func (s *Server) GetDepth(ctx context.Context, req *GetDepthRequest) (*Depth, error) {
+	if req.Max < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid max")
+	}
+
+	var depth *orderbook.Depth
+	s.gw.View(func(book *orderbook.OrderBook) { depth = book.Depth(int(req.Max)) })
+	return &Depth{Bids: newLevels(depth.Bids), Asks: newLevels(depth.Asks)}, nil
+}
+
+// StreamMarketData sends snapshot of price levels and updates of the changed levels with trades.
+// Stream is finished with ResourceExhausted status if the client is too slow
+func (s *Server) StreamMarketData(req *StreamMarketDataRequest, stream OrderBook_StreamMarketDataServer) error {
+	snapshot, updates, unsubscribe := s.gw.Subscribe()
+	defer unsubscribe()
+
+	if err := stream.Send(NewMarketDataUpdate(snapshot)); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case update, ok := <-updates:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "client is too slow, resubscribe")
+			}
+			if err := stream.Send(NewMarketDataUpdate(update)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// NewSide converts the book side to protobuf enum
+func NewSide(side orderbook.Side) Side {
+	if side == orderbook.Buy {
+		return Side_SIDE_BUY
+	}
+	return Side_SIDE_SELL
+}
+
+// NewOrder converts the book order to protobuf message
+func NewOrder(o *orderbook.Order) *Order {
+	if o == nil {
+		return nil
+	}
+	return &Order{
+		Id:       o.ID(),
+		Owner:    o.Owner(),
+		Side:     NewSide(o.Side()),
+		Quantity: o.Quantity().String(),
+		Price:    o.Price().String(),
+		Time:     timestamppb.New(o.Time()),
+	}
+}
+
+func newOrders(orders []*orderbook.Order) []*Order {
+	var result []*Order
+	for _, o := range orders {
+		result = append(result, NewOrder(o))
+	}
+	return result
+}
+
+// NewFill converts the fill to protobuf message
+func NewFill(f *orderbook.Fill) *Fill {
+	return &Fill{
+		TakerId:    f.TakerID,
+		TakerOwner: f.TakerOwner,
+		MakerId:    f.MakerID,
+		MakerOwner: f.MakerOwner,
+		Side:       NewSide(f.Side),
+		Price:      f.Price.String(),
+		Quantity:   f.Quantity.String(),
+		MakerFee:   f.MakerFee.String(),
+		TakerFee:   f.TakerFee.String(),
+		Time:       timestamppb.New(f.Time),
+	}
+}
+
+func newFills(fills []*orderbook.Fill) []*Fill {
+	var result []*Fill
+	for _, f := range fills {
+		result = append(result, NewFill(f))
+	}
+	return result
+}
+
+func newLevels(levels [][]decimal.Decimal) []*PriceLevel {
+	var result []*PriceLevel
+	for _, level := range levels {
+		result = append(result, &PriceLevel{Price: level[0].String(), Volume: level[1].String()})
+	}
+	return result
+}
+
+// NewOrderResult converts the command result to protobuf message
+func NewOrderResult(r *orderbook.Result) *OrderResult {
+	return &OrderResult{
+		Done:                     newOrders(r.Done),
+		Partial:                  NewOrder(r.Partial),
+		PartialQuantityProcessed: r.PartialQuantityProcessed.String(),
+		Left:                     r.Left.String(),
+		Cancelled:                newOrders(r.Cancelled),
+		Fills:                    newFills(r.Fills),
+	}
+}
+
+// NewMarketDataUpdate converts market data update of the gateway to protobuf message
+func NewMarketDataUpdate(u *httpapi.Update) *MarketDataUpdate {
+	t := MarketDataUpdate_TYPE_UPDATE
+	if u.Type == "snapshot" {
+		t = MarketDataUpdate_TYPE_SNAPSHOT
+	}
+	return &MarketDataUpdate{
+		Type:   t,
+		Seq:    u.Seq,
+		Bids:   newLevels(u.Bids),
+		Asks:   newLevels(u.Asks),
+		Trades: newFills(u.Trades),
+	}
+}
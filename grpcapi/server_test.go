@@ -0,0 +1,125 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/centny/orderbook"
+	"github.com/centny/orderbook/httpapi"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func dial(t *testing.T, book *orderbook.OrderBook) (OrderBookClient, func()) {
+	t.Helper()
+	l := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	RegisterOrderBookServer(srv, NewServer(httpapi.NewServer(book)))
+	go srv.Serve(l)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return l.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewOrderBookClient(conn), func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func TestServer(t *testing.T) {
+	client, stop := dial(t, orderbook.NewOrderBook())
+	defer stop()
+	ctx := context.Background()
+
+	limit := func(id string, side Side, quantity, price string) (*OrderResult, error) {
+		return client.PlaceOrder(ctx, &PlaceOrderRequest{
+			Type: OrderType_ORDER_TYPE_LIMIT, Id: id, Owner: "alice", Side: side, Quantity: quantity, Price: price,
+		})
+	}
+
+	if _, err := limit("s-100", Side_SIDE_SELL, "2", "100"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limit("s-110", Side_SIDE_SELL, "2", "110"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limit("s-110", Side_SIDE_SELL, "2", "110"); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if _, err := limit("bad", Side_SIDE_SELL, "x", "110"); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if _, err := limit("bad", Side_SIDE_UNSPECIFIED, "1", "110"); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invalid error: %v", err)
+	}
+
+	result, err := client.PlaceOrder(ctx, &PlaceOrderRequest{Type: OrderType_ORDER_TYPE_MARKET, Side: Side_SIDE_BUY, Quantity: "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Done) != 1 || len(result.Fills) != 2 || result.Fills[0].MakerOwner != "alice" ||
+		result.Partial.GetId() != "s-110" || result.PartialQuantityProcessed != "1" {
+		t.Fatalf("invalid market order result: %v", result)
+	}
+
+	order, err := client.GetOrder(ctx, &GetOrderRequest{Id: "s-110"})
+	if err != nil || order.Quantity != "1" || order.Side != Side_SIDE_SELL || order.Owner != "alice" {
+		t.Fatalf("invalid order: %v %v", order, err)
+	}
+	if _, err := client.GetOrder(ctx, &GetOrderRequest{Id: "s-100"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("invalid error: %v", err)
+	}
+
+	if _, err := client.ReplaceOrder(ctx, &ReplaceOrderRequest{Id: "s-110", Quantity: "5", Price: "120"}); err != nil {
+		t.Fatal(err)
+	}
+	depth, err := client.GetDepth(ctx, &GetDepthRequest{})
+	if err != nil || len(depth.Asks) != 1 || depth.Asks[0].Price != "120" || depth.Asks[0].Volume != "5" {
+		t.Fatalf("invalid depth: %v %v", depth, err)
+	}
+
+	result, err = client.CancelOrder(ctx, &CancelOrderRequest{Id: "s-110"})
+	if err != nil || len(result.Cancelled) != 1 || result.Cancelled[0].Id != "s-110" {
+		t.Fatalf("invalid cancel result: %v %v", result, err)
+	}
+	if _, err := client.CancelOrder(ctx, &CancelOrderRequest{Id: "s-110"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("invalid error: %v", err)
+	}
+}
+
+func TestStreamMarketData(t *testing.T) {
+	book := orderbook.NewOrderBook()
+	book.ProcessLimitOrder(orderbook.Sell, "s-100", decimal.New(2, 0), decimal.New(100, 0))
+
+	client, stop := dial(t, book)
+	defer stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamMarketData(ctx, &StreamMarketDataRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := stream.Recv()
+	if err != nil || snapshot.Type != MarketDataUpdate_TYPE_SNAPSHOT || len(snapshot.Asks) != 1 {
+		t.Fatalf("invalid snapshot: %v %v", snapshot, err)
+	}
+
+	_, err = client.PlaceOrder(ctx, &PlaceOrderRequest{Type: OrderType_ORDER_TYPE_MARKET, Side: Side_SIDE_BUY, Quantity: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	update, err := stream.Recv()
+	if err != nil || update.Type != MarketDataUpdate_TYPE_UPDATE || update.Seq != snapshot.Seq+1 ||
+		len(update.Trades) != 1 || len(update.Asks) != 1 || update.Asks[0].Volume != "1" {
+		t.Fatalf("invalid update: %v %v", update, err)
+	}
+}
@@ -0,0 +1,65 @@
+package orderbook
+
+import (
+	"crypto/sha256"
+	"hash/crc32"
+	"strings"
+)
+
+// StateHash returns SHA-256 digest of all orders of the book in price-time order
+// (asks from the lowest price, then bids from the highest price, FIFO within the level).
+// Decimals are hashed in canonical form, so the digest is the same for books with
+// equal orders regardless of the way they were built or restored
+func (ob *OrderBook) StateHash() [sha256.Size]byte {
+	h := sha256.New()
+	w := &binaryWriter{}
+
+	for _, os := range []*OrderSide{ob.asks, ob.bids} {
+		level, next := os.MinPriceQueue(), os.GreaterThan
+		if os == ob.bids {
+			level, next = os.MaxPriceQueue(), os.LessThan
+		}
+
+		w.buf = w.buf[:0]
+		w.uvarint(uint64(os.Len()))
+		h.Write(w.buf)
+
+		for ; level != nil; level = next(level.Price()) {
+			for iter := level.Head(); iter != nil; iter = iter.Next() {
+				o := iter.Value.(*Order)
+				w.buf = w.buf[:0]
+				w.string(o.ID())
+				w.string(o.Owner())
+				w.buf = append(w.buf, byte(o.Side()))
+				w.string(o.Price().String())
+				w.string(o.Quantity().String())
+				w.varint(o.Time().UnixNano())
+				h.Write(w.buf)
+			}
+		}
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// DepthChecksum returns exchange style checksum of the top levels: CRC-32 (IEEE) of
+// "bid price:bid volume:ask price:ask volume:..." string built from the best levels
+// interleaving bids and asks (missing levels are skipped), as signed 32-bit integer.
+// All levels are used if levels is not positive, like in Depth
+func (ob *OrderBook) DepthChecksum(levels int) int32 {
+	depth := ob.Depth(levels)
+
+	parts := make([]string, 0, 2*(len(depth.Bids)+len(depth.Asks)))
+	for i := 0; i < len(depth.Bids) || i < len(depth.Asks); i++ {
+		if i < len(depth.Bids) {
+			parts = append(parts, depth.Bids[i][0].String(), depth.Bids[i][1].String())
+		}
+		if i < len(depth.Asks) {
+			parts = append(parts, depth.Asks[i][0].String(), depth.Asks[i][1].String())
+		}
+	}
+
+	return int32(crc32.ChecksumIEEE([]byte(strings.Join(parts, ":"))))
+}
@@ -0,0 +1,69 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"hash/crc32"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestStateHash(t *testing.T) {
+	now := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	build := func() *OrderBook {
+		ob := NewOrderBook()
+		ob.SetClock(func() time.Time { return now })
+		addDepth(ob, "01-", decimal.New(2, 0))
+		addDepth(ob, "02-", decimal.New(1, 0))
+		return ob
+	}
+
+	a, b := build(), build()
+	if a.StateHash() != b.StateHash() {
+		t.Fatal("equal books have different hashes")
+	}
+
+	data, _ := json.Marshal(a)
+	restored := NewOrderBook()
+	json.Unmarshal(data, restored)
+	if restored.StateHash() != a.StateHash() {
+		t.Fatal("restored book has different hash")
+	}
+
+	// same levels, different time priority
+	b.CancelOrder("01-sell-100")
+	b.ProcessLimitOrder(Sell, "01-sell-100", decimal.New(2, 0), decimal.New(100, 0))
+	if a.Depth(0).String() != b.Depth(0).String() || a.StateHash() == b.StateHash() {
+		t.Fatal("queue order is not hashed")
+	}
+
+	// canonical decimals
+	c, d := NewOrderBook(), NewOrderBook()
+	c.SetClock(func() time.Time { return now })
+	d.SetClock(func() time.Time { return now })
+	c.ProcessLimitOrder(Buy, "x", decimal.RequireFromString("1.50"), decimal.New(10, 0))
+	d.ProcessLimitOrder(Buy, "x", decimal.RequireFromString("1.5"), decimal.RequireFromString("10.0"))
+	if c.StateHash() != d.StateHash() {
+		t.Fatal("hash depends on decimal representation")
+	}
+}
+
+func TestDepthChecksum(t *testing.T) {
+	ob := NewOrderBook()
+	ob.ProcessLimitOrder(Buy, "b1", decimal.New(3, 0), decimal.New(99, 0))
+	ob.ProcessLimitOrder(Sell, "s1", decimal.New(1, 0), decimal.RequireFromString("100.5"))
+	ob.ProcessLimitOrder(Sell, "s2", decimal.New(2, 0), decimal.New(101, 0))
+
+	want := int32(crc32.ChecksumIEEE([]byte("99:3:100.5:1:101:2")))
+	if sum := ob.DepthChecksum(25); sum != want {
+		t.Fatalf("invalid checksum: %d (want %d)", sum, want)
+	}
+
+	if ob.DepthChecksum(1) != int32(crc32.ChecksumIEEE([]byte("99:3:100.5:1"))) {
+		t.Fatal("invalid top level checksum")
+	}
+	if ob.DepthChecksum(-1) != ob.DepthChecksum(0) || ob.DepthChecksum(0) != ob.DepthChecksum(1000) {
+		t.Fatal("not positive levels are not all levels")
+	}
+}
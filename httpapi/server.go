@@ -0,0 +1,201 @@
+// Package httpapi exposes the OrderBook over HTTP with JSON bodies:
+//
+//	POST   /orders       - submit order, body is orderbook.Command of limit, market or market-price type
+//	DELETE /orders/{id}  - cancel order
+//	GET    /orders/{id}  - get order
+//	GET    /depth?max=N  - get price levels, all levels if max is not set
+//	GET    /snapshot     - get the book JSON snapshot
+//	GET    /stream       - WebSocket stream of depth and trades (see Update)
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/centny/orderbook"
+)
+
+// Server serializes access to the book and implements http.Handler
+type Server struct {
+	mu   sync.Mutex
+	book *orderbook.OrderBook
+	mux  *http.ServeMux
+
+	seq         uint64 // sequence number of the last market data update
+	subscribers map[*subscriber]struct{}
+}
+
+// NewServer creates Server which owns the book
+func NewServer(book *orderbook.OrderBook) *Server {
+	s := &Server{
+		book:        book,
+		mux:         http.NewServeMux(),
+		subscribers: map[*subscriber]struct{}{},
+	}
+	s.mux.HandleFunc("/orders", s.handleSubmit)
+	s.mux.HandleFunc("/orders/", s.handleOrder)
+	s.mux.HandleFunc("/depth", s.handleDepth)
+	s.mux.HandleFunc("/snapshot", s.handleSnapshot)
+	s.mux.HandleFunc("/stream", s.handleStream)
+	return s
+}
+
+// ServeHTTP implements http.Handler interface
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Handle registers additional handler on the server mux
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Apply applies the command to the book under the server lock and publishes market data
+// update to the stream subscribers, rollback of the result is removed. Time and Seq of the
+// command are cleared, so clients of the gateways can not move the book clock (e.g. to run
+// scheduled phase transitions early) or backdate their orders
+func (s *Server) Apply(cmd *orderbook.Command) *orderbook.Result {
+	c := *cmd
+	c.Time, c.Seq = time.Time{}, 0
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := s.book.Apply(&c)
+	result.Rollback = nil
+	s.publish(&c, result)
+	return result
+}
+
+// View calls fn with the book under the server lock, fn must not change the book
+func (s *Server) View(fn func(book *orderbook.OrderBook)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.book)
+}
+
+// OnFill registers handler of the book fills (see orderbook.OrderBook.OnFill), the handler is
+// called under the server lock, so it must not call the server
+func (s *Server) OnFill(handler orderbook.FillHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.book.OnFill(handler)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &errorResponse{Error: err.Error()})
+}
+
+// errorStatus returns HTTP status code of the book error
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, orderbook.ErrOrderNotExists):
+		return http.StatusNotFound
+	case errors.Is(err, orderbook.ErrOrderExists),
+		errors.Is(err, orderbook.ErrHalted),
+		errors.Is(err, orderbook.ErrNotAllowed),
+		errors.Is(err, orderbook.ErrPriceBand):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	cmd := &orderbook.Command{}
+	if err := json.NewDecoder(r.Body).Decode(cmd); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch cmd.Type {
+	case orderbook.CommandLimit, orderbook.CommandMarket, orderbook.CommandMarketPrice:
+	default:
+		writeError(w, http.StatusBadRequest, orderbook.ErrUnknownCommand)
+		return
+	}
+
+	result := s.Apply(cmd)
+	if result.Err != nil {
+		writeError(w, errorStatus(result.Err), result.Err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/orders/")
+	if len(id) == 0 || strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, orderbook.ErrOrderNotExists)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var order *orderbook.Order
+		s.View(func(book *orderbook.OrderBook) { order = book.Order(id) })
+		if order == nil {
+			writeError(w, http.StatusNotFound, orderbook.ErrOrderNotExists)
+			return
+		}
+		writeJSON(w, http.StatusOK, order)
+	case http.MethodDelete:
+		result := s.Apply(&orderbook.Command{Type: orderbook.CommandCancel, ID: id})
+		if result.Err != nil {
+			writeError(w, errorStatus(result.Err), result.Err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result.Cancelled[0])
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodDelete)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (s *Server) handleDepth(w http.ResponseWriter, r *http.Request) {
+	max := 0
+	if value := r.URL.Query().Get("max"); len(value) > 0 {
+		var err error
+		if max, err = strconv.Atoi(value); err != nil || max < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid max"))
+			return
+		}
+	}
+
+	var depth *orderbook.Depth
+	s.View(func(book *orderbook.OrderBook) { depth = book.Depth(max) })
+	writeJSON(w, http.StatusOK, depth)
+}
+
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	var data []byte
+	var err error
+	s.View(func(book *orderbook.OrderBook) { data, err = json.Marshal(book) })
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
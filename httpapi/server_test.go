@@ -0,0 +1,106 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+func request(t *testing.T, srv *httptest.Server, method, path, body string, status int, v interface{}) {
+	t.Helper()
+	req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		t.Fatalf("%s %s: status %d, want %d", method, path, resp.StatusCode, status)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	book := orderbook.NewOrderBook()
+	srv := httptest.NewServer(NewServer(book))
+	defer srv.Close()
+
+	request(t, srv, "POST", "/orders", `{"type":"limit","id":"s-100","owner":"alice","side":"sell","quantity":"2","price":"100"}`, http.StatusOK, nil)
+	request(t, srv, "POST", "/orders", `{"type":"limit","id":"s-110","side":"sell","quantity":"2","price":"110"}`, http.StatusOK, nil)
+	request(t, srv, "POST", "/orders", `{"type":"limit","id":"s-110","side":"sell","quantity":"2","price":"110"}`, http.StatusConflict, nil)
+	request(t, srv, "POST", "/orders", `{"type":"limit","id":"bad","side":"sell","quantity":"0","price":"110"}`, http.StatusBadRequest, nil)
+	request(t, srv, "POST", "/orders", `{"type":"cancel","id":"s-100"}`, http.StatusBadRequest, nil)
+	request(t, srv, "POST", "/orders", `{"type":`, http.StatusBadRequest, nil)
+	request(t, srv, "GET", "/orders", "", http.StatusMethodNotAllowed, nil)
+
+	var result struct {
+		Done  []*orderbook.Order `json:"done"`
+		Fills []*orderbook.Fill  `json:"fills"`
+	}
+	request(t, srv, "POST", "/orders", `{"type":"market","side":"buy","quantity":"3"}`, http.StatusOK, &result)
+	if len(result.Done) != 1 || len(result.Fills) != 2 || result.Fills[0].MakerOwner != "alice" {
+		t.Fatalf("invalid market order result: %+v", result)
+	}
+
+	order := &orderbook.Order{}
+	request(t, srv, "GET", "/orders/s-110", "", http.StatusOK, order)
+	if order.ID() != "s-110" || !order.Quantity().Equal(decimal.New(1, 0)) {
+		t.Fatalf("invalid order: %s", order)
+	}
+	request(t, srv, "GET", "/orders/s-100", "", http.StatusNotFound, nil)
+
+	request(t, srv, "POST", "/orders", `{"type":"limit","id":"b-90","side":"buy","quantity":"5","price":"90"}`, http.StatusOK, nil)
+	depth := &orderbook.Depth{}
+	request(t, srv, "GET", "/depth?max=1", "", http.StatusOK, depth)
+	if len(depth.Asks) != 1 || len(depth.Bids) != 1 || !depth.Bids[0][1].Equal(decimal.New(5, 0)) {
+		t.Fatalf("invalid depth: %s", depth)
+	}
+	request(t, srv, "GET", "/depth?max=x", "", http.StatusBadRequest, nil)
+
+	request(t, srv, "DELETE", "/orders/b-90", "", http.StatusOK, order)
+	if order.ID() != "b-90" {
+		t.Fatalf("invalid cancelled order: %s", order)
+	}
+	request(t, srv, "DELETE", "/orders/b-90", "", http.StatusNotFound, nil)
+
+	snapshot := orderbook.NewOrderBook()
+	request(t, srv, "GET", "/snapshot", "", http.StatusOK, snapshot)
+	if snapshot.Order("s-110") == nil || snapshot.StateHash() != book.StateHash() {
+		t.Fatal("invalid snapshot")
+	}
+
+	book.SetPhase(orderbook.Halted)
+	request(t, srv, "POST", "/orders", `{"type":"market","side":"buy","quantity":"1"}`, http.StatusConflict, nil)
+}
+
+func TestServerCommandTime(t *testing.T) {
+	now := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	book := orderbook.NewOrderBook()
+	book.SetClock(func() time.Time { return now })
+	book.SetPhase(orderbook.Closed)
+	book.SchedulePhase(now.Add(time.Hour), orderbook.Open)
+	srv := httptest.NewServer(NewServer(book))
+	defer srv.Close()
+
+	// time of the client does not run the scheduled opening
+	request(t, srv, "POST", "/orders", `{"type":"limit","id":"a","side":"sell","quantity":"1","price":"100","time":"2100-01-01T00:00:00Z","seq":7}`, http.StatusConflict, nil)
+
+	now = now.Add(2 * time.Hour)
+	request(t, srv, "POST", "/orders", `{"type":"limit","id":"a","side":"sell","quantity":"1","price":"100","time":"2000-01-01T00:00:00Z"}`, http.StatusOK, nil)
+	order := &orderbook.Order{}
+	request(t, srv, "GET", "/orders/a", "", http.StatusOK, order)
+	if !order.Time().Equal(now) {
+		t.Fatalf("order time is %s, want the book clock %s", order.Time(), now)
+	}
+}
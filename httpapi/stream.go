@@ -0,0 +1,201 @@
+package httpapi
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+// Update is the message of the market data stream. Client receives "snapshot" with all price
+// levels first and "update" messages after that. Update contains new volume of the changed
+// price levels (zero volume means the level is removed) and trades. Sequence number is
+// incremented by one for every update, client should request new snapshot (or reconnect) if
+// it detects a gap. Client requests snapshot by sending {"op":"snapshot"} message
+type Update struct {
+	Type   string              `json:"type"` // snapshot or update
+	Seq    uint64              `json:"seq"`
+	Bids   [][]decimal.Decimal `json:"bids"`
+	Asks   [][]decimal.Decimal `json:"asks"`
+	Trades []*orderbook.Fill   `json:"trades,omitempty"`
+}
+
+// SubscriberBuffer is amount of updates buffered for the stream client,
+// client which is behind more is disconnected
+var SubscriberBuffer = 1024
+
+type subscriber struct {
+	updates chan *Update
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+type level struct {
+	side  orderbook.Side
+	price decimal.Decimal
+}
+
+// publish sends update of the levels changed by the command, s.mu must be held
+func (s *Server) publish(cmd *orderbook.Command, result *orderbook.Result) {
+	var levels []level
+	for _, f := range result.Fills {
+		makerSide := orderbook.Sell
+		if f.Side == orderbook.Sell {
+			makerSide = orderbook.Buy
+		}
+		levels = append(levels, level{makerSide, f.Price})
+	}
+	if cmd.Type == orderbook.CommandLimit && result.Err == nil {
+		levels = append(levels, level{cmd.Side, cmd.Price})
+	}
+	if cmd.Type == orderbook.CommandReplace && result.Err == nil {
+		// the old level is in the cancelled orders, the order may rest at the new price
+		if o := s.book.Order(cmd.ID); o != nil {
+			levels = append(levels, level{o.Side(), o.Price()})
+		}
+	}
+	for _, o := range result.Cancelled {
+		levels = append(levels, level{o.Side(), o.Price()})
+	}
+	if len(levels) == 0 {
+		return
+	}
+
+	update := &Update{Type: "update", Trades: result.Fills}
+	seen := map[string]bool{}
+	for _, l := range levels {
+		key := l.side.String() + l.price.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		volume := decimal.Zero
+		if queue := s.book.PriceLevel(l.side, l.price); queue != nil {
+			volume = queue.Volume()
+		}
+		if l.side == orderbook.Buy {
+			update.Bids = append(update.Bids, []decimal.Decimal{l.price, volume})
+		} else {
+			update.Asks = append(update.Asks, []decimal.Decimal{l.price, volume})
+		}
+	}
+	sort.Slice(update.Bids, func(i, j int) bool { return update.Bids[i][0].GreaterThan(update.Bids[j][0]) })
+	sort.Slice(update.Asks, func(i, j int) bool { return update.Asks[i][0].LessThan(update.Asks[j][0]) })
+
+	s.seq++
+	update.Seq = s.seq
+	for sub := range s.subscribers {
+		select {
+		case sub.updates <- update:
+		default:
+			// slow client is disconnected and should resync
+			delete(s.subscribers, sub)
+			close(sub.updates)
+		}
+	}
+}
+
+// snapshot returns all price levels with the current sequence number, s.mu must be held
+func (s *Server) snapshot() *Update {
+	depth := s.book.Depth(0)
+	return &Update{
+		Type: "snapshot",
+		Seq:  s.seq,
+		Bids: depth.Bids,
+		Asks: depth.Asks,
+	}
+}
+
+func (s *Server) subscribe() (*subscriber, *Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := &subscriber{updates: make(chan *Update, SubscriberBuffer)}
+	s.subscribers[sub] = struct{}{}
+	return sub, s.snapshot()
+}
+
+// Subscribe subscribes to market data updates for other transports. Snapshot is consistent
+// with the sequence of updates, the channel is closed when the subscriber falls behind
+// by SubscriberBuffer updates or unsubscribe is called
+func (s *Server) Subscribe() (snapshot *Update, updates <-chan *Update, unsubscribe func()) {
+	sub, snapshot := s.subscribe()
+	return snapshot, sub.updates, func() { s.unsubscribe(sub) }
+}
+
+func (s *Server) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.updates)
+	}
+}
+
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sub, snapshot := s.subscribe()
+	defer s.unsubscribe(sub)
+
+	requests := make(chan struct{}, 1)
+	go func() {
+		defer close(requests)
+		for {
+			var req struct {
+				Op string `json:"op"`
+			}
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			if req.Op == "snapshot" {
+				select {
+				case requests <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	pending := []*Update{snapshot}
+	for {
+		for _, update := range pending {
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteJSON(update); err != nil {
+				return
+			}
+		}
+		pending = pending[:0]
+
+		select {
+		case update, ok := <-sub.updates:
+			if !ok {
+				return
+			}
+			pending = append(pending, update)
+		case _, ok := <-requests:
+			if !ok {
+				return
+			}
+			// snapshot is taken under the lock, so updates queued before it are dropped
+			s.mu.Lock()
+			snapshot := s.snapshot()
+			for n := len(sub.updates); n > 0; n-- {
+				<-sub.updates
+			}
+			s.mu.Unlock()
+			pending = append(pending, snapshot)
+		}
+	}
+}
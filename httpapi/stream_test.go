@@ -0,0 +1,111 @@
+package httpapi
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+func readUpdate(t *testing.T, conn *websocket.Conn) *Update {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	update := &Update{}
+	if err := conn.ReadJSON(update); err != nil {
+		t.Fatal(err)
+	}
+	return update
+}
+
+func TestStream(t *testing.T) {
+	book := orderbook.NewOrderBook()
+	book.ProcessLimitOrder(orderbook.Sell, "s-100", decimal.New(2, 0), decimal.New(100, 0))
+	book.ProcessLimitOrder(orderbook.Sell, "s-110", decimal.New(2, 0), decimal.New(110, 0))
+
+	srv := httptest.NewServer(NewServer(book))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	snapshot := readUpdate(t, conn)
+	if snapshot.Type != "snapshot" || snapshot.Seq != 0 || len(snapshot.Asks) != 2 || len(snapshot.Bids) != 0 {
+		t.Fatalf("invalid snapshot: %+v", snapshot)
+	}
+
+	request(t, srv, "POST", "/orders", `{"type":"limit","id":"b-100","side":"buy","quantity":"3","price":"100"}`, 200, nil)
+	update := readUpdate(t, conn)
+	if update.Type != "update" || update.Seq != 1 || len(update.Trades) != 1 ||
+		len(update.Asks) != 1 || !update.Asks[0][1].IsZero() ||
+		len(update.Bids) != 1 || !update.Bids[0][1].Equal(decimal.New(1, 0)) {
+		t.Fatalf("invalid update: %+v", update)
+	}
+
+	// rejected command does not change the book
+	request(t, srv, "POST", "/orders", `{"type":"limit","id":"b-100","side":"buy","quantity":"3","price":"100"}`, 409, nil)
+
+	request(t, srv, "DELETE", "/orders/s-110", "", 200, nil)
+	update = readUpdate(t, conn)
+	if update.Seq != 2 || len(update.Trades) != 0 || len(update.Asks) != 1 ||
+		!update.Asks[0][0].Equal(decimal.New(110, 0)) || !update.Asks[0][1].IsZero() {
+		t.Fatalf("invalid update: %+v", update)
+	}
+
+	if err := conn.WriteJSON(map[string]string{"op": "snapshot"}); err != nil {
+		t.Fatal(err)
+	}
+	snapshot = readUpdate(t, conn)
+	if snapshot.Type != "snapshot" || snapshot.Seq != 2 || len(snapshot.Asks) != 0 || len(snapshot.Bids) != 1 {
+		t.Fatalf("invalid snapshot: %+v", snapshot)
+	}
+}
+
+func TestStreamReplace(t *testing.T) {
+	book := orderbook.NewOrderBook()
+	book.ProcessLimitOrder(orderbook.Buy, "b", decimal.New(2, 0), decimal.New(90, 0))
+
+	server := NewServer(book)
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	readUpdate(t, conn)
+
+	server.Apply(&orderbook.Command{Type: orderbook.CommandReplace, ID: "b", Quantity: decimal.New(3, 0), Price: decimal.New(95, 0)})
+	update := readUpdate(t, conn)
+	if update.Seq != 1 || len(update.Bids) != 2 ||
+		!update.Bids[0][0].Equal(decimal.New(95, 0)) || !update.Bids[0][1].Equal(decimal.New(3, 0)) ||
+		!update.Bids[1][0].Equal(decimal.New(90, 0)) || !update.Bids[1][1].IsZero() {
+		t.Fatalf("invalid update: %+v", update)
+	}
+}
+
+func TestStreamSlowSubscriber(t *testing.T) {
+	s := NewServer(orderbook.NewOrderBook())
+	buffer := SubscriberBuffer
+	SubscriberBuffer = 1
+	defer func() { SubscriberBuffer = buffer }()
+
+	sub, _ := s.subscribe()
+	s.Apply(&orderbook.Command{Type: orderbook.CommandLimit, ID: "b-1", Side: orderbook.Buy, Quantity: decimal.New(1, 0), Price: decimal.New(1, 0)})
+	s.Apply(&orderbook.Command{Type: orderbook.CommandLimit, ID: "b-2", Side: orderbook.Buy, Quantity: decimal.New(1, 0), Price: decimal.New(2, 0)})
+
+	if _, ok := <-sub.updates; !ok {
+		t.Fatal("first update is lost")
+	}
+	if _, ok := <-sub.updates; ok {
+		t.Fatal("slow subscriber is not disconnected")
+	}
+	s.unsubscribe(sub)
+}
@@ -0,0 +1,234 @@
+// Package importer builds and evolves an OrderBook from historical vendor data: L2 snapshot and
+// update CSV files (price level sizes) and L3 CSV files (add, cancel, trade and modify of orders).
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// Errors of the importers
+var (
+	ErrInvalidRecord = errors.New("importer: invalid record")
+	ErrUnknownColumn = errors.New("importer: unknown column")
+	ErrUnknownOrder  = errors.New("importer: unknown order")
+)
+
+// Config maps the fields to columns of the file. Column is the name in the header or zero-based
+// index if it is a number, optional columns may be empty
+type Config struct {
+	Time     string // optional, book clock is used if it is empty
+	Type     string // L2 only: snapshot or update, rows of a snapshot replace the book
+	Action   string // L3 only: add, cancel, trade or modify
+	ID       string // L3 only
+	Side     string
+	Price    string
+	Quantity string
+
+	// TimeFormat is the layout of time.Parse or unix, unixms, unixus, unixns for numeric epoch
+	// times, RFC 3339 by default
+	TimeFormat string
+	// Values translate vendor values of type, action and side columns, e.g. "B": "buy", canonical
+	// values are case-insensitive
+	Values   map[string]string
+	Comma    rune // field delimiter, comma by default
+	NoHeader bool // file has no header, columns must be indexes
+}
+
+// DefaultL2Config returns config of L2 files with time,type,side,price,quantity header
+func DefaultL2Config() Config {
+	return Config{Time: "time", Type: "type", Side: "side", Price: "price", Quantity: "quantity"}
+}
+
+// DefaultL3Config returns config of L3 files with time,action,id,side,price,quantity header
+func DefaultL3Config() Config {
+	return Config{Time: "time", Action: "action", ID: "id", Side: "side", Price: "price", Quantity: "quantity"}
+}
+
+// reader reads records and resolves configured columns
+type reader struct {
+	config  Config
+	csv     *csv.Reader
+	columns map[string]int // field to column index
+	n       int            // number of the record, the header is not counted
+	record  []string
+}
+
+func newReader(r io.Reader, config Config) *reader {
+	c := csv.NewReader(r)
+	if config.Comma != 0 {
+		c.Comma = config.Comma
+	}
+	c.FieldsPerRecord = -1
+	c.TrimLeadingSpace = true
+	return &reader{config: config, csv: c}
+}
+
+// resolve maps the fields to column indexes using the header
+func (r *reader) resolve(fields map[string]string) error {
+	var header map[string]int
+	if !r.config.NoHeader {
+		names, err := r.csv.Read()
+		if err != nil {
+			return err
+		}
+		header = map[string]int{}
+		for i, name := range names {
+			header[strings.TrimSpace(name)] = i
+		}
+	}
+
+	r.columns = map[string]int{}
+	for field, column := range fields {
+		if len(column) == 0 {
+			continue
+		}
+		if i, err := strconv.Atoi(column); err == nil && i >= 0 {
+			r.columns[field] = i
+			continue
+		}
+		i, ok := header[column]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+		r.columns[field] = i
+	}
+	return nil
+}
+
+// next reads the next non-empty record
+func (r *reader) next() error {
+	for {
+		record, err := r.csv.Read()
+		if err != nil {
+			return err
+		}
+		r.n++
+		if len(record) == 1 && len(strings.TrimSpace(record[0])) == 0 {
+			continue
+		}
+		r.record = record
+		return nil
+	}
+}
+
+// errorf returns error of the current record
+func (r *reader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: record %d: %s", ErrInvalidRecord, r.n, fmt.Sprintf(format, args...))
+}
+
+// field returns value of the field, empty if the column is not configured or missing
+func (r *reader) field(name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+// value returns canonical lowercase value of the field
+func (r *reader) value(name string) string {
+	v := r.field(name)
+	if translated, ok := r.config.Values[v]; ok {
+		v = translated
+	}
+	return strings.ToLower(v)
+}
+
+func (r *reader) decimal(name string) (decimal.Decimal, error) {
+	v := r.field(name)
+	d, err := decimal.NewFromString(v)
+	if err != nil {
+		return decimal.Zero, r.errorf("invalid %s %q", name, v)
+	}
+	return d, nil
+}
+
+func (r *reader) side() (orderbook.Side, error) {
+	switch v := r.value("side"); v {
+	case "buy", "bid", "b":
+		return orderbook.Buy, nil
+	case "sell", "ask", "offer", "s", "a":
+		return orderbook.Sell, nil
+	default:
+		return orderbook.Buy, r.errorf("invalid side %q", v)
+	}
+}
+
+// time returns time of the record, zero if the column is not configured
+func (r *reader) time() (time.Time, error) {
+	v := r.field("time")
+	if _, ok := r.columns["time"]; !ok {
+		return time.Time{}, nil
+	}
+
+	var unit time.Duration
+	switch r.config.TimeFormat {
+	case "":
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return t, r.errorf("invalid time %q", v)
+		}
+		return t, nil
+	case "unix":
+		unit = time.Second
+	case "unixms":
+		unit = time.Millisecond
+	case "unixus":
+		unit = time.Microsecond
+	case "unixns":
+		unit = time.Nanosecond
+	default:
+		t, err := time.Parse(r.config.TimeFormat, v)
+		if err != nil {
+			return t, r.errorf("invalid time %q", v)
+		}
+		return t, nil
+	}
+
+	d, err := decimal.NewFromString(v)
+	if err != nil {
+		return time.Time{}, r.errorf("invalid time %q", v)
+	}
+	ns := d.Mul(decimal.NewFromInt(int64(unit))).IntPart()
+	return time.Unix(0, ns).UTC(), nil
+}
+
+// apply applies the command to the book
+func (r *reader) apply(book *orderbook.OrderBook, cmd *orderbook.Command) error {
+	if err := book.Apply(cmd).Err; err != nil {
+		return fmt.Errorf("importer: record %d: %s %s: %w", r.n, cmd.Type, cmd.ID, err)
+	}
+	return nil
+}
+
+// uncross cancels orders of the opposite side levels crossed by the price, so the order
+// placed at the price is not matched
+func (r *reader) uncross(book *orderbook.OrderBook, at time.Time, side orderbook.Side, price decimal.Decimal) error {
+	for {
+		q, crossed := book.BestAsk(), func(p decimal.Decimal) bool { return p.LessThanOrEqual(price) }
+		if side == orderbook.Sell {
+			q, crossed = book.BestBid(), func(p decimal.Decimal) bool { return p.GreaterThanOrEqual(price) }
+		}
+		if q == nil || !crossed(q.Price()) {
+			return nil
+		}
+		var ids []string
+		for e := q.Head(); e != nil; e = e.Next() {
+			ids = append(ids, e.Value.(*orderbook.Order).ID())
+		}
+		for _, id := range ids {
+			if err := r.apply(book, &orderbook.Command{Type: orderbook.CommandCancel, Time: at, ID: id}); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReaderTime(t *testing.T) {
+	expected := time.Date(2024, 1, 2, 9, 30, 0, 500000000, time.UTC)
+	for format, value := range map[string]string{
+		"":                        "2024-01-02T09:30:00.5Z",
+		"unix":                    "1704187800.5",
+		"unixms":                  "1704187800500",
+		"unixus":                  "1704187800500000",
+		"unixns":                  "1704187800500000000",
+		"2006-01-02 15:04:05.000": "2024-01-02 09:30:00.500",
+	} {
+		r := newReader(strings.NewReader("t\n"+value+"\n"), Config{TimeFormat: format})
+		if err := r.resolve(map[string]string{"time": "t"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.next(); err != nil {
+			t.Fatal(err)
+		}
+		if at, err := r.time(); err != nil || !at.Equal(expected) {
+			t.Errorf("%q %q: %s, %v", format, value, at, err)
+		}
+	}
+
+	r := newReader(strings.NewReader("t\nyesterday\n"), Config{})
+	r.resolve(map[string]string{"time": "t"})
+	r.next()
+	if _, err := r.time(); !errors.Is(err, ErrInvalidRecord) || !strings.Contains(err.Error(), "record 1") {
+		t.Fatalf("error %v", err)
+	}
+}
+
+func TestReaderColumns(t *testing.T) {
+	r := newReader(strings.NewReader("\n100;B;x\n"), Config{Comma: ';', NoHeader: true, Values: map[string]string{"B": "buy"}})
+	if err := r.resolve(map[string]string{"price": "0", "side": "1", "time": ""}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.next(); err != nil {
+		t.Fatal(err)
+	}
+	if r.n != 1 || r.field("price") != "100" || r.value("side") != "buy" || r.field("id") != "" {
+		t.Fatalf("record %d %v", r.n, r.record)
+	}
+	if at, err := r.time(); err != nil || !at.IsZero() {
+		t.Fatalf("time %s, %v", at, err)
+	}
+
+	r = newReader(strings.NewReader("price,side\n"), Config{})
+	if err := r.resolve(map[string]string{"price": "px"}); !errors.Is(err, ErrUnknownColumn) {
+		t.Fatalf("error %v", err)
+	}
+}
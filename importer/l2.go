@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// L2 applies price level records to the book, every level is a single order. Quantity is the
+// size of the level, zero removes the level. Consecutive snapshot rows with the same time replace
+// the whole book, the other rows are updates. Levels of the opposite side crossed by the update
+// are removed, the book is never matched
+type L2 struct {
+	r    *reader
+	book *orderbook.OrderBook
+	err  error
+
+	snapshot bool      // previous record is a snapshot row
+	last     time.Time // time of the previous record
+	nextID   int
+}
+
+// NewL2 creates importer of L2 records to the book
+func NewL2(r io.Reader, book *orderbook.OrderBook, config Config) *L2 {
+	l := &L2{r: newReader(r, config), book: book}
+	l.err = l.r.resolve(map[string]string{
+		"time":     config.Time,
+		"type":     config.Type,
+		"side":     config.Side,
+		"price":    config.Price,
+		"quantity": config.Quantity,
+	})
+	return l
+}
+
+// Next reads and applies the next record, error is io.EOF at the end of file
+func (l *L2) Next() error {
+	if l.err != nil {
+		return l.err
+	}
+	if err := l.r.next(); err != nil {
+		return err
+	}
+
+	at, err := l.r.time()
+	if err != nil {
+		return err
+	}
+	side, err := l.r.side()
+	if err != nil {
+		return err
+	}
+	price, err := l.r.decimal("price")
+	if err != nil {
+		return err
+	}
+	quantity, err := l.r.decimal("quantity")
+	if err != nil {
+		return err
+	}
+	if quantity.Sign() < 0 {
+		return l.r.errorf("negative quantity %s", quantity)
+	}
+
+	snapshot := false
+	switch t := l.r.value("type"); t {
+	case "snapshot", "s", "true", "1":
+		snapshot = true
+	case "update", "u", "false", "0", "":
+	default:
+		return l.r.errorf("invalid type %q", t)
+	}
+
+	if snapshot && !(l.snapshot && l.last.Equal(at)) {
+		if err := l.r.apply(l.book, &orderbook.Command{Type: orderbook.CommandCancelAll, Time: at}); err != nil {
+			return err
+		}
+	}
+	l.snapshot, l.last = snapshot, at
+	return l.level(at, side, price, quantity)
+}
+
+// level sets size of the price level
+func (l *L2) level(at time.Time, side orderbook.Side, price, quantity decimal.Decimal) error {
+	if q := l.book.PriceLevel(side, price); q != nil {
+		id := q.Head().Value.(*orderbook.Order).ID()
+		if quantity.Sign() == 0 {
+			return l.r.apply(l.book, &orderbook.Command{Type: orderbook.CommandCancel, Time: at, ID: id})
+		}
+		return l.r.apply(l.book, &orderbook.Command{Type: orderbook.CommandReplace, Time: at, ID: id, Quantity: quantity, Price: price})
+	}
+	if quantity.Sign() == 0 {
+		return nil
+	}
+
+	if err := l.r.uncross(l.book, at, side, price); err != nil {
+		return err
+	}
+
+	id := l.id()
+	return l.r.apply(l.book, &orderbook.Command{Type: orderbook.CommandLimit, Time: at, ID: id, Side: side, Quantity: quantity, Price: price})
+}
+
+// id returns the next unused order ID
+func (l *L2) id() string {
+	for {
+		l.nextID++
+		id := "L2-" + strconv.Itoa(l.nextID)
+		if l.book.Order(id) == nil {
+			return id
+		}
+	}
+}
+
+// Run applies all records and returns the number of applied records
+func (l *L2) Run() (n int, err error) {
+	for {
+		if err := l.Next(); err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+		n++
+	}
+}
@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+func levels(values ...int64) [][]decimal.Decimal {
+	var result [][]decimal.Decimal
+	for i := 0; i < len(values); i += 2 {
+		result = append(result, []decimal.Decimal{decimal.New(values[i], 0), decimal.New(values[i+1], 0)})
+	}
+	return result
+}
+
+func checkDepth(t *testing.T, book *orderbook.OrderBook, bids, asks [][]decimal.Decimal) {
+	t.Helper()
+	depth := book.Depth(0)
+	equal := func(a, b [][]decimal.Decimal) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !a[i][0].Equal(b[i][0]) || !a[i][1].Equal(b[i][1]) {
+				return false
+			}
+		}
+		return true
+	}
+	if !equal(depth.Bids, bids) || !equal(depth.Asks, asks) {
+		t.Fatalf("bids %v asks %v, expected %v %v", depth.Bids, depth.Asks, bids, asks)
+	}
+	if err := book.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestL2(t *testing.T) {
+	data := `time,type,side,price,quantity
+2024-01-02T09:30:00Z,snapshot,bid,99,5
+2024-01-02T09:30:00Z,snapshot,bid,98,7
+2024-01-02T09:30:00Z,snapshot,ask,101,4
+2024-01-02T09:30:01Z,update,bid,99,6
+2024-01-02T09:30:02Z,update,bid,98,0
+2024-01-02T09:30:03Z,update,ask,102,2
+2024-01-02T09:30:04Z,update,bid,97,0
+`
+	book := orderbook.NewOrderBook()
+	l := NewL2(strings.NewReader(data), book, DefaultL2Config())
+	if n, err := l.Run(); err != nil || n != 7 {
+		t.Fatalf("applied %d records, %v", n, err)
+	}
+	checkDepth(t, book, levels(99, 6), levels(101, 4, 102, 2))
+
+	// update crossing the asks removes them
+	l = NewL2(strings.NewReader("time,type,side,price,quantity\n2024-01-02T09:30:05Z,update,bid,101,1\n"), book, DefaultL2Config())
+	if _, err := l.Run(); err != nil {
+		t.Fatal(err)
+	}
+	checkDepth(t, book, levels(101, 1, 99, 6), levels(102, 2))
+	if len(book.Fills()) != 0 {
+		t.Fatal("book must not be matched")
+	}
+
+	// new snapshot replaces the book
+	data = `time,type,side,price,quantity
+2024-01-02T09:31:00Z,snapshot,bid,90,1
+2024-01-02T09:31:00Z,snapshot,ask,91,1
+2024-01-02T09:31:01Z,snapshot,ask,92,1
+`
+	if _, err := NewL2(strings.NewReader(data), book, DefaultL2Config()).Run(); err != nil {
+		t.Fatal(err)
+	}
+	checkDepth(t, book, nil, levels(92, 1))
+}
+
+func TestL2Updates(t *testing.T) {
+	config := Config{Side: "side", Price: "price", Quantity: "size", Values: map[string]string{"0": "buy", "1": "sell"}}
+	data := "side,price,size\n0,10,1\n1,12,1\n1,11,3\n0,10,2\n"
+	book := orderbook.NewOrderBook()
+	if _, err := NewL2(strings.NewReader(data), book, config).Run(); err != nil {
+		t.Fatal(err)
+	}
+	checkDepth(t, book, levels(10, 2), levels(11, 3, 12, 1))
+
+	for _, data := range []string{"side,price,size\n0,10,-1\n", "side,price,size\n2,10,1\n"} {
+		if _, err := NewL2(strings.NewReader(data), orderbook.NewOrderBook(), config).Run(); err == nil {
+			t.Errorf("%q must fail", data)
+		}
+	}
+	config.Type = "side"
+	if _, err := NewL2(strings.NewReader("side,price,size\n0,10,1\n"), orderbook.NewOrderBook(), config).Run(); err == nil {
+		t.Error("invalid type must fail")
+	}
+}
@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// L3 applies order-level records to the book:
+//
+//	add     new order with ID, side, quantity and price
+//	cancel  removes the order, or cancels the quantity of it if the quantity is set
+//	trade   executes the quantity of the resting order
+//	modify  replaces the order with the quantity and the price (see OrderBook.ReplaceOrder)
+//
+// Resting orders executed by trade records keep their priority, the trades are not matched
+// by the book so they produce no fills. Orders of the opposite side crossed by the price of
+// add and modify records are removed (like L2 does), the book is never matched
+type L3 struct {
+	r    *reader
+	book *orderbook.OrderBook
+	err  error
+}
+
+// NewL3 creates importer of L3 records to the book
+func NewL3(r io.Reader, book *orderbook.OrderBook, config Config) *L3 {
+	l := &L3{r: newReader(r, config), book: book}
+	l.err = l.r.resolve(map[string]string{
+		"time":     config.Time,
+		"action":   config.Action,
+		"id":       config.ID,
+		"side":     config.Side,
+		"price":    config.Price,
+		"quantity": config.Quantity,
+	})
+	return l
+}
+
+// Next reads and applies the next record, error is io.EOF at the end of file
+func (l *L3) Next() error {
+	if l.err != nil {
+		return l.err
+	}
+	if err := l.r.next(); err != nil {
+		return err
+	}
+
+	at, err := l.r.time()
+	if err != nil {
+		return err
+	}
+	id := l.r.field("id")
+	if len(id) == 0 {
+		return l.r.errorf("id is missing")
+	}
+
+	switch action := l.r.value("action"); action {
+	case "add":
+		side, err := l.r.side()
+		if err != nil {
+			return err
+		}
+		quantity, err := l.r.decimal("quantity")
+		if err != nil {
+			return err
+		}
+		price, err := l.r.decimal("price")
+		if err != nil {
+			return err
+		}
+		if err := l.r.uncross(l.book, at, side, price); err != nil {
+			return err
+		}
+		return l.r.apply(l.book, &orderbook.Command{Type: orderbook.CommandLimit, Time: at, ID: id, Side: side, Quantity: quantity, Price: price})
+	case "cancel", "trade":
+		order := l.book.Order(id)
+		if order == nil {
+			return fmt.Errorf("%w: record %d: %s of order %s", ErrUnknownOrder, l.r.n, action, id)
+		}
+		quantity := order.Quantity()
+		if action == "trade" || len(l.r.field("quantity")) > 0 {
+			if quantity, err = l.r.decimal("quantity"); err != nil {
+				return err
+			}
+		}
+		return l.reduce(at, order, quantity)
+	case "modify":
+		quantity, err := l.r.decimal("quantity")
+		if err != nil {
+			return err
+		}
+		price, err := l.r.decimal("price")
+		if err != nil {
+			return err
+		}
+		if order := l.book.Order(id); order != nil {
+			if err := l.r.uncross(l.book, at, order.Side(), price); err != nil {
+				return err
+			}
+		}
+		return l.r.apply(l.book, &orderbook.Command{Type: orderbook.CommandReplace, Time: at, ID: id, Quantity: quantity, Price: price})
+	default:
+		return l.r.errorf("unknown action %q", action)
+	}
+}
+
+// reduce removes the quantity of the order keeping its priority
+func (l *L3) reduce(at time.Time, order *orderbook.Order, quantity decimal.Decimal) error {
+	left := order.Quantity().Sub(quantity)
+	switch {
+	case quantity.Sign() <= 0 || left.Sign() < 0:
+		return l.r.errorf("quantity %s of order %s with quantity %s", quantity, order.ID(), order.Quantity())
+	case left.Sign() == 0:
+		return l.r.apply(l.book, &orderbook.Command{Type: orderbook.CommandCancel, Time: at, ID: order.ID()})
+	}
+	return l.r.apply(l.book, &orderbook.Command{Type: orderbook.CommandReplace, Time: at, ID: order.ID(), Quantity: left, Price: order.Price()})
+}
+
+// Run applies all records and returns the number of applied records
+func (l *L3) Run() (n int, err error) {
+	for {
+		if err := l.Next(); err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+		n++
+	}
+}
@@ -0,0 +1,121 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+const l3 = `time,action,id,side,price,quantity
+2024-01-02T09:30:00Z,add,1,buy,99,5
+2024-01-02T09:30:00Z,add,2,buy,99,3
+2024-01-02T09:30:01Z,add,3,sell,101,4
+2024-01-02T09:30:02Z,trade,1,,,2
+2024-01-02T09:30:03Z,cancel,3,,,1
+2024-01-02T09:30:04Z,add,4,sell,102,1
+2024-01-02T09:30:05Z,modify,4,,103,2
+2024-01-02T09:30:06Z,cancel,2,,,
+`
+
+func TestL3(t *testing.T) {
+	book := orderbook.NewOrderBook()
+	n, err := NewL3(strings.NewReader(l3), book, DefaultL3Config()).Run()
+	if err != nil || n != 8 {
+		t.Fatalf("applied %d records, %v", n, err)
+	}
+
+	if book.Order("2") != nil {
+		t.Fatal("order 2 must be cancelled")
+	}
+	o := book.Order("1")
+	if o == nil || !o.Quantity().Equal(decimal.New(3, 0)) || !o.Time().Equal(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)) {
+		t.Fatalf("order 1 %v", o)
+	}
+	if o := book.Order("3"); o == nil || !o.Quantity().Equal(decimal.New(3, 0)) {
+		t.Fatalf("order 3 %v", o)
+	}
+	if o := book.Order("4"); o == nil || !o.Price().Equal(decimal.New(103, 0)) || !o.Quantity().Equal(decimal.New(2, 0)) {
+		t.Fatalf("order 4 %v", o)
+	}
+	if len(book.Fills()) != 0 {
+		t.Fatal("trades must not be matched")
+	}
+	if err := book.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestL3Priority(t *testing.T) {
+	book := orderbook.NewOrderBook()
+	if _, err := NewL3(strings.NewReader(l3[:strings.Index(l3, "2024-01-02T09:30:01Z")]+"2024-01-02T09:30:01Z,trade,1,,,1\n"), book, DefaultL3Config()).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if rank, _, err := book.QueuePosition("1"); err != nil || rank != 1 {
+		t.Fatalf("executed order must keep priority, rank %d, %v", rank, err)
+	}
+}
+
+func TestL3Crossed(t *testing.T) {
+	records := `time,action,id,side,price,quantity
+2024-01-02T09:30:00Z,add,1,sell,100,1
+2024-01-02T09:30:00Z,add,2,sell,101,1
+2024-01-02T09:30:00Z,add,3,sell,102,1
+2024-01-02T09:30:01Z,add,4,buy,100,2
+2024-01-02T09:30:02Z,add,5,buy,99,1
+2024-01-02T09:30:03Z,modify,5,,101,1
+`
+	book := orderbook.NewOrderBook()
+	if _, err := NewL3(strings.NewReader(records), book, DefaultL3Config()).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if book.Order("1") != nil || book.Order("2") != nil || book.Order("3") == nil {
+		t.Fatal("crossed orders must be removed")
+	}
+	if o := book.Order("4"); o == nil || !o.Quantity().Equal(decimal.New(2, 0)) {
+		t.Fatalf("order 4 %v", o)
+	}
+	if o := book.Order("5"); o == nil || !o.Price().Equal(decimal.New(101, 0)) {
+		t.Fatalf("order 5 %v", o)
+	}
+	if len(book.Fills()) != 0 || book.LastPrice().Sign() != 0 {
+		t.Fatal("crossed orders must not be matched")
+	}
+}
+
+func TestL3Config(t *testing.T) {
+	config := Config{
+		Time: "0", Action: "1", ID: "2", Side: "3", Price: "4", Quantity: "5",
+		TimeFormat: "unixms",
+		Values:     map[string]string{"A": "add", "D": "cancel", "E": "trade", "B": "buy", "S": "sell"},
+		NoHeader:   true,
+	}
+	data := "1704187800000,A,x,B,10,2\n1704187800001,A,y,S,11,2\n1704187800002,E,y,,,1\n"
+	book := orderbook.NewOrderBook()
+	if n, err := NewL3(strings.NewReader(data), book, config).Run(); err != nil || n != 3 {
+		t.Fatalf("applied %d records, %v", n, err)
+	}
+	if o := book.Order("y"); o == nil || !o.Quantity().Equal(decimal.New(1, 0)) {
+		t.Fatalf("order y %v", o)
+	}
+}
+
+func TestL3Errors(t *testing.T) {
+	for data, expected := range map[string]error{
+		"time,action,id,side,price,quantity\n2024-01-02T09:30:00Z,cancel,1,,,\n":                                       ErrUnknownOrder,
+		"time,action,id,side,price,quantity\n2024-01-02T09:30:00Z,add,1,up,1,1\n":                                      ErrInvalidRecord,
+		"time,action,id,side,price,quantity\n2024-01-02T09:30:00Z,add,,buy,1,1\n":                                      ErrInvalidRecord,
+		"time,action,id,side,price,quantity\n2024-01-02T09:30:00Z,fill,1,buy,1,1\n":                                    ErrInvalidRecord,
+		"time,action,id,side,price,quantity\n2024-01-02T09:30:00Z,add,1,buy,x,1\n":                                     ErrInvalidRecord,
+		"time,action,id,side,price,quantity\n2024-01-02T09:30:00Z,add,1,buy,1,1\n2024-01-02T09:30:01Z,trade,1,,,2\n":   ErrInvalidRecord,
+		"time,action,id,side,price,quantity\n2024-01-02T09:30:00Z,add,1,buy,1,1\n2024-01-02T09:30:01Z,add,1,buy,1,1\n": orderbook.ErrOrderExists,
+		"time,kind\n": ErrUnknownColumn,
+	} {
+		if _, err := NewL3(strings.NewReader(data), orderbook.NewOrderBook(), DefaultL3Config()).Run(); !errors.Is(err, expected) {
+			t.Errorf("%q: error %v, expected %v", data, err, expected)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package itch
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/centny/orderbook"
+)
+
+// Decoder reads messages and applies them to the book, orders get reference numbers as IDs
+type Decoder struct {
+	r    io.Reader
+	book *orderbook.OrderBook
+
+	PriceScale    int32
+	QuantityScale int32
+	Date          time.Time // midnight UTC of the day of the timestamps, current day by default
+}
+
+// NewDecoder creates decoder which rebuilds the book
+func NewDecoder(r io.Reader, book *orderbook.OrderBook) *Decoder {
+	y, m, d := time.Now().UTC().Date()
+	return &Decoder{
+		r:             r,
+		book:          book,
+		PriceScale:    DefaultScale,
+		QuantityScale: DefaultScale,
+		Date:          time.Date(y, m, d, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// Decode reads the next message and applies it to the book, error is io.EOF at the end of stream
+func (d *Decoder) Decode() (*Message, error) {
+	m, err := ReadMessage(d.r)
+	if err != nil {
+		return nil, err
+	}
+	return m, d.Apply(m)
+}
+
+// Apply applies the message to the book
+func (d *Decoder) Apply(m *Message) error {
+	at := d.Date.Add(m.Timestamp)
+	id := strconv.FormatUint(m.Ref, 10)
+
+	switch m.Type {
+	case AddOrder:
+		return d.add(at, id, m.Side, m.Quantity, m.Price)
+	case OrderExecuted, OrderCancel:
+		order := d.book.Order(id)
+		if order == nil {
+			return fmt.Errorf("%w: %d", ErrUnknownOrder, m.Ref)
+		}
+		left := order.Quantity().Sub(fromFixed(m.Quantity, d.QuantityScale))
+		switch left.Sign() {
+		case -1:
+			return fmt.Errorf("%w: quantity of %q message is more than order %d quantity", ErrInvalidMessage, m.Type, m.Ref)
+		case 0:
+			return d.apply(&orderbook.Command{Type: orderbook.CommandCancel, Time: at, ID: id})
+		}
+		return d.apply(&orderbook.Command{Type: orderbook.CommandReplace, Time: at, ID: id, Quantity: left, Price: order.Price()})
+	case OrderDelete:
+		return d.apply(&orderbook.Command{Type: orderbook.CommandCancel, Time: at, ID: id})
+	case OrderReplace:
+		order := d.book.Order(id)
+		if order == nil {
+			return fmt.Errorf("%w: %d", ErrUnknownOrder, m.Ref)
+		}
+		if err := d.apply(&orderbook.Command{Type: orderbook.CommandCancel, Time: at, ID: id}); err != nil {
+			return err
+		}
+		return d.add(at, strconv.FormatUint(m.NewRef, 10), order.Side(), m.Quantity, m.Price)
+	}
+	return fmt.Errorf("%w: unknown type %q", ErrInvalidMessage, m.Type)
+}
+
+func (d *Decoder) add(at time.Time, id string, side orderbook.Side, quantity, price int64) error {
+	return d.apply(&orderbook.Command{
+		Type:     orderbook.CommandLimit,
+		Time:     at,
+		ID:       id,
+		Side:     side,
+		Quantity: fromFixed(quantity, d.QuantityScale),
+		Price:    fromFixed(price, d.PriceScale),
+	})
+}
+
+func (d *Decoder) apply(cmd *orderbook.Command) error {
+	if err := d.book.Apply(cmd).Err; err != nil {
+		return fmt.Errorf("itch: %s of order %s: %w", cmd.Type, cmd.ID, err)
+	}
+	return nil
+}
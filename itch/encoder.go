@@ -0,0 +1,235 @@
+package itch
+
+import (
+	"io"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultScale is the default number of decimal places of prices and quantities
+const DefaultScale = 8
+
+// Encoder writes order-level events of the book. The book must be encoded with EncodeBook
+// before the first command if it is not empty, then every applied command should be encoded
+// in the same order with its result
+type Encoder struct {
+	w      io.Writer
+	symbol string
+
+	Locate        uint16
+	PriceScale    int32
+	QuantityScale int32
+
+	orders  map[string]*resting
+	nextRef uint64
+	match   uint64
+}
+
+// resting tracks reference and quantity left of the resting order
+type resting struct {
+	ref      uint64
+	quantity decimal.Decimal
+}
+
+// NewEncoder creates encoder of the book with the symbol
+func NewEncoder(w io.Writer, symbol string) *Encoder {
+	return &Encoder{
+		w:             w,
+		symbol:        symbol,
+		PriceScale:    DefaultScale,
+		QuantityScale: DefaultScale,
+		orders:        map[string]*resting{},
+	}
+}
+
+// EncodeBook writes AddOrder messages for all resting orders of the book in price-time priority
+func (e *Encoder) EncodeBook(book *orderbook.OrderBook) error {
+	var messages []*Message
+	depth := book.Depth(0)
+	for _, side := range []struct {
+		side   orderbook.Side
+		levels [][]decimal.Decimal
+	}{{orderbook.Sell, depth.Asks}, {orderbook.Buy, depth.Bids}} {
+		for _, level := range side.levels {
+			for el := book.PriceLevel(side.side, level[0]).Head(); el != nil; el = el.Next() {
+				o := el.Value.(*orderbook.Order)
+				m, err := e.add(Timestamp(o.Time()), o.ID(), o.Side(), o.Quantity(), o.Price())
+				if err != nil {
+					return err
+				}
+				messages = append(messages, m)
+			}
+		}
+	}
+	return e.write(messages)
+}
+
+// Encode writes events of the applied command, nothing is written if the result has error.
+// Encoder must not be used after error, it is out of sync with the book
+func (e *Encoder) Encode(cmd *orderbook.Command, result *orderbook.Result) error {
+	if result.Err != nil {
+		return nil
+	}
+
+	at := cmd.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	ts := Timestamp(at)
+
+	var messages []*Message
+	// replaced order is removed or amended after the executions of the new order
+	var replaced *orderbook.Order
+	for _, o := range result.Cancelled {
+		if cmd.Type == orderbook.CommandReplace {
+			replaced = o
+			continue
+		}
+		m, err := e.delete(ts, o.ID())
+		if err != nil {
+			return err
+		}
+		messages = append(messages, m)
+	}
+
+	executed := decimal.Zero
+	for _, f := range result.Fills {
+		m, err := e.execute(f)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, m)
+		executed = executed.Add(f.Quantity)
+	}
+
+	switch cmd.Type {
+	case orderbook.CommandLimit:
+		if left := cmd.Quantity.Sub(executed); left.Sign() > 0 {
+			m, err := e.add(ts, cmd.ID, cmd.Side, left, cmd.Price)
+			if err != nil {
+				return err
+			}
+			messages = append(messages, m)
+		}
+	case orderbook.CommandReplace:
+		if replaced == nil {
+			break
+		}
+		m, err := e.replace(ts, replaced, cmd.Quantity.Sub(executed), cmd.Price)
+		if err != nil {
+			return err
+		}
+		if m != nil {
+			messages = append(messages, m)
+		}
+	}
+	return e.write(messages)
+}
+
+func (e *Encoder) write(messages []*Message) error {
+	for _, m := range messages {
+		if err := WriteMessage(e.w, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) header(t byte, ts time.Duration) *Message {
+	return &Message{Type: t, Locate: e.Locate, Timestamp: ts}
+}
+
+func (e *Encoder) add(ts time.Duration, id string, side orderbook.Side, quantity, price decimal.Decimal) (*Message, error) {
+	m := e.header(AddOrder, ts)
+	var err error
+	if m.Quantity, err = toFixed(quantity, e.QuantityScale); err != nil {
+		return nil, err
+	}
+	if m.Price, err = toFixed(price, e.PriceScale); err != nil {
+		return nil, err
+	}
+
+	e.nextRef++
+	m.Ref = e.nextRef
+	m.Side = side
+	m.Symbol = e.symbol
+	e.orders[id] = &resting{ref: m.Ref, quantity: quantity}
+	return m, nil
+}
+
+func (e *Encoder) execute(f *orderbook.Fill) (*Message, error) {
+	o, ok := e.orders[f.MakerID]
+	if !ok {
+		return nil, ErrUnknownOrder
+	}
+
+	m := e.header(OrderExecuted, Timestamp(f.Time))
+	var err error
+	if m.Quantity, err = toFixed(f.Quantity, e.QuantityScale); err != nil {
+		return nil, err
+	}
+
+	e.match++
+	m.Ref = o.ref
+	m.Match = e.match
+	if o.quantity = o.quantity.Sub(f.Quantity); o.quantity.Sign() <= 0 {
+		delete(e.orders, f.MakerID)
+	}
+	return m, nil
+}
+
+func (e *Encoder) delete(ts time.Duration, id string) (*Message, error) {
+	o, ok := e.orders[id]
+	if !ok {
+		return nil, ErrUnknownOrder
+	}
+
+	m := e.header(OrderDelete, ts)
+	m.Ref = o.ref
+	delete(e.orders, id)
+	return m, nil
+}
+
+// replace encodes replace of the order with quantity left after executions, the order keeps
+// its reference if it is reduced in place (see OrderBook.ReplaceOrder)
+func (e *Encoder) replace(ts time.Duration, old *orderbook.Order, left, price decimal.Decimal) (*Message, error) {
+	o, ok := e.orders[old.ID()]
+	if !ok {
+		return nil, ErrUnknownOrder
+	}
+
+	if left.Sign() <= 0 {
+		return e.delete(ts, old.ID())
+	}
+
+	if price.Equal(old.Price()) && left.LessThanOrEqual(old.Quantity()) {
+		if left.Equal(old.Quantity()) {
+			return nil, nil
+		}
+		m := e.header(OrderCancel, ts)
+		var err error
+		if m.Quantity, err = toFixed(old.Quantity().Sub(left), e.QuantityScale); err != nil {
+			return nil, err
+		}
+		m.Ref = o.ref
+		o.quantity = left
+		return m, nil
+	}
+
+	m := e.header(OrderReplace, ts)
+	var err error
+	if m.Quantity, err = toFixed(left, e.QuantityScale); err != nil {
+		return nil, err
+	}
+	if m.Price, err = toFixed(price, e.PriceScale); err != nil {
+		return nil, err
+	}
+
+	e.nextRef++
+	m.Ref = o.ref
+	m.NewRef = e.nextRef
+	e.orders[old.ID()] = &resting{ref: m.NewRef, quantity: left}
+	return m, nil
+}
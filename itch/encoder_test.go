@@ -0,0 +1,94 @@
+package itch
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	book := orderbook.NewOrderBook()
+	book.ProcessLimitOrderFor("mm", orderbook.Sell, "s-100", decimal.New(2, 0), decimal.New(100, 0))
+	book.ProcessLimitOrderFor("mm", orderbook.Sell, "s-100-2", decimal.New(15, -1), decimal.New(100, 0))
+	book.ProcessLimitOrderFor("mm", orderbook.Buy, "b-90", decimal.New(3, 0), decimal.New(90, 0))
+
+	var stream bytes.Buffer
+	enc := NewEncoder(&stream, "BTC-USD")
+	if err := enc.EncodeBook(book); err != nil {
+		t.Fatal(err)
+	}
+
+	replica := orderbook.NewOrderBook()
+	dec := NewDecoder(&stream, replica)
+	decode := func() {
+		t.Helper()
+		for {
+			if _, err := dec.Decode(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got, want := replica.Depth(0).String(), book.Depth(0).String(); got != want {
+			t.Fatalf("invalid replica depth:\n%s\nwant:\n%s", got, want)
+		}
+	}
+	decode()
+
+	start := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	dec.Date = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	commands := []*orderbook.Command{
+		{Type: orderbook.CommandLimit, ID: "b-100", Owner: "alice", Side: orderbook.Buy, Quantity: decimal.New(25, -1), Price: decimal.New(100, 0)},
+		{Type: orderbook.CommandLimit, ID: "b-95", Owner: "alice", Side: orderbook.Buy, Quantity: decimal.New(4, 0), Price: decimal.New(95, 0)},
+		{Type: orderbook.CommandReplace, ID: "b-95", Quantity: decimal.New(3, 0), Price: decimal.New(95, 0)},
+		{Type: orderbook.CommandReplace, ID: "b-90", Quantity: decimal.New(5, 0), Price: decimal.New(91, 0)},
+		{Type: orderbook.CommandLimit, ID: "s-120", Owner: "mm", Side: orderbook.Sell, Quantity: decimal.New(1, 0), Price: decimal.New(120, 0)},
+		{Type: orderbook.CommandReplace, ID: "s-120", Quantity: decimal.New(5, 0), Price: decimal.New(94, 0)},
+		{Type: orderbook.CommandMarket, Side: orderbook.Buy, Quantity: decimal.New(1, 0)},
+		{Type: orderbook.CommandCancel, ID: "b-95"},
+		{Type: orderbook.CommandCancel, ID: "none"},
+		{Type: orderbook.CommandLimit, ID: "s-80", Owner: "mm", Side: orderbook.Sell, Quantity: decimal.New(10, 0), Price: decimal.New(80, 0)},
+		{Type: orderbook.CommandCancelAll, Owner: "mm"},
+	}
+	for i, cmd := range commands {
+		cmd.Time = start.Add(time.Duration(i) * time.Millisecond)
+		if err := enc.Encode(cmd, book.Apply(cmd)); err != nil {
+			t.Fatalf("command %d: %v", i, err)
+		}
+		decode()
+	}
+
+	if len(replica.Depth(0).Bids)+len(replica.Depth(0).Asks) != 0 {
+		t.Fatalf("replica is not empty: %s", replica.Depth(0))
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	book := orderbook.NewOrderBook()
+	book.ProcessLimitOrder(orderbook.Sell, "s-100", decimal.New(1, 0), decimal.New(100, 0))
+
+	enc := NewEncoder(io.Discard, "BTC-USD")
+	cmd := &orderbook.Command{Type: orderbook.CommandMarket, Side: orderbook.Buy, Quantity: decimal.New(1, 0)}
+	if err := enc.Encode(cmd, book.Apply(cmd)); !errors.Is(err, ErrUnknownOrder) {
+		t.Fatalf("execution of unknown order is encoded: %v", err)
+	}
+
+	enc.PriceScale = 0
+	cmd = &orderbook.Command{Type: orderbook.CommandLimit, ID: "b-1", Side: orderbook.Buy, Quantity: decimal.New(1, 0), Price: decimal.New(15, -1)}
+	if err := enc.Encode(cmd, book.Apply(cmd)); !errors.Is(err, ErrPrecision) {
+		t.Fatalf("price is rounded: %v", err)
+	}
+
+	dec := NewDecoder(nil, orderbook.NewOrderBook())
+	if err := dec.Apply(&Message{Type: OrderDelete, Ref: 1}); !errors.Is(err, orderbook.ErrOrderNotExists) {
+		t.Fatalf("unknown order is deleted: %v", err)
+	}
+	if err := dec.Apply(&Message{Type: OrderExecuted, Ref: 1}); !errors.Is(err, ErrUnknownOrder) {
+		t.Fatalf("unknown order is executed: %v", err)
+	}
+}
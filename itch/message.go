@@ -0,0 +1,214 @@
+// Package itch implements compact fixed-layout binary market data modeled on NASDAQ
+// TotalView-ITCH 5.0. Encoder converts order-level events of the book into messages:
+//
+//	'A' Add Order      - order is rested on the book
+//	'E' Order Executed - resting order is executed (trade), match number identifies the trade
+//	'X' Order Cancel   - quantity of the resting order is reduced
+//	'D' Order Delete   - resting order is removed
+//	'U' Order Replace  - resting order is replaced with new order (new reference, price and quantity)
+//
+// Orders are identified by 64-bit reference numbers assigned by the encoder. Decoder applies the
+// messages to an OrderBook, so a client rebuilds the book (orders get reference numbers as IDs).
+//
+// All integers are big endian, prices and quantities are fixed point integers with the scale
+// configured for both sides. Timestamp is 6 bytes of nanoseconds since midnight UTC. Every message
+// is prefixed with 2 bytes length in the stream like in ITCH files and MoldUDP64 packets.
+package itch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// ITCH errors
+var (
+	ErrInvalidMessage = errors.New("itch: invalid message")
+	ErrPrecision      = errors.New("itch: value does not fit fixed point scale")
+	ErrUnknownOrder   = errors.New("itch: unknown order")
+)
+
+// Message types
+const (
+	AddOrder      byte = 'A'
+	OrderExecuted byte = 'E'
+	OrderCancel   byte = 'X'
+	OrderDelete   byte = 'D'
+	OrderReplace  byte = 'U'
+)
+
+// header is type, stock locate, tracking number and timestamp
+const headerSize = 1 + 2 + 2 + 6
+
+var messageSizes = map[byte]int{
+	AddOrder:      headerSize + 8 + 1 + 8 + 8 + 8, // ref, side, quantity, symbol, price
+	OrderExecuted: headerSize + 8 + 8 + 8,         // ref, executed quantity, match number
+	OrderCancel:   headerSize + 8 + 8,             // ref, cancelled quantity
+	OrderDelete:   headerSize + 8,                 // ref
+	OrderReplace:  headerSize + 8 + 8 + 8 + 8,     // original ref, new ref, quantity, price
+}
+
+// Message is decoded ITCH message, fields which are not used by the type are zero
+type Message struct {
+	Type      byte
+	Locate    uint16
+	Tracking  uint16
+	Timestamp time.Duration // since midnight UTC
+	Ref       uint64        // original reference for OrderReplace
+	NewRef    uint64        // OrderReplace
+	Side      orderbook.Side
+	Quantity  int64 // shares of AddOrder and OrderReplace, executed or cancelled shares
+	Symbol    string
+	Price     int64 // AddOrder and OrderReplace
+	Match     uint64
+}
+
+// Timestamp returns time of the day in ITCH timestamp format
+func Timestamp(t time.Time) time.Duration {
+	t = t.UTC()
+	y, m, d := t.Date()
+	return t.Sub(time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface
+func (m *Message) MarshalBinary() ([]byte, error) {
+	size, ok := messageSizes[m.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidMessage, m.Type)
+	}
+
+	data := make([]byte, size)
+	data[0] = m.Type
+	binary.BigEndian.PutUint16(data[1:], m.Locate)
+	binary.BigEndian.PutUint16(data[3:], m.Tracking)
+	ts := uint64(m.Timestamp)
+	binary.BigEndian.PutUint16(data[5:], uint16(ts>>32))
+	binary.BigEndian.PutUint32(data[7:], uint32(ts))
+
+	body := data[headerSize:]
+	binary.BigEndian.PutUint64(body, m.Ref)
+	switch m.Type {
+	case AddOrder:
+		body[8] = 'B'
+		if m.Side == orderbook.Sell {
+			body[8] = 'S'
+		}
+		binary.BigEndian.PutUint64(body[9:], uint64(m.Quantity))
+		copy(body[17:25], fmt.Sprintf("%-8.8s", m.Symbol))
+		binary.BigEndian.PutUint64(body[25:], uint64(m.Price))
+	case OrderExecuted:
+		binary.BigEndian.PutUint64(body[8:], uint64(m.Quantity))
+		binary.BigEndian.PutUint64(body[16:], m.Match)
+	case OrderCancel:
+		binary.BigEndian.PutUint64(body[8:], uint64(m.Quantity))
+	case OrderReplace:
+		binary.BigEndian.PutUint64(body[8:], m.NewRef)
+		binary.BigEndian.PutUint64(body[16:], uint64(m.Quantity))
+		binary.BigEndian.PutUint64(body[24:], uint64(m.Price))
+	}
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface
+func (m *Message) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: empty message", ErrInvalidMessage)
+	}
+	size, ok := messageSizes[data[0]]
+	if !ok {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidMessage, data[0])
+	}
+	if len(data) != size {
+		return fmt.Errorf("%w: %q message length is %d, want %d", ErrInvalidMessage, data[0], len(data), size)
+	}
+
+	*m = Message{
+		Type:      data[0],
+		Locate:    binary.BigEndian.Uint16(data[1:]),
+		Tracking:  binary.BigEndian.Uint16(data[3:]),
+		Timestamp: time.Duration(uint64(binary.BigEndian.Uint16(data[5:]))<<32 | uint64(binary.BigEndian.Uint32(data[7:]))),
+	}
+
+	body := data[headerSize:]
+	m.Ref = binary.BigEndian.Uint64(body)
+	switch m.Type {
+	case AddOrder:
+		switch body[8] {
+		case 'B':
+			m.Side = orderbook.Buy
+		case 'S':
+			m.Side = orderbook.Sell
+		default:
+			return fmt.Errorf("%w: unknown side %q", ErrInvalidMessage, body[8])
+		}
+		m.Quantity = int64(binary.BigEndian.Uint64(body[9:]))
+		m.Symbol = strings.TrimRight(string(body[17:25]), " ")
+		m.Price = int64(binary.BigEndian.Uint64(body[25:]))
+	case OrderExecuted:
+		m.Quantity = int64(binary.BigEndian.Uint64(body[8:]))
+		m.Match = binary.BigEndian.Uint64(body[16:])
+	case OrderCancel:
+		m.Quantity = int64(binary.BigEndian.Uint64(body[8:]))
+	case OrderReplace:
+		m.NewRef = binary.BigEndian.Uint64(body[8:])
+		m.Quantity = int64(binary.BigEndian.Uint64(body[16:]))
+		m.Price = int64(binary.BigEndian.Uint64(body[24:]))
+	}
+	return nil
+}
+
+// WriteMessage writes the message with length prefix
+func WriteMessage(w io.Writer, m *Message) error {
+	data, err := m.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, 2+len(data))
+	binary.BigEndian.PutUint16(frame, uint16(len(data)))
+	copy(frame[2:], data)
+	_, err = w.Write(frame)
+	return err
+}
+
+// ReadMessage reads the length prefixed message
+func ReadMessage(r io.Reader) (*Message, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	m := &Message{}
+	if err := m.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// toFixed converts decimal to fixed point integer with the scale
+func toFixed(d decimal.Decimal, scale int32) (int64, error) {
+	shifted := d.Shift(scale)
+	if !shifted.Equal(shifted.Truncate(0)) || shifted.Abs().GreaterThan(decimal.New(1<<62, 0)) {
+		return 0, fmt.Errorf("%w: %s (scale %d)", ErrPrecision, d, scale)
+	}
+	return shifted.IntPart(), nil
+}
+
+// fromFixed converts fixed point integer with the scale to decimal
+func fromFixed(v int64, scale int32) decimal.Decimal {
+	return decimal.New(v, -scale)
+}
@@ -0,0 +1,75 @@
+package itch
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+func TestMessageBinary(t *testing.T) {
+	messages := []*Message{
+		{Type: AddOrder, Locate: 1, Timestamp: 12*time.Hour + time.Nanosecond, Ref: 1, Side: orderbook.Sell, Quantity: 100, Symbol: "BTC-USD", Price: 1234500},
+		{Type: AddOrder, Ref: 2, Side: orderbook.Buy, Quantity: 1, Symbol: "VERYLONGSYMBOL", Price: 1},
+		{Type: OrderExecuted, Ref: 1, Quantity: 40, Match: 7},
+		{Type: OrderCancel, Ref: 1, Quantity: 10},
+		{Type: OrderDelete, Ref: 1},
+		{Type: OrderReplace, Ref: 2, NewRef: 3, Quantity: 5, Price: 2},
+	}
+
+	var buf bytes.Buffer
+	for _, m := range messages {
+		if err := WriteMessage(&buf, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buf.Len() != 2*len(messages)+2*44+35+27+19+43 {
+		t.Fatalf("invalid stream length: %d", buf.Len())
+	}
+
+	messages[1].Symbol = "VERYLONG"
+	for _, want := range messages {
+		m, err := ReadMessage(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *m != *want {
+			t.Fatalf("invalid message: %+v, want %+v", m, want)
+		}
+	}
+	if _, err := ReadMessage(&buf); err != io.EOF {
+		t.Fatalf("invalid error: %v", err)
+	}
+}
+
+func TestMessageErrors(t *testing.T) {
+	if _, err := (&Message{Type: 'Z'}).MarshalBinary(); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("unknown type is encoded: %v", err)
+	}
+
+	data, _ := (&Message{Type: OrderDelete, Ref: 1}).MarshalBinary()
+	if err := (&Message{}).UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("short message is decoded: %v", err)
+	}
+
+	data, _ = (&Message{Type: AddOrder, Side: orderbook.Buy}).MarshalBinary()
+	data[headerSize+8] = 'X'
+	if err := (&Message{}).UnmarshalBinary(data); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("unknown side is decoded: %v", err)
+	}
+
+	if _, err := ReadMessage(bytes.NewReader([]byte{0, 19, 'D'})); err != io.ErrUnexpectedEOF {
+		t.Fatalf("truncated stream is read: %v", err)
+	}
+
+	if _, err := toFixed(decimal.New(1, -9), 8); !errors.Is(err, ErrPrecision) {
+		t.Fatalf("value is rounded: %v", err)
+	}
+	if v, err := toFixed(decimal.New(15, -1), 8); err != nil || v != 150000000 {
+		t.Fatalf("invalid fixed point: %d %v", v, err)
+	}
+}
@@ -0,0 +1,84 @@
+package loadgen
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Distribution samples order sizes
+type Distribution interface {
+	Sample(r *rand.Rand) float64
+}
+
+// Fixed is the constant size
+type Fixed float64
+
+// Sample implements Distribution interface
+func (d Fixed) Sample(r *rand.Rand) float64 {
+	return float64(d)
+}
+
+// Uniform samples sizes uniformly from [Min, Max)
+type Uniform struct {
+	Min, Max float64
+}
+
+// Sample implements Distribution interface
+func (d Uniform) Sample(r *rand.Rand) float64 {
+	return d.Min + r.Float64()*(d.Max-d.Min)
+}
+
+// Exponential samples sizes exponentially distributed with the mean
+type Exponential struct {
+	Mean float64
+}
+
+// Sample implements Distribution interface
+func (d Exponential) Sample(r *rand.Rand) float64 {
+	return r.ExpFloat64() * d.Mean
+}
+
+// LogNormal samples sizes whose logarithm is normally distributed with Mu and Sigma
+type LogNormal struct {
+	Mu, Sigma float64
+}
+
+// Sample implements Distribution interface
+func (d LogNormal) Sample(r *rand.Rand) float64 {
+	return math.Exp(d.Mu + r.NormFloat64()*d.Sigma)
+}
+
+// ParseDistribution parses distribution of the form name:params, e.g. fixed:1, uniform:1,10,
+// exp:5 or lognormal:0,1
+func ParseDistribution(s string) (Distribution, error) {
+	name, params := s, ""
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		name, params = s[:i], s[i+1:]
+	}
+
+	var values []float64
+	if len(params) > 0 {
+		for _, p := range strings.Split(params, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidDistribution, s)
+			}
+			values = append(values, v)
+		}
+	}
+
+	switch {
+	case name == "fixed" && len(values) == 1:
+		return Fixed(values[0]), nil
+	case name == "uniform" && len(values) == 2 && values[0] <= values[1]:
+		return Uniform{Min: values[0], Max: values[1]}, nil
+	case name == "exp" && len(values) == 1:
+		return Exponential{Mean: values[0]}, nil
+	case name == "lognormal" && len(values) == 2:
+		return LogNormal{Mu: values[0], Sigma: values[1]}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrInvalidDistribution, s)
+}
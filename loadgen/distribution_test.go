@@ -0,0 +1,49 @@
+package loadgen
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestParseDistribution(t *testing.T) {
+	for s, expected := range map[string]Distribution{
+		"fixed:2":          Fixed(2),
+		"uniform:1,10":     Uniform{Min: 1, Max: 10},
+		"exp:5":            Exponential{Mean: 5},
+		"lognormal:0, 1.5": LogNormal{Mu: 0, Sigma: 1.5},
+	} {
+		d, err := ParseDistribution(s)
+		if err != nil || d != expected {
+			t.Errorf("%q: %#v, %v", s, d, err)
+		}
+	}
+
+	for _, s := range []string{"", "fixed", "fixed:x", "uniform:10,1", "exp:1,2", "normal:0,1"} {
+		if _, err := ParseDistribution(s); !errors.Is(err, ErrInvalidDistribution) {
+			t.Errorf("%q: %v", s, err)
+		}
+	}
+}
+
+func TestDistributionMean(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, c := range []struct {
+		d    Distribution
+		mean float64
+	}{
+		{Fixed(3), 3},
+		{Uniform{Min: 2, Max: 4}, 3},
+		{Exponential{Mean: 3}, 3},
+		{LogNormal{Mu: 0, Sigma: 0.5}, math.Exp(0.125)},
+	} {
+		sum := 0.0
+		for i := 0; i < 100000; i++ {
+			sum += c.d.Sample(r)
+		}
+		if mean := sum / 100000; math.Abs(mean-c.mean) > c.mean*0.02 {
+			t.Errorf("%#v: mean %f, expected %f", c.d, mean, c.mean)
+		}
+	}
+}
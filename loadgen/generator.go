@@ -0,0 +1,166 @@
+// Package loadgen generates synthetic order flow against an OrderBook and measures
+// throughput and latency of matching.
+package loadgen
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// Errors of the order flow configuration
+var (
+	ErrInvalidDistribution = errors.New("loadgen: invalid distribution") // returned by ParseDistribution
+	ErrInvalidTick         = errors.New("loadgen: tick is not positive")
+)
+
+// Config of the order flow
+type Config struct {
+	Seed  int64
+	Start time.Time // time of the first command, current time by default
+	Rate  float64   // mean arrivals per second, inter-arrival times are exponential (Poisson process)
+
+	Mid        decimal.Decimal // initial mid price
+	Tick       decimal.Decimal // price increment
+	Volatility float64         // standard deviation of the mid price step per arrival in ticks
+	Depth      float64         // mean distance of the limit price from the mid in ticks
+
+	CancelRatio float64 // share of cancels among commands
+	MarketShare float64 // share of market orders among new orders
+
+	Size           Distribution // order size
+	QuantityPlaces int32        // quantities are rounded to the decimal places
+	Owners         int          // number of owners the orders are spread across
+}
+
+// DefaultConfig returns config of moderately active market around 100
+func DefaultConfig() Config {
+	return Config{
+		Seed:           1,
+		Rate:           1000,
+		Mid:            decimal.New(100, 0),
+		Tick:           decimal.New(1, -2),
+		Volatility:     0.5,
+		Depth:          10,
+		CancelRatio:    0.4,
+		MarketShare:    0.1,
+		Size:           LogNormal{Mu: 2, Sigma: 1},
+		QuantityPlaces: 0,
+		Owners:         100,
+	}
+}
+
+// Generator produces commands of the order flow, cancels refer to orders resting in the book
+type Generator struct {
+	config Config
+	book   *orderbook.OrderBook
+	rand   *rand.Rand
+
+	now    time.Time
+	mid    float64 // mid price in ticks
+	nextID int
+	live   []string // IDs of limit orders which may be resting
+}
+
+// NewGenerator creates generator of the order flow for the book, error is ErrInvalidTick
+// if the tick is not positive
+func NewGenerator(book *orderbook.OrderBook, config Config) (*Generator, error) {
+	if config.Tick.Sign() <= 0 {
+		return nil, ErrInvalidTick
+	}
+	if config.Start.IsZero() {
+		config.Start = time.Now()
+	}
+	if config.Size == nil {
+		config.Size = Fixed(1)
+	}
+	if config.Owners <= 0 {
+		config.Owners = 1
+	}
+
+	mid, _ := config.Mid.Div(config.Tick).Float64()
+	return &Generator{
+		config: config,
+		book:   book,
+		rand:   rand.New(rand.NewSource(config.Seed)),
+		now:    config.Start,
+		mid:    mid,
+	}, nil
+}
+
+// Mid returns the current mid price of the random walk
+func (g *Generator) Mid() decimal.Decimal {
+	return g.price(g.mid)
+}
+
+// Next returns the next command of the flow
+func (g *Generator) Next() *orderbook.Command {
+	if g.config.Rate > 0 {
+		g.now = g.now.Add(time.Duration(g.rand.ExpFloat64() / g.config.Rate * float64(time.Second)))
+	}
+	g.mid = math.Max(1, g.mid+g.rand.NormFloat64()*g.config.Volatility)
+
+	if g.rand.Float64() < g.config.CancelRatio {
+		if id, ok := g.resting(); ok {
+			return &orderbook.Command{Type: orderbook.CommandCancel, Time: g.now, ID: id}
+		}
+	}
+
+	cmd := &orderbook.Command{
+		Time:     g.now,
+		Owner:    "owner-" + strconv.Itoa(g.rand.Intn(g.config.Owners)),
+		Side:     orderbook.Buy,
+		Quantity: g.quantity(),
+	}
+	if g.rand.Intn(2) == 0 {
+		cmd.Side = orderbook.Sell
+	}
+
+	if g.rand.Float64() < g.config.MarketShare {
+		cmd.Type = orderbook.CommandMarket
+		return cmd
+	}
+
+	offset := g.rand.ExpFloat64() * g.config.Depth
+	if cmd.Side == orderbook.Buy {
+		offset = -offset
+	}
+	g.nextID++
+	cmd.Type = orderbook.CommandLimit
+	cmd.ID = "g" + strconv.Itoa(g.nextID)
+	cmd.Price = g.price(math.Max(1, g.mid+offset))
+	g.live = append(g.live, cmd.ID)
+	return cmd
+}
+
+// resting picks random order resting in the book, IDs of filled or cancelled orders are dropped
+func (g *Generator) resting() (string, bool) {
+	for len(g.live) > 0 {
+		i := g.rand.Intn(len(g.live))
+		id := g.live[i]
+		last := len(g.live) - 1
+		g.live[i] = g.live[last]
+		g.live = g.live[:last]
+		if g.book.Order(id) != nil {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+func (g *Generator) price(ticks float64) decimal.Decimal {
+	return decimal.NewFromInt(int64(math.Round(ticks))).Mul(g.config.Tick)
+}
+
+func (g *Generator) quantity() decimal.Decimal {
+	q := decimal.NewFromFloat(g.config.Size.Sample(g.rand)).Round(g.config.QuantityPlaces)
+	if lot := decimal.New(1, -g.config.QuantityPlaces); q.LessThan(lot) {
+		return lot
+	}
+	return q
+}
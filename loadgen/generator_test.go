@@ -0,0 +1,114 @@
+package loadgen
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+func newGenerator(t testing.TB, book *orderbook.OrderBook, config Config) *Generator {
+	t.Helper()
+	g, err := NewGenerator(book, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestGenerator(t *testing.T) {
+	book := orderbook.NewOrderBook()
+	config := DefaultConfig()
+	config.Start = time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	g := newGenerator(t, book, config)
+
+	const n = 20000
+	counts := map[orderbook.CommandType]int{}
+	last := config.Start
+	for i := 0; i < n; i++ {
+		cmd := g.Next()
+		counts[cmd.Type]++
+
+		if cmd.Time.Before(last) {
+			t.Fatalf("command %d time goes back", i)
+		}
+		last = cmd.Time
+
+		switch cmd.Type {
+		case orderbook.CommandCancel:
+			if book.Order(cmd.ID) == nil {
+				t.Fatalf("command %d cancels order %s which is not resting", i, cmd.ID)
+			}
+		case orderbook.CommandLimit:
+			if cmd.Price.Sign() <= 0 || !cmd.Price.Mod(config.Tick).IsZero() {
+				t.Fatalf("command %d price %s", i, cmd.Price)
+			}
+		}
+		if cmd.Type != orderbook.CommandCancel && (cmd.Quantity.Sign() <= 0 || cmd.Quantity.Exponent() < -config.QuantityPlaces) {
+			t.Fatalf("command %d quantity %s", i, cmd.Quantity)
+		}
+
+		if err := book.Apply(cmd).Err; err != nil && cmd.Type != orderbook.CommandMarket {
+			t.Fatalf("command %d: %v", i, err)
+		}
+	}
+
+	near := func(name string, actual, expected float64) {
+		if math.Abs(actual-expected) > 0.02 {
+			t.Errorf("%s %f, expected %f", name, actual, expected)
+		}
+	}
+	near("cancel ratio", float64(counts[orderbook.CommandCancel])/n, config.CancelRatio)
+	orders := float64(counts[orderbook.CommandLimit] + counts[orderbook.CommandMarket])
+	near("market share", float64(counts[orderbook.CommandMarket])/orders, config.MarketShare)
+
+	interval := last.Sub(config.Start).Seconds() / n
+	if expected := 1 / config.Rate; math.Abs(interval-expected) > expected*0.05 {
+		t.Errorf("mean inter-arrival time %f, expected %f", interval, expected)
+	}
+
+	if err := book.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// quotes follow the random walk
+	if mid, err := book.MidPrice(); err != nil || mid.Sub(g.Mid()).Abs().GreaterThan(decimal.New(1, 0)) {
+		t.Errorf("book mid %s, walk mid %s, %v", mid, g.Mid(), err)
+	}
+}
+
+func TestGeneratorSeed(t *testing.T) {
+	config := DefaultConfig()
+	config.Start = time.Unix(0, 0)
+	generate := func(seed int64) []*orderbook.Command {
+		config.Seed = seed
+		book := orderbook.NewOrderBook()
+		g := newGenerator(t, book, config)
+		var commands []*orderbook.Command
+		for i := 0; i < 100; i++ {
+			cmd := g.Next()
+			book.Apply(cmd)
+			commands = append(commands, cmd)
+		}
+		return commands
+	}
+
+	if !reflect.DeepEqual(generate(7), generate(7)) {
+		t.Fatal("same seed must generate the same flow")
+	}
+	if reflect.DeepEqual(generate(7), generate(8)) {
+		t.Fatal("different seeds must generate different flows")
+	}
+}
+
+func TestGeneratorTick(t *testing.T) {
+	config := DefaultConfig()
+	for _, tick := range []decimal.Decimal{decimal.Zero, decimal.New(-1, -2)} {
+		config.Tick = tick
+		if _, err := NewGenerator(orderbook.NewOrderBook(), config); err != ErrInvalidTick {
+			t.Fatalf("tick %s: %v", tick, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package loadgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Runner applies generated commands to the book of the generator
+type Runner struct {
+	Generator *Generator
+	Paced     bool      // commands are applied at their arrival times instead of as fast as possible
+	Log       io.Writer // applied commands are written as JSON lines if set (see cmd/obreplay)
+}
+
+// Report of the run, latency is the time spent in OrderBook.Apply
+type Report struct {
+	Commands   int           `json:"commands"`
+	Errors     int           `json:"errors"`
+	Fills      int           `json:"fills"`
+	Elapsed    time.Duration `json:"elapsed"`
+	Throughput float64       `json:"throughput"` // commands per second of the elapsed time
+	P50        time.Duration `json:"p50"`
+	P90        time.Duration `json:"p90"`
+	P99        time.Duration `json:"p99"`
+	P999       time.Duration `json:"p999"`
+	Max        time.Duration `json:"max"`
+}
+
+// String implements fmt.Stringer interface
+func (r *Report) String() string {
+	return fmt.Sprintf("commands: %d\nerrors: %d\nfills: %d\nelapsed: %s\nthroughput: %.0f/s\np50: %s\np90: %s\np99: %s\np99.9: %s\nmax: %s\n",
+		r.Commands, r.Errors, r.Fills, r.Elapsed, r.Throughput, r.P50, r.P90, r.P99, r.P999, r.Max)
+}
+
+// Run applies n commands and reports throughput and latency percentiles
+func (r *Runner) Run(n int) (*Report, error) {
+	var log *json.Encoder
+	if r.Log != nil {
+		log = json.NewEncoder(r.Log)
+	}
+
+	book := r.Generator.book
+	report := &Report{}
+	latencies := make([]time.Duration, 0, n)
+	started := time.Now()
+	first := r.Generator.now
+	for i := 0; i < n; i++ {
+		cmd := r.Generator.Next()
+		if r.Paced {
+			if wait := cmd.Time.Sub(first) - time.Since(started); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		at := time.Now()
+		result := book.Apply(cmd)
+		latencies = append(latencies, time.Since(at))
+
+		if result.Err != nil {
+			report.Errors++
+		}
+		report.Fills += len(result.Fills)
+		if log != nil {
+			if err := log.Encode(cmd); err != nil {
+				return nil, err
+			}
+		}
+	}
+	report.Elapsed = time.Since(started)
+
+	report.Commands = len(latencies)
+	if report.Commands == 0 {
+		return report, nil
+	}
+	if report.Elapsed > 0 {
+		report.Throughput = float64(report.Commands) / report.Elapsed.Seconds()
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	report.P50 = percentile(0.5)
+	report.P90 = percentile(0.9)
+	report.P99 = percentile(0.99)
+	report.P999 = percentile(0.999)
+	report.Max = latencies[len(latencies)-1]
+	return report, nil
+}
@@ -0,0 +1,80 @@
+package loadgen
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/centny/orderbook"
+)
+
+func TestRunner(t *testing.T) {
+	book := orderbook.NewOrderBook()
+	config := DefaultConfig()
+	config.Start = time.Unix(0, 0)
+	var log bytes.Buffer
+	r := &Runner{Generator: newGenerator(t, book, config), Log: &log}
+
+	report, err := r.Run(5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Commands != 5000 || report.Fills == 0 || report.Throughput <= 0 {
+		t.Fatalf("report %+v", report)
+	}
+	if !(report.P50 <= report.P90 && report.P90 <= report.P99 && report.P99 <= report.P999 && report.P999 <= report.Max) {
+		t.Fatalf("percentiles are not ordered: %+v", report)
+	}
+
+	replayed := orderbook.NewOrderBook()
+	scanner := bufio.NewScanner(&log)
+	n := 0
+	for ; scanner.Scan(); n++ {
+		cmd := &orderbook.Command{}
+		if err := json.Unmarshal(scanner.Bytes(), cmd); err != nil {
+			t.Fatal(err)
+		}
+		replayed.Apply(cmd)
+	}
+	if n != 5000 || replayed.StateHash() != book.StateHash() {
+		t.Fatalf("replay of %d logged commands differs", n)
+	}
+}
+
+func TestRunnerPaced(t *testing.T) {
+	config := DefaultConfig()
+	config.Rate = 2000
+	r := &Runner{Generator: newGenerator(t, orderbook.NewOrderBook(), config), Paced: true}
+
+	report, err := r.Run(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Elapsed < 25*time.Millisecond {
+		t.Fatalf("100 commands at 2000/s took %s", report.Elapsed)
+	}
+}
+
+func TestRunnerEmpty(t *testing.T) {
+	r := &Runner{Generator: newGenerator(t, orderbook.NewOrderBook(), DefaultConfig())}
+	if report, err := r.Run(0); err != nil || report.Commands != 0 || report.Max != 0 {
+		t.Fatalf("report %+v, %v", report, err)
+	}
+}
+
+// BenchmarkOrderFlow measures matching of realistic flow: crossing limit orders, market orders and cancels
+func BenchmarkOrderFlow(b *testing.B) {
+	book := orderbook.NewOrderBook()
+	g := newGenerator(b, book, DefaultConfig())
+	for i := 0; i < 10000; i++ {
+		book.Apply(g.Next())
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		book.Apply(g.Next())
+	}
+}
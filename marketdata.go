@@ -0,0 +1,315 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TradeTape stores recent trades in ring buffer of fixed capacity
+type TradeTape struct {
+	trades []*Fill
+	head   int // index of the oldest trade
+	size   int
+}
+
+// NewTradeTape creates TradeTape which keeps up to capacity trades
+func NewTradeTape(capacity int) *TradeTape {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &TradeTape{trades: make([]*Fill, capacity)}
+}
+
+// Len returns amount of trades in tape
+func (tt *TradeTape) Len() int {
+	return tt.size
+}
+
+// Add appends trade to the tape, the oldest trade is dropped if tape is full
+func (tt *TradeTape) Add(f *Fill) {
+	tt.add(f)
+}
+
+// add appends trade to the tape and returns the dropped one
+func (tt *TradeTape) add(f *Fill) (dropped *Fill) {
+	if tt.size < len(tt.trades) {
+		tt.trades[(tt.head+tt.size)%len(tt.trades)] = f
+		tt.size++
+		return nil
+	}
+	dropped = tt.trades[tt.head]
+	tt.trades[tt.head] = f
+	tt.head = (tt.head + 1) % len(tt.trades)
+	return dropped
+}
+
+// removeLast removes the most recent trade and puts back the trade dropped by it
+func (tt *TradeTape) removeLast(dropped *Fill) {
+	if dropped == nil {
+		tt.size--
+		tt.trades[(tt.head+tt.size)%len(tt.trades)] = nil
+		return
+	}
+	tt.head = (tt.head - 1 + len(tt.trades)) % len(tt.trades)
+	tt.trades[tt.head] = dropped
+}
+
+// Last returns the most recent trade, nil if tape is empty
+func (tt *TradeTape) Last() *Fill {
+	if tt.size == 0 {
+		return nil
+	}
+	return tt.trades[(tt.head+tt.size-1)%len(tt.trades)]
+}
+
+// Trades returns copy of trades from the oldest to the most recent
+func (tt *TradeTape) Trades() []*Fill {
+	trades := make([]*Fill, tt.size)
+	for i := range trades {
+		trades[i] = tt.trades[(tt.head+i)%len(tt.trades)]
+	}
+	return trades
+}
+
+// Candle stores OHLCV of trades within time interval
+type Candle struct {
+	Start    time.Time       `json:"start"`
+	Interval time.Duration   `json:"interval"`
+	Open     decimal.Decimal `json:"open"`
+	High     decimal.Decimal `json:"high"`
+	Low      decimal.Decimal `json:"low"`
+	Close    decimal.Decimal `json:"close"`
+	Volume   decimal.Decimal `json:"volume"`
+	Notional decimal.Decimal `json:"notional"` // sum of price * quantity
+	Trades   int             `json:"trades"`
+}
+
+func newCandle(start time.Time, interval time.Duration, f *Fill) *Candle {
+	return &Candle{
+		Start:    start,
+		Interval: interval,
+		Open:     f.Price,
+		High:     f.Price,
+		Low:      f.Price,
+		Close:    f.Price,
+		Volume:   f.Quantity,
+		Notional: f.Notional(),
+		Trades:   1,
+	}
+}
+
+func (c *Candle) add(f *Fill) {
+	if f.Price.GreaterThan(c.High) {
+		c.High = f.Price
+	}
+	if f.Price.LessThan(c.Low) {
+		c.Low = f.Price
+	}
+	c.Close = f.Price
+	c.Volume = c.Volume.Add(f.Quantity)
+	c.Notional = c.Notional.Add(f.Notional())
+	c.Trades++
+}
+
+// VWAP returns volume weighted average price of the candle
+func (c *Candle) VWAP() decimal.Decimal {
+	if c.Volume.Sign() == 0 {
+		return decimal.Zero
+	}
+	return c.Notional.Div(c.Volume)
+}
+
+// String implements fmt.Stringer interface
+func (c *Candle) String() string {
+	data, _ := json.Marshal(c)
+	return string(data)
+}
+
+// CandleSeries stores rolling candles of definite interval
+type CandleSeries struct {
+	interval time.Duration
+	limit    int
+	candles  []*Candle
+}
+
+// NewCandleSeries creates CandleSeries which keeps up to limit the most recent candles
+func NewCandleSeries(interval time.Duration, limit int) *CandleSeries {
+	if limit < 1 {
+		limit = 1
+	}
+	return &CandleSeries{
+		interval: interval,
+		limit:    limit,
+	}
+}
+
+// Interval returns duration of the series candles
+func (cs *CandleSeries) Interval() time.Duration {
+	return cs.interval
+}
+
+// Add updates candle the trade belongs to, trades older than the kept candles are ignored.
+// Intervals without trades have no candles
+func (cs *CandleSeries) Add(f *Fill) {
+	cs.add(f)
+}
+
+// candleUndo restores the series changed by the trade
+type candleUndo struct {
+	index   int     // index of the changed candle, negative if the trade is ignored
+	prev    *Candle // copy of the changed candle, nil if the candle is created by the trade
+	dropped *Candle // the oldest candle trimmed after the candle creation
+}
+
+func (cs *CandleSeries) add(f *Fill) candleUndo {
+	start := f.Time.Truncate(cs.interval)
+
+	for i := len(cs.candles) - 1; i >= 0; i-- {
+		c := cs.candles[i]
+		if c.Start.Equal(start) {
+			prev := *c
+			c.add(f)
+			return candleUndo{index: i, prev: &prev}
+		}
+		if c.Start.Before(start) {
+			if i == len(cs.candles)-1 {
+				break
+			}
+			// late trade for the missed interval
+			cs.candles = append(cs.candles[:i+1], append([]*Candle{newCandle(start, cs.interval, f)}, cs.candles[i+1:]...)...)
+			u := candleUndo{index: i + 1, dropped: cs.trim()}
+			if u.dropped != nil {
+				u.index--
+			}
+			return u
+		}
+	}
+
+	if n := len(cs.candles); n > 0 && start.Before(cs.candles[0].Start) {
+		return candleUndo{index: -1}
+	}
+
+	cs.candles = append(cs.candles, newCandle(start, cs.interval, f))
+	dropped := cs.trim()
+	return candleUndo{index: len(cs.candles) - 1, dropped: dropped}
+}
+
+// revert restores the series changed by the most recent trade
+func (cs *CandleSeries) revert(u candleUndo) {
+	switch {
+	case u.index < 0:
+	case u.prev != nil:
+		*cs.candles[u.index] = *u.prev
+	default:
+		cs.candles = append(cs.candles[:u.index], cs.candles[u.index+1:]...)
+		if u.dropped != nil {
+			cs.candles = append([]*Candle{u.dropped}, cs.candles...)
+		}
+	}
+}
+
+// trim drops the oldest candles over the limit, the most recent dropped one is returned
+func (cs *CandleSeries) trim() (dropped *Candle) {
+	if n := len(cs.candles); n > cs.limit {
+		dropped = cs.candles[n-cs.limit-1]
+		copy(cs.candles, cs.candles[n-cs.limit:])
+		for i := cs.limit; i < n; i++ {
+			cs.candles[i] = nil
+		}
+		cs.candles = cs.candles[:cs.limit]
+	}
+	return dropped
+}
+
+// Last returns the most recent candle, nil if there are no trades
+func (cs *CandleSeries) Last() *Candle {
+	if len(cs.candles) == 0 {
+		return nil
+	}
+	return cs.candles[len(cs.candles)-1]
+}
+
+// Candles returns copy of candles from the oldest to the most recent
+func (cs *CandleSeries) Candles() []*Candle {
+	candles := make([]*Candle, len(cs.candles))
+	copy(candles, cs.candles)
+	return candles
+}
+
+// MarketData maintains trade tape and candles of several intervals from the OrderBook fills
+//
+//	md := NewMarketData(1000, 500, time.Second, time.Minute, time.Hour)
+//	ob.OnFill(md.HandleFill)
+//	ob.OnRollback(md.HandleRollback)
+type MarketData struct {
+	tape    *TradeTape
+	series  []*CandleSeries
+	journal []marketDataUndo // the most recent fills, see HandleRollback
+}
+
+// MarketDataUndoLimit is the number of the most recent fills MarketData can roll back
+const MarketDataUndoLimit = 1024
+
+// marketDataUndo restores the tape and the candles changed by the fill
+type marketDataUndo struct {
+	fill    *Fill
+	dropped *Fill
+	candles []candleUndo // by series
+}
+
+// NewMarketData creates MarketData with tape of tapeSize trades and up to candleLimit candles per interval
+func NewMarketData(tapeSize, candleLimit int, intervals ...time.Duration) *MarketData {
+	md := &MarketData{tape: NewTradeTape(tapeSize)}
+	for _, interval := range intervals {
+		md.series = append(md.series, NewCandleSeries(interval, candleLimit))
+	}
+	return md
+}
+
+// HandleFill implements FillHandler
+func (md *MarketData) HandleFill(f *Fill) {
+	u := marketDataUndo{fill: f, dropped: md.tape.add(f)}
+	for _, cs := range md.series {
+		u.candles = append(u.candles, cs.add(f))
+	}
+
+	if len(md.journal) >= MarketDataUndoLimit {
+		copy(md.journal, md.journal[1:])
+		md.journal = md.journal[:len(md.journal)-1]
+	}
+	md.journal = append(md.journal, u)
+}
+
+// HandleRollback removes the rolled back fill from the tape and the candles, it is registered
+// with OrderBook.OnRollback. Only the most recent fill is removed, so fills have to be rolled
+// back in reverse order (as the OrderBook does) and within MarketDataUndoLimit fills
+func (md *MarketData) HandleRollback(f *Fill) {
+	n := len(md.journal)
+	if n == 0 || md.journal[n-1].fill != f {
+		return
+	}
+	u := md.journal[n-1]
+	md.journal = md.journal[:n-1]
+
+	md.tape.removeLast(u.dropped)
+	for i, cs := range md.series {
+		cs.revert(u.candles[i])
+	}
+}
+
+// Tape returns trade tape
+func (md *MarketData) Tape() *TradeTape {
+	return md.tape
+}
+
+// Series returns candles of the interval, nil if the interval is not maintained
+func (md *MarketData) Series(interval time.Duration) *CandleSeries {
+	for _, cs := range md.series {
+		if cs.Interval() == interval {
+			return cs
+		}
+	}
+	return nil
+}
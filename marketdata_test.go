@@ -0,0 +1,144 @@
+package orderbook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func testFill(price, quantity int64, at time.Time) *Fill {
+	return &Fill{Price: decimal.New(price, 0), Quantity: decimal.New(quantity, 0), Time: at}
+}
+
+func TestTradeTape(t *testing.T) {
+	tt := NewTradeTape(3)
+	if tt.Last() != nil || len(tt.Trades()) != 0 {
+		t.Fatal("empty tape has trades")
+	}
+
+	start := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := int64(1); i <= 5; i++ {
+		tt.Add(testFill(i, 1, start))
+	}
+
+	trades := tt.Trades()
+	if tt.Len() != 3 || len(trades) != 3 || trades[0].Price.IntPart() != 3 || tt.Last().Price.IntPart() != 5 {
+		t.Fatalf("invalid trades: %v", trades)
+	}
+}
+
+func TestCandleSeries(t *testing.T) {
+	start := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	cs := NewCandleSeries(time.Minute, 2)
+
+	cs.Add(testFill(100, 1, start.Add(5*time.Second)))
+	cs.Add(testFill(110, 3, start.Add(10*time.Second)))
+	cs.Add(testFill(90, 1, start.Add(20*time.Second)))
+	cs.Add(testFill(95, 5, start.Add(59*time.Second)))
+
+	c := cs.Last()
+	if !c.Start.Equal(start) || c.Open.IntPart() != 100 || c.High.IntPart() != 110 || c.Low.IntPart() != 90 ||
+		c.Close.IntPart() != 95 || c.Volume.IntPart() != 10 || c.Trades != 4 {
+		t.Fatalf("invalid candle: %s", c)
+	}
+
+	// (100 + 330 + 90 + 475) / 10
+	if !c.VWAP().Equal(decimal.RequireFromString("99.5")) {
+		t.Fatalf("invalid vwap: %s", c.VWAP())
+	}
+
+	cs.Add(testFill(120, 1, start.Add(3*time.Minute)))
+	cs.Add(testFill(105, 1, start.Add(90*time.Second))) // late trade of the missed interval
+	candles := cs.Candles()
+	if len(candles) != 2 || !candles[0].Start.Equal(start.Add(time.Minute)) || candles[1].Close.IntPart() != 120 {
+		t.Fatalf("invalid candles: %v", candles)
+	}
+
+	cs.Add(testFill(1, 1, start)) // older than kept candles
+	if cs.Candles()[0].Trades != 1 || len(cs.Candles()) != 2 {
+		t.Fatalf("invalid candles: %v", cs.Candles())
+	}
+}
+
+func TestMarketDataFeed(t *testing.T) {
+	ob := NewOrderBook()
+	md := NewMarketData(10, 10, time.Second, time.Hour)
+	ob.OnFill(md.HandleFill)
+
+	addDepth(ob, "", decimal.New(2, 0))
+	ob.ProcessMarketQuantityOrder(Buy, decimal.New(3, 0))
+	ob.ProcessMarketQuantityOrder(Sell, decimal.New(1, 0))
+
+	if md.Tape().Len() != 3 || md.Tape().Last().Price.IntPart() != 90 {
+		t.Fatalf("invalid tape: %v", md.Tape().Trades())
+	}
+
+	c := md.Series(time.Hour).Last()
+	if c.Trades != 3 || c.Volume.IntPart() != 4 || c.High.IntPart() != 110 || c.Low.IntPart() != 90 {
+		t.Fatalf("invalid candle: %s", c)
+	}
+
+	if md.Series(time.Minute) != nil {
+		t.Fatal("unknown interval is maintained")
+	}
+}
+
+func TestMarketDataRollback(t *testing.T) {
+	start := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	md := NewMarketData(2, 1, time.Minute)
+
+	f1 := testFill(100, 1, start)
+	f2 := testFill(110, 2, start.Add(10*time.Second))
+	f3 := testFill(90, 1, start.Add(2*time.Minute))
+	for _, f := range []*Fill{f1, f2, f3} {
+		md.HandleFill(f)
+	}
+	if md.Tape().Trades()[0] != f2 || md.Series(time.Minute).Last().Open.IntPart() != 90 {
+		t.Fatalf("invalid market data: %v %v", md.Tape().Trades(), md.Series(time.Minute).Candles())
+	}
+
+	md.HandleRollback(f1) // not the most recent fill
+	md.HandleRollback(f3)
+	trades := md.Tape().Trades()
+	c := md.Series(time.Minute).Last()
+	if len(trades) != 2 || trades[0] != f1 || trades[1] != f2 || !c.Start.Equal(start) || c.Trades != 2 {
+		t.Fatalf("invalid market data: %v %s", trades, c)
+	}
+
+	md.HandleRollback(f2)
+	c = md.Series(time.Minute).Last()
+	if md.Tape().Len() != 1 || c.Trades != 1 || c.High.IntPart() != 100 || c.Volume.IntPart() != 1 {
+		t.Fatalf("invalid market data: %v %s", md.Tape().Trades(), c)
+	}
+
+	md.HandleRollback(f1)
+	if md.Tape().Len() != 0 || md.Series(time.Minute).Last() != nil {
+		t.Fatal("rolled back fill is kept")
+	}
+
+	// late trade of the missed interval
+	cs := NewCandleSeries(time.Minute, 2)
+	cs.Add(testFill(100, 1, start))
+	cs.Add(testFill(120, 1, start.Add(3*time.Minute)))
+	cs.revert(cs.add(testFill(105, 1, start.Add(90*time.Second))))
+	if candles := cs.Candles(); len(candles) != 2 || !candles[0].Start.Equal(start) || candles[1].Close.IntPart() != 120 {
+		t.Fatalf("invalid candles: %v", candles)
+	}
+}
+
+func TestMarketDataBookRollback(t *testing.T) {
+	ob := NewOrderBook()
+	md := NewMarketData(10, 10, time.Hour)
+	ob.OnFill(md.HandleFill)
+	ob.OnRollback(md.HandleRollback)
+
+	addDepth(ob, "", decimal.New(2, 0))
+	ob.ProcessMarketQuantityOrder(Buy, decimal.New(1, 0))
+	_, _, _, _, rollback, _ := ob.ProcessMarketQuantityOrder(Buy, decimal.New(3, 0))
+	rollback()
+
+	if c := md.Series(time.Hour).Last(); md.Tape().Len() != 1 || c.Trades != 1 || c.Volume.IntPart() != 1 {
+		t.Fatalf("invalid market data: %v %s", md.Tape().Trades(), c)
+	}
+}
@@ -12,6 +12,7 @@ import (
 type Order struct {
 	side      Side
 	id        string
+	owner     string
 	timestamp time.Time
 	quantity  decimal.Decimal
 	price     decimal.Decimal
@@ -19,8 +20,14 @@ type Order struct {
 
 // NewOrder creates new constant object Order
 func NewOrder(orderID string, side Side, quantity, price decimal.Decimal, timestamp time.Time) *Order {
+	return NewOrderWithOwner(orderID, "", side, quantity, price, timestamp)
+}
+
+// NewOrderWithOwner creates new constant object Order which belongs to the given account
+func NewOrderWithOwner(orderID, owner string, side Side, quantity, price decimal.Decimal, timestamp time.Time) *Order {
 	return &Order{
 		id:        orderID,
+		owner:     owner,
 		side:      side,
 		quantity:  quantity,
 		price:     price,
@@ -33,6 +40,11 @@ func (o *Order) ID() string {
 	return o.id
 }
 
+// Owner returns account which the order belongs to
+func (o *Order) Owner() string {
+	return o.owner
+}
+
 // Side returns side of the order
 func (o *Order) Side() Side {
 	return o.side
@@ -64,12 +76,14 @@ func (o *Order) MarshalJSON() ([]byte, error) {
 		&struct {
 			S         Side            `json:"side"`
 			ID        string          `json:"id"`
+			Owner     string          `json:"owner,omitempty"`
 			Timestamp time.Time       `json:"timestamp"`
 			Quantity  decimal.Decimal `json:"quantity"`
 			Price     decimal.Decimal `json:"price"`
 		}{
 			S:         o.Side(),
 			ID:        o.ID(),
+			Owner:     o.Owner(),
 			Timestamp: o.Time(),
 			Quantity:  o.Quantity(),
 			Price:     o.Price(),
@@ -82,6 +96,7 @@ func (o *Order) UnmarshalJSON(data []byte) error {
 	obj := struct {
 		S         Side            `json:"side"`
 		ID        string          `json:"id"`
+		Owner     string          `json:"owner,omitempty"`
 		Timestamp time.Time       `json:"timestamp"`
 		Quantity  decimal.Decimal `json:"quantity"`
 		Price     decimal.Decimal `json:"price"`
@@ -93,6 +108,7 @@ func (o *Order) UnmarshalJSON(data []byte) error {
 
 	o.side = obj.S
 	o.id = obj.ID
+	o.owner = obj.Owner
 	o.timestamp = obj.Timestamp
 	o.quantity = obj.Quantity
 	o.price = obj.Price
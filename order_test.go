@@ -33,3 +33,17 @@ func TestOrderJSON(t *testing.T) {
 		t.Fatal("can unmarshal unsupported value")
 	}
 }
+
+func TestOrderOwnerJSON(t *testing.T) {
+	o := NewOrderWithOwner("one", "alice", Buy, decimal.New(1, 0), decimal.New(10, 0), time.Now().UTC())
+
+	result, _ := json.Marshal(o)
+	restored := &Order{}
+	if err := json.Unmarshal(result, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Owner() != "alice" {
+		t.Fatalf("invalid owner: %s", result)
+	}
+}
@@ -10,21 +10,53 @@ import (
 
 // OrderBook implements standard matching algorithm
 type OrderBook struct {
-	orders map[string]*list.Element // orderID -> *Order (*list.Element.Value.(*Order))
+	orders map[string]*list.Element            // orderID -> *Order (*list.Element.Value.(*Order))
+	owners map[string]map[string]*list.Element // owner -> orderID -> *Order
 
 	asks *OrderSide
 	bids *OrderSide
+
+	fees             FeeModel
+	fills            []*Fill
+	handlers         []FillHandler
+	rollbackHandlers []FillHandler
+
+	clock      func() time.Time
+	at         time.Time // time of the applied command
+	band       *PriceBand
+	refPrice   decimal.Decimal
+	lastPrice  decimal.Decimal
+	phase      Phase
+	schedule   []PhaseTransition
+	operations map[Phase]Operation
 }
 
 // NewOrderBook creates Orderbook object
 func NewOrderBook() *OrderBook {
 	return &OrderBook{
 		orders: map[string]*list.Element{},
+		owners: map[string]map[string]*list.Element{},
 		bids:   NewOrderSide(),
 		asks:   NewOrderSide(),
 	}
 }
 
+// SetClock sets up source of time used for order timestamps, fills and halts.
+// Default clock is time.Now().UTC(), nil restores it
+func (ob *OrderBook) SetClock(clock func() time.Time) {
+	ob.clock = clock
+}
+
+func (ob *OrderBook) now() time.Time {
+	if !ob.at.IsZero() {
+		return ob.at
+	}
+	if ob.clock == nil {
+		return time.Now().UTC()
+	}
+	return ob.clock()
+}
+
 // ProcessMarketQuantityOrder immediately gets definite quantity from the order book with market price
 // Arguments:
 //      side     - what do you want to do (ob.Sell or ob.Buy)
@@ -39,6 +71,13 @@ func NewOrderBook() *OrderBook {
 //      partialQuantityProcessed - if partial order is not nil this result contains processed quatity from partial order
 //      quantityLeft - more than zero if it is not enought orders to process all quantity
 func (ob *OrderBook) ProcessMarketQuantityOrder(side Side, quantity decimal.Decimal) (done []*Order, partial *Order, partialQuantityProcessed, quantityLeft decimal.Decimal, rollback func(), err error) {
+	return ob.ProcessMarketQuantityOrderFor("", side, quantity)
+}
+
+// ProcessMarketQuantityOrderFor works as ProcessMarketQuantityOrder on behalf of the given account (owner),
+// the account is reported as taker in the fills of the order
+func (ob *OrderBook) ProcessMarketQuantityOrderFor(owner string, side Side, quantity decimal.Decimal) (done []*Order, partial *Order, partialQuantityProcessed, quantityLeft decimal.Decimal, rollback func(), err error) {
+	ob.fills = nil
 	if quantity.Sign() <= 0 {
 		return nil, nil, decimal.Zero, decimal.Zero, nil, ErrInvalidQuantity
 	}
@@ -56,28 +95,33 @@ func (ob *OrderBook) ProcessMarketQuantityOrder(side Side, quantity decimal.Deci
 		sideToProcess = ob.bids
 	}
 
-	var rollbackPartial func()
+	if err = ob.checkPhase(OpMarketOrder | OpMatch); err != nil {
+		return nil, nil, decimal.Zero, decimal.Zero, nil, err
+	}
+
+	if err = ob.checkBand(side, quantity, decimal.Zero, decimal.Zero); err != nil {
+		return nil, nil, decimal.Zero, decimal.Zero, nil, err
+	}
+
+	lastPrice := ob.lastPrice
+	taker := NewOrderWithOwner("", owner, side, quantity, decimal.Zero, ob.now())
+	var rollbacks []func()
 	for quantity.Sign() > 0 && sideToProcess.Len() > 0 {
 		bestPrice := iter()
-		ordersDone, partialDone, partialProcessed, quantityLeft, rollbackPart := ob.processQueue(bestPrice, quantity)
+		ordersDone, partialDone, partialProcessed, quantityLeft, rollbackQueue := ob.processQueue(taker, bestPrice, quantity)
 		done = append(done, ordersDone...)
 		partial = partialDone
 		partialQuantityProcessed = partialProcessed
 		quantity = quantityLeft
-		rollbackPartial = rollbackPart
+		rollbacks = append(rollbacks, rollbackQueue)
 	}
-	var rollbackDone = done
 
 	quantityLeft = quantity
 
-	if rollbackPartial != nil || len(rollbackDone) > 0 {
+	if len(done) > 0 || partial != nil {
 		rollback = func() {
-			if rollbackPartial != nil {
-				rollbackPartial()
-			}
-			for _, o := range rollbackDone {
-				ob.orders[o.ID()] = sideToProcess.Append(o)
-			}
+			undo(rollbacks)
+			ob.lastPrice = lastPrice
 		}
 	}
 	return
@@ -97,6 +141,13 @@ func (ob *OrderBook) ProcessMarketQuantityOrder(side Side, quantity decimal.Deci
 //      partialQuantityProcessed - if partial order is not nil this result contains processed quatity from partial order
 //      quantityLeft - more than zero if it is not enought orders to process all quantity
 func (ob *OrderBook) ProcessMarketPriceBuy(price decimal.Decimal, places int32) (done []*Order, partial *Order, partialQuantityProcessed, priceLeft decimal.Decimal, rollback func(), err error) {
+	return ob.ProcessMarketPriceBuyFor("", price, places)
+}
+
+// ProcessMarketPriceBuyFor works as ProcessMarketPriceBuy on behalf of the given account (owner),
+// the account is reported as taker in the fills of the order
+func (ob *OrderBook) ProcessMarketPriceBuyFor(owner string, price decimal.Decimal, places int32) (done []*Order, partial *Order, partialQuantityProcessed, priceLeft decimal.Decimal, rollback func(), err error) {
+	ob.fills = nil
 	if price.Sign() <= 0 {
 		return nil, nil, decimal.Zero, decimal.Zero, nil, ErrInvalidPrice
 	}
@@ -109,32 +160,37 @@ func (ob *OrderBook) ProcessMarketPriceBuy(price decimal.Decimal, places int32)
 	iter = ob.asks.MinPriceQueue
 	sideToProcess = ob.asks
 
-	var rollbackPartial func()
+	if err = ob.checkPhase(OpMarketOrder | OpMatch); err != nil {
+		return nil, nil, decimal.Zero, decimal.Zero, nil, err
+	}
+
+	if err = ob.checkBand(Buy, decimal.Zero, decimal.Zero, price); err != nil {
+		return nil, nil, decimal.Zero, decimal.Zero, nil, err
+	}
+
+	lastPrice := ob.lastPrice
+	taker := NewOrderWithOwner("", owner, Buy, decimal.Zero, decimal.Zero, ob.now())
+	var rollbacks []func()
 	for price.Sign() > 0 && sideToProcess.Len() > 0 {
 		bestPrice := iter()
 		quantity := price.DivRound(bestPrice.Price(), places)
 		if quantity.Sign() <= 0 {
 			break
 		}
-		ordersDone, partialDone, partialProcessed, quantityLeft, rollbackPart := ob.processQueue(bestPrice, quantity)
+		ordersDone, partialDone, partialProcessed, quantityLeft, rollbackQueue := ob.processQueue(taker, bestPrice, quantity)
 		done = append(done, ordersDone...)
 		partial = partialDone
 		partialQuantityProcessed = partialProcessed
 		price = price.Sub(quantity.Sub(quantityLeft).Mul(bestPrice.price))
-		rollbackPartial = rollbackPart
+		rollbacks = append(rollbacks, rollbackQueue)
 	}
-	var rollbackDone = done
 
 	priceLeft = price
 
-	if rollbackPartial != nil || len(rollbackDone) > 0 {
+	if len(done) > 0 || partial != nil {
 		rollback = func() {
-			if rollbackPartial != nil {
-				rollbackPartial()
-			}
-			for _, o := range rollbackDone {
-				ob.orders[o.ID()] = sideToProcess.Append(o)
-			}
+			undo(rollbacks)
+			ob.lastPrice = lastPrice
 		}
 	}
 	return
@@ -157,6 +213,13 @@ func (ob *OrderBook) ProcessMarketPriceBuy(price decimal.Decimal, places int32)
 //                your order with quantity to left
 //      partialQuantityProcessed - if partial order is not nil this result contains processed quatity from partial order
 func (ob *OrderBook) ProcessLimitOrder(side Side, orderID string, quantity, price decimal.Decimal) (done []*Order, partial *Order, partialQuantityProcessed decimal.Decimal, rollback func(), err error) {
+	return ob.ProcessLimitOrderFor("", side, orderID, quantity, price)
+}
+
+// ProcessLimitOrderFor works as ProcessLimitOrder on behalf of the given account (owner),
+// the placed order keeps the owner and it is reported in the fills of the order
+func (ob *OrderBook) ProcessLimitOrderFor(owner string, side Side, orderID string, quantity, price decimal.Decimal) (done []*Order, partial *Order, partialQuantityProcessed decimal.Decimal, rollback func(), err error) {
+	ob.fills = nil
 	if _, ok := ob.orders[orderID]; ok {
 		return nil, nil, decimal.Zero, nil, ErrOrderExists
 	}
@@ -172,44 +235,54 @@ func (ob *OrderBook) ProcessLimitOrder(side Side, orderID string, quantity, pric
 	quantityToTrade := quantity
 	var (
 		sideToProcess *OrderSide
-		sideToAdd     *OrderSide
 		comparator    func(decimal.Decimal) bool
 		iter          func() *OrderQueue
 	)
 
 	if side == Buy {
-		sideToAdd = ob.bids
 		sideToProcess = ob.asks
 		comparator = price.GreaterThanOrEqual
 		iter = ob.asks.MinPriceQueue
 	} else {
-		sideToAdd = ob.asks
 		sideToProcess = ob.bids
 		comparator = price.LessThanOrEqual
 		iter = ob.bids.MaxPriceQueue
 	}
 
+	ops := OpLimitOrder
+	if ob.crosses(side, price) {
+		ops |= OpMatch
+	}
+	if err = ob.checkPhase(ops); err != nil {
+		return nil, nil, decimal.Zero, nil, err
+	}
+
+	if err = ob.checkBand(side, quantity, price, decimal.Zero); err != nil {
+		return nil, nil, decimal.Zero, nil, err
+	}
+
+	lastPrice := ob.lastPrice
+	taker := NewOrderWithOwner(orderID, owner, side, quantity, price, ob.now())
 	bestPrice := iter()
-	var rollbackPartial func()
+	var rollbacks []func()
 	for quantityToTrade.Sign() > 0 && sideToProcess.Len() > 0 && comparator(bestPrice.Price()) {
-		ordersDone, partialDone, partialQty, quantityLeft, rollbackPart := ob.processQueue(bestPrice, quantityToTrade)
+		ordersDone, partialDone, partialQty, quantityLeft, rollbackQueue := ob.processQueue(taker, bestPrice, quantityToTrade)
 		done = append(done, ordersDone...)
 		partial = partialDone
 		partialQuantityProcessed = partialQty
 		quantityToTrade = quantityLeft
 		bestPrice = iter()
-		rollbackPartial = rollbackPart
+		rollbacks = append(rollbacks, rollbackQueue)
 	}
-	var rollbackDone = done
 	var rollbackCancel string
 
 	if quantityToTrade.Sign() > 0 {
-		o := NewOrder(orderID, side, quantityToTrade, price, time.Now().UTC())
+		o := NewOrderWithOwner(orderID, owner, side, quantityToTrade, price, taker.Time())
 		if len(done) > 0 {
 			partialQuantityProcessed = quantity.Sub(quantityToTrade)
 			partial = o
 		}
-		ob.orders[orderID] = sideToAdd.Append(o)
+		ob.addOrder(o)
 		rollbackCancel = orderID
 	} else {
 		totalQuantity := decimal.Zero
@@ -225,46 +298,144 @@ func (ob *OrderBook) ProcessLimitOrder(side Side, orderID string, quantity, pric
 			totalPrice = totalPrice.Add(partial.Price().Mul(partialQuantityProcessed))
 		}
 
-		done = append(done, NewOrder(orderID, side, quantity, totalPrice.Div(totalQuantity), time.Now().UTC()))
+		done = append(done, NewOrderWithOwner(orderID, owner, side, quantity, totalPrice.Div(totalQuantity), taker.Time()))
 	}
-	if len(rollbackCancel) > 0 || rollbackPartial != nil || len(rollbackDone) > 0 {
+	if len(rollbackCancel) > 0 || len(rollbacks) > 0 {
 		rollback = func() {
 			if len(rollbackCancel) > 0 {
-				ob.CancelOrder(rollbackCancel)
-			}
-			if rollbackPartial != nil {
-				rollbackPartial()
-			}
-			for _, o := range rollbackDone {
-				ob.orders[o.ID()] = sideToProcess.Append(o)
+				ob.cancelOrder(rollbackCancel)
 			}
+			undo(rollbacks)
+			ob.lastPrice = lastPrice
 		}
 	}
 	return
 }
 
-func (ob *OrderBook) processQueue(orderQueue *OrderQueue, quantityToTrade decimal.Decimal) (done []*Order, partial *Order, partialQuantityProcessed, quantityLeft decimal.Decimal, rollbackPartial func()) {
+// processQueue matches the taker with the orders of the price level from the head, rollback
+// restores the partially filled order and puts the filled ones back ahead of it
+func (ob *OrderBook) processQueue(taker *Order, orderQueue *OrderQueue, quantityToTrade decimal.Decimal) (done []*Order, partial *Order, partialQuantityProcessed, quantityLeft decimal.Decimal, rollback func()) {
 	quantityLeft = quantityToTrade
+	var amended *Order
+	var removed []removedOrder
+	start := len(ob.fills)
 
 	for orderQueue.Len() > 0 && quantityLeft.Sign() > 0 {
 		headOrderEl := orderQueue.Head()
 		headOrder := headOrderEl.Value.(*Order)
 
 		if quantityLeft.LessThan(headOrder.Quantity()) {
-			partial = NewOrder(headOrder.ID(), headOrder.Side(), headOrder.Quantity().Sub(quantityLeft), headOrder.Price(), headOrder.Time())
+			partial = NewOrderWithOwner(headOrder.ID(), headOrder.Owner(), headOrder.Side(), headOrder.Quantity().Sub(quantityLeft), headOrder.Price(), headOrder.Time())
 			partialQuantityProcessed = quantityLeft
-			orderQueue.Update(headOrderEl, partial)
+			ob.updateOrder(headOrderEl, partial)
+			ob.fill(taker, headOrder, quantityLeft)
 			quantityLeft = decimal.Zero
-			rollbackPartial = func() { orderQueue.Update(headOrderEl, headOrder) }
+			amended = headOrder
 		} else {
 			quantityLeft = quantityLeft.Sub(headOrder.Quantity())
+			next := ob.nextID(headOrderEl)
 			done = append(done, ob.cancelOrder(headOrder.ID()))
+			removed = append(removed, removedOrder{order: headOrder, next: next})
+			ob.fill(taker, headOrder, headOrder.Quantity())
 		}
 	}
 
+	fills := ob.fills[start:len(ob.fills):len(ob.fills)]
+	rollback = func() {
+		if amended != nil {
+			// the element may be re-created by rollbacks of the intervening operations
+			if e, ok := ob.orders[amended.ID()]; ok {
+				ob.updateOrder(e, amended)
+			}
+		}
+		ob.restoreOrders(removed)
+		ob.revertFills(fills)
+	}
 	return
 }
 
+// removedOrder is the order removed from the book with ID of the order following it in the
+// price level, empty if it was the tail
+type removedOrder struct {
+	order *Order
+	next  string
+}
+
+// undo calls rollbacks in reverse order
+func undo(rollbacks []func()) {
+	for i := len(rollbacks) - 1; i >= 0; i-- {
+		if rollbacks[i] != nil {
+			rollbacks[i]()
+		}
+	}
+}
+
+// fill records the trade between taker and resting maker order with the maker price
+func (ob *OrderBook) fill(taker, maker *Order, quantity decimal.Decimal) {
+	f := &Fill{
+		TakerID:    taker.ID(),
+		TakerOwner: taker.Owner(),
+		MakerID:    maker.ID(),
+		MakerOwner: maker.Owner(),
+		Side:       taker.Side(),
+		Price:      maker.Price(),
+		Quantity:   quantity,
+		MakerFee:   decimal.Zero,
+		TakerFee:   decimal.Zero,
+		Time:       taker.Time(),
+	}
+	if ob.fees != nil {
+		f.MakerFee, f.TakerFee = ob.fees.Fees(f)
+	}
+	ob.fills = append(ob.fills, f)
+	ob.lastPrice = f.Price
+	for _, handler := range ob.handlers {
+		handler(f)
+	}
+}
+
+// revertFills notifies the fee model and the rollback handlers about rolled back fills in
+// reverse order, the fills are removed from Fills if they are produced by the last order
+func (ob *OrderBook) revertFills(fills []*Fill) {
+	reverter, _ := ob.fees.(FeeReverter)
+	for i := len(fills) - 1; i >= 0; i-- {
+		if reverter != nil {
+			reverter.Revert(fills[i])
+		}
+		for _, handler := range ob.rollbackHandlers {
+			handler(fills[i])
+		}
+	}
+
+	if n, k := len(ob.fills), len(fills); k > 0 && n >= k && ob.fills[n-1] == fills[k-1] {
+		ob.fills = ob.fills[: n-k : n-k]
+	}
+}
+
+// Fills returns trades produced by the last processed market or limit order,
+// fills are removed by rollback of the order
+func (ob *OrderBook) Fills() []*Fill {
+	return ob.fills
+}
+
+// OnFill registers handler called for every fill right after it is produced.
+// Handlers are not notified about rollback of the order, see OnRollback
+func (ob *OrderBook) OnFill(handler FillHandler) {
+	ob.handlers = append(ob.handlers, handler)
+}
+
+// OnRollback registers handler called for every fill of the rolled back order,
+// fills are passed in reverse order of their production
+func (ob *OrderBook) OnRollback(handler FillHandler) {
+	ob.rollbackHandlers = append(ob.rollbackHandlers, handler)
+}
+
+// SetFeeModel sets up model used to calculate maker and taker fees of every fill,
+// nil model disables fees calculation
+func (ob *OrderBook) SetFeeModel(fees FeeModel) {
+	ob.fees = fees
+}
+
 // Order returns order by id
 func (ob *OrderBook) Order(orderID string) *Order {
 	e, ok := ob.orders[orderID]
@@ -315,20 +486,88 @@ func (ob *OrderBook) Depth(max int) (depth *Depth) {
 	return
 }
 
-// CancelOrder removes order with given ID from the order book
+// CancelOrder removes order with given ID from the order book,
+// order is nil if it does not exist or cancelling is not allowed in the trading phase,
+// Allowed(OpCancel) tells the cases apart. Apply of CommandCancel reports them as
+// ErrOrderNotExists, ErrHalted or ErrNotAllowed
 func (ob *OrderBook) CancelOrder(orderID string) (order *Order, rollback func()) {
-	order = ob.cancelOrder(orderID)
-	if order == nil {
+	if ob.checkPhase(OpCancel) != nil {
 		return
 	}
+
+	e, ok := ob.orders[orderID]
+	if !ok {
+		return
+	}
+	removed := []removedOrder{{next: ob.nextID(e)}}
+	order = ob.cancelOrder(orderID)
+	removed[0].order = order
 	rollback = func() {
-		if order.Side() == Buy {
-			ob.orders[order.ID()] = ob.bids.Append(order)
+		ob.restoreOrders(removed)
+	}
+	return
+}
+
+// addOrder appends order to the tail of its price level and indexes it by ID and owner
+func (ob *OrderBook) addOrder(o *Order) {
+	var e *list.Element
+	if o.Side() == Buy {
+		e = ob.bids.Append(o)
+	} else {
+		e = ob.asks.Append(o)
+	}
+	ob.indexOrder(e)
+}
+
+// nextID returns ID of the order following the element in its price level, empty for the tail
+func (ob *OrderBook) nextID(e *list.Element) string {
+	if next := e.Next(); next != nil {
+		return next.Value.(*Order).ID()
+	}
+	return ""
+}
+
+// restoreOrders puts removed orders back in reverse order of removal, every order is inserted
+// before the order it preceded so it gets its time priority back
+func (ob *OrderBook) restoreOrders(removed []removedOrder) {
+	for i := len(removed) - 1; i >= 0; i-- {
+		o, next := removed[i].order, removed[i].next
+		mark, ok := ob.orders[next]
+		if len(next) == 0 || !ok || mark.Value.(*Order).Side() != o.Side() || !mark.Value.(*Order).Price().Equal(o.Price()) {
+			ob.addOrder(o)
+			continue
+		}
+
+		if o.Side() == Buy {
+			ob.indexOrder(ob.bids.InsertBefore(o, mark))
 		} else {
-			ob.orders[order.ID()] = ob.asks.Append(order)
+			ob.indexOrder(ob.asks.InsertBefore(o, mark))
 		}
 	}
-	return
+}
+
+// updateOrder replaces resting order with its amended copy keeping the time priority
+func (ob *OrderBook) updateOrder(e *list.Element, o *Order) {
+	if o.Side() == Buy {
+		ob.bids.Update(e, o)
+	} else {
+		ob.asks.Update(e, o)
+	}
+}
+
+func (ob *OrderBook) indexOrder(e *list.Element) {
+	o := e.Value.(*Order)
+	ob.orders[o.ID()] = e
+	if len(o.Owner()) == 0 {
+		return
+	}
+
+	owned, ok := ob.owners[o.Owner()]
+	if !ok {
+		owned = map[string]*list.Element{}
+		ob.owners[o.Owner()] = owned
+	}
+	owned[o.ID()] = e
 }
 
 func (ob *OrderBook) cancelOrder(orderID string) (order *Order) {
@@ -338,6 +577,13 @@ func (ob *OrderBook) cancelOrder(orderID string) (order *Order) {
 	}
 
 	delete(ob.orders, orderID)
+	if owner := e.Value.(*Order).Owner(); len(owner) > 0 {
+		owned := ob.owners[owner]
+		delete(owned, orderID)
+		if len(owned) == 0 {
+			delete(ob.owners, owner)
+		}
+	}
 
 	if e.Value.(*Order).Side() == Buy {
 		return ob.bids.Remove(e)
@@ -349,39 +595,8 @@ func (ob *OrderBook) cancelOrder(orderID string) (order *Order) {
 // CalculateMarketPrice returns total market price for requested quantity
 // if err is not nil price returns total price of all levels in side
 func (ob *OrderBook) CalculateMarketPrice(side Side, quantity decimal.Decimal) (price decimal.Decimal, err error) {
-	price = decimal.Zero
-
-	var (
-		level *OrderQueue
-		iter  func(decimal.Decimal) *OrderQueue
-	)
-
-	if side == Buy {
-		level = ob.asks.MinPriceQueue()
-		iter = ob.asks.GreaterThan
-	} else {
-		level = ob.bids.MaxPriceQueue()
-		iter = ob.bids.LessThan
-	}
-
-	for quantity.Sign() > 0 && level != nil {
-		levelVolume := level.Volume()
-		levelPrice := level.Price()
-		if quantity.GreaterThanOrEqual(levelVolume) {
-			price = price.Add(levelPrice.Mul(levelVolume))
-			quantity = quantity.Sub(levelVolume)
-			level = iter(levelPrice)
-		} else {
-			price = price.Add(levelPrice.Mul(quantity))
-			quantity = decimal.Zero
-		}
-	}
-
-	if quantity.Sign() > 0 {
-		err = ErrInsufficientQuantity
-	}
-
-	return
+	impact, err := ob.CalculateMarketImpact(side, quantity)
+	return impact.Total, err
 }
 
 // String implements fmt.Stringer interface
@@ -402,7 +617,8 @@ func (ob *OrderBook) MarshalJSON() ([]byte, error) {
 	)
 }
 
-// UnmarshalJSON implements json.Unmarshaler interface
+// UnmarshalJSON implements json.Unmarshaler interface.
+// Restored book is validated (see Validate), the book is not changed on error
 func (ob *OrderBook) UnmarshalJSON(data []byte) error {
 	obj := struct {
 		Asks *OrderSide `json:"asks"`
@@ -413,17 +629,5 @@ func (ob *OrderBook) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	ob.asks = obj.Asks
-	ob.bids = obj.Bids
-	ob.orders = map[string]*list.Element{}
-
-	for _, order := range ob.asks.Orders() {
-		ob.orders[order.Value.(*Order).ID()] = order
-	}
-
-	for _, order := range ob.bids.Orders() {
-		ob.orders[order.Value.(*Order).ID()] = order
-	}
-
-	return nil
+	return ob.restore(obj.Asks, obj.Bids)
 }
@@ -586,6 +586,32 @@ func TestLimitRollback(t *testing.T) {
 	}
 }
 
+func TestRollbackPriority(t *testing.T) {
+	ob := NewOrderBook()
+	for i := 1; i <= 3; i++ {
+		ob.ProcessLimitOrder(Sell, fmt.Sprintf("s-%d", i), decimal.New(int64(i), 0), decimal.New(100, 0))
+	}
+
+	// fills s-1 and part of s-2, the rest of s-2 is cancelled before the rollbacks
+	_, _, _, _, rollbackMarket, _ := ob.ProcessMarketQuantityOrder(Buy, decimal.New(2, 0))
+	_, rollbackCancel := ob.CancelOrder("s-2")
+	rollbackCancel()
+	rollbackMarket()
+
+	queue := ob.asks.MinPriceQueue()
+	if queue.Len() != 3 || !queue.Volume().Equal(decimal.New(6, 0)) {
+		t.Fatalf("invalid price level: %s", queue)
+	}
+	i := 1
+	for e := queue.Head(); e != nil; e = e.Next() {
+		o := e.Value.(*Order)
+		if o.ID() != fmt.Sprintf("s-%d", i) || !o.Quantity().Equal(decimal.New(int64(i), 0)) {
+			t.Fatalf("invalid order at %d: %s", i, o)
+		}
+		i++
+	}
+}
+
 func TestCancelRollback(t *testing.T) {
 	ob := NewOrderBook()
 	{ //buy cancel rollback
@@ -14,6 +14,8 @@ type OrderQueue struct {
 	volume decimal.Decimal
 	price  decimal.Decimal
 	orders *list.List
+
+	index *positionIndex // nil until the first Position call
 }
 
 // NewOrderQueue creates and initialize OrderQueue object
@@ -53,13 +55,31 @@ func (oq *OrderQueue) Tail() *list.Element {
 // Append adds order to tail of the queue
 func (oq *OrderQueue) Append(o *Order) *list.Element {
 	oq.volume = oq.volume.Add(o.Quantity())
-	return oq.orders.PushBack(o)
+	e := oq.orders.PushBack(o)
+	if oq.index != nil {
+		if oq.index.full() {
+			oq.index = newPositionIndex(oq.orders)
+		} else {
+			oq.index.append(e)
+		}
+	}
+	return e
+}
+
+// InsertBefore adds order to the queue before the mark element, e.g. to restore its priority
+func (oq *OrderQueue) InsertBefore(o *Order, mark *list.Element) *list.Element {
+	oq.volume = oq.volume.Add(o.Quantity())
+	oq.index = nil // positions of the following orders are shifted, rebuilt on demand
+	return oq.orders.InsertBefore(o, mark)
 }
 
 // Update sets up new order to list value
 func (oq *OrderQueue) Update(e *list.Element, o *Order) *list.Element {
 	oq.volume = oq.volume.Sub(e.Value.(*Order).Quantity())
 	oq.volume = oq.volume.Add(o.Quantity())
+	if oq.index != nil {
+		oq.index.update(e, o.Quantity().Sub(e.Value.(*Order).Quantity()))
+	}
 	e.Value = o
 	return e
 }
@@ -67,6 +87,9 @@ func (oq *OrderQueue) Update(e *list.Element, o *Order) *list.Element {
 // Remove removes order from the queue and link order chain
 func (oq *OrderQueue) Remove(e *list.Element) *Order {
 	oq.volume = oq.volume.Sub(e.Value.(*Order).Quantity())
+	if oq.index != nil {
+		oq.index.remove(e)
+	}
 	return oq.orders.Remove(e).(*Order)
 }
 
@@ -107,23 +130,31 @@ func (oq *OrderQueue) MarshalJSON() ([]byte, error) {
 	)
 }
 
-// UnmarshalJSON implements json.Unmarshaler interface
+// UnmarshalJSON implements json.Unmarshaler interface.
+// Volume is recalculated from the orders, the queue is rejected if it doesn't match the payload
 func (oq *OrderQueue) UnmarshalJSON(data []byte) error {
 	obj := struct {
-		Volume decimal.Decimal `json:"volume"`
-		Price  decimal.Decimal `json:"price"`
-		Orders []*Order        `json:"orders"`
+		Volume *decimal.Decimal `json:"volume"`
+		Price  decimal.Decimal  `json:"price"`
+		Orders []*Order         `json:"orders"`
 	}{}
 
 	if err := json.Unmarshal(data, &obj); err != nil {
 		return err
 	}
 
-	oq.volume = obj.Volume
-	oq.price = obj.Price
-	oq.orders = list.New()
+	queue := NewOrderQueue(obj.Price)
 	for _, order := range obj.Orders {
-		oq.orders.PushBack(order)
+		if order == nil {
+			return fmt.Errorf("%w: price level %s contains empty order", ErrInvalidSnapshot, obj.Price)
+		}
+		queue.Append(order)
 	}
+
+	if obj.Volume != nil && !obj.Volume.Equal(queue.Volume()) {
+		return fmt.Errorf("%w: price level %s volume is %s, orders volume is %s", ErrInvalidSnapshot, obj.Price, obj.Volume, queue.Volume())
+	}
+
+	*oq = *queue
 	return nil
 }
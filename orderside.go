@@ -4,6 +4,7 @@ import (
 	"container/list"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	rbtx "github.com/emirpasic/gods/examples/redblacktreeextended"
@@ -68,6 +69,20 @@ func (os *OrderSide) Append(o *Order) *list.Element {
 	return priceQueue.Append(o)
 }
 
+// Update sets up new order to list value, order must have the same price
+func (os *OrderSide) Update(e *list.Element, o *Order) *list.Element {
+	os.volume = os.volume.Sub(e.Value.(*Order).Quantity())
+	os.volume = os.volume.Add(o.Quantity())
+	return os.prices[o.Price().String()].Update(e, o)
+}
+
+// InsertBefore adds order to the side before the mark element of its price level
+func (os *OrderSide) InsertBefore(o *Order, mark *list.Element) *list.Element {
+	os.numOrders++
+	os.volume = os.volume.Add(o.Quantity())
+	return os.prices[o.Price().String()].InsertBefore(o, mark)
+}
+
 // Remove removes order from definite price level
 func (os *OrderSide) Remove(e *list.Element) *Order {
 	price := e.Value.(*Order).Price()
@@ -191,11 +206,13 @@ func (os *OrderSide) MarshalJSON() ([]byte, error) {
 	)
 }
 
-// UnmarshalJSON implements json.Unmarshaler interface
+// UnmarshalJSON implements json.Unmarshaler interface.
+// Aggregates are recalculated from the orders, the side is rejected if they don't match
+// the payload or price levels are inconsistent
 func (os *OrderSide) UnmarshalJSON(data []byte) error {
 	obj := struct {
-		NumOrders int                    `json:"numOrders"`
-		Depth     int                    `json:"depth"`
+		NumOrders *int                   `json:"numOrders"`
+		Depth     *int                   `json:"depth"`
 		Prices    map[string]*OrderQueue `json:"prices"`
 	}{}
 
@@ -203,16 +220,43 @@ func (os *OrderSide) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	os.numOrders = obj.NumOrders
-	os.depth = obj.Depth
-	os.prices = obj.Prices
-	os.priceTree = &rbtx.RedBlackTreeExtended{
-		Tree: rbt.NewWith(rbtComparator),
+	// levels are appended in price order to keep restoring deterministic
+	queues := make([]*OrderQueue, 0, len(obj.Prices))
+	for price, queue := range obj.Prices {
+		if queue == nil || queue.Len() == 0 {
+			return fmt.Errorf("%w: price level %s is empty", ErrInvalidSnapshot, price)
+		}
+		if p, err := decimal.NewFromString(price); err != nil || !p.Equal(queue.Price()) {
+			return fmt.Errorf("%w: price level key %s doesn't match level price %s", ErrInvalidSnapshot, price, queue.Price())
+		}
+		queues = append(queues, queue)
+	}
+	sort.Slice(queues, func(i, j int) bool {
+		return queues[i].Price().LessThan(queues[j].Price())
+	})
+
+	side := NewOrderSide()
+	for _, queue := range queues {
+		if _, ok := side.prices[queue.Price().String()]; ok {
+			return fmt.Errorf("%w: duplicated price level %s", ErrInvalidSnapshot, queue.Price())
+		}
+		for iter := queue.Head(); iter != nil; iter = iter.Next() {
+			o := iter.Value.(*Order)
+			if err := validateOrder(o, queue.Price()); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+			}
+			side.Append(o)
+		}
+	}
+
+	if obj.NumOrders != nil && *obj.NumOrders != side.Len() {
+		return fmt.Errorf("%w: side has %d orders, payload declares %d", ErrInvalidSnapshot, side.Len(), *obj.NumOrders)
 	}
 
-	for price, queue := range os.prices {
-		os.priceTree.Put(decimal.RequireFromString(price), queue)
+	if obj.Depth != nil && *obj.Depth != side.Depth() {
+		return fmt.Errorf("%w: side has %d price levels, payload declares %d", ErrInvalidSnapshot, side.Depth(), *obj.Depth)
 	}
 
+	*os = *side
 	return nil
 }
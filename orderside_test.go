@@ -145,3 +145,18 @@ func BenchmarkOrderSide(b *testing.B) {
 	elapsed := time.Since(stopwatch)
 	fmt.Printf("\n\nElapsed: %s\nTransactions per second: %f\n", elapsed, float64(b.N)/elapsed.Seconds())
 }
+
+func TestOrderSideUpdate(t *testing.T) {
+	os := NewOrderSide()
+	o := NewOrder("one", Buy, decimal.New(10, 0), decimal.New(100, 0), time.Now().UTC())
+	e := os.Append(o)
+	os.Append(NewOrder("two", Buy, decimal.New(5, 0), decimal.New(100, 0), time.Now().UTC()))
+
+	os.Update(e, NewOrder("one", Buy, decimal.New(4, 0), decimal.New(100, 0), o.Time()))
+	if !os.Volume().Equal(decimal.New(9, 0)) || !os.MaxPriceQueue().Volume().Equal(decimal.New(9, 0)) {
+		t.Fatalf("invalid volume: %s", os.Volume())
+	}
+	if os.MaxPriceQueue().Head() != e {
+		t.Fatal("updated order lost priority")
+	}
+}
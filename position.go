@@ -0,0 +1,114 @@
+package orderbook
+
+import (
+	"container/list"
+
+	"github.com/shopspring/decimal"
+)
+
+// positionIndex implements order statistics over the OrderQueue (Fenwick tree).
+// Every order gets a slot in order of appending, so the number of orders and
+// volume ahead of the order are prefix sums over the slots
+type positionIndex struct {
+	slots   map[*list.Element]int
+	counts  []int
+	volumes []decimal.Decimal
+	next    int
+}
+
+func newPositionIndex(orders *list.List) *positionIndex {
+	size := 2*orders.Len() + 16
+	idx := &positionIndex{
+		slots:   make(map[*list.Element]int, orders.Len()),
+		counts:  make([]int, size+1),
+		volumes: make([]decimal.Decimal, size+1),
+		next:    1,
+	}
+
+	for iter := orders.Front(); iter != nil; iter = iter.Next() {
+		idx.slots[iter] = idx.next
+		idx.counts[idx.next] = 1
+		idx.volumes[idx.next] = iter.Value.(*Order).Quantity()
+		idx.next++
+	}
+
+	// linear construction of the tree from slot values
+	for i := 1; i <= size; i++ {
+		if parent := i + i&-i; parent <= size {
+			idx.counts[parent] += idx.counts[i]
+			idx.volumes[parent] = idx.volumes[parent].Add(idx.volumes[i])
+		}
+	}
+	return idx
+}
+
+func (idx *positionIndex) add(slot, count int, volume decimal.Decimal) {
+	for ; slot < len(idx.counts); slot += slot & -slot {
+		idx.counts[slot] += count
+		idx.volumes[slot] = idx.volumes[slot].Add(volume)
+	}
+}
+
+func (idx *positionIndex) prefix(slot int) (count int, volume decimal.Decimal) {
+	volume = decimal.Zero
+	for ; slot > 0; slot -= slot & -slot {
+		count += idx.counts[slot]
+		volume = volume.Add(idx.volumes[slot])
+	}
+	return
+}
+
+// full reports that there are no free slots for appending
+func (idx *positionIndex) full() bool {
+	return idx.next >= len(idx.counts)
+}
+
+func (idx *positionIndex) append(e *list.Element) {
+	idx.slots[e] = idx.next
+	idx.add(idx.next, 1, e.Value.(*Order).Quantity())
+	idx.next++
+}
+
+func (idx *positionIndex) update(e *list.Element, delta decimal.Decimal) {
+	idx.add(idx.slots[e], 0, delta)
+}
+
+func (idx *positionIndex) remove(e *list.Element) {
+	idx.add(idx.slots[e], -1, e.Value.(*Order).Quantity().Neg())
+	delete(idx.slots, e)
+}
+
+// Position returns rank of the order in the queue (starting from 1) and total
+// quantity of the orders ahead of it. Rank is 0 if the element is not in the queue.
+// The index is built on the first call and maintained by the queue after that
+func (oq *OrderQueue) Position(e *list.Element) (rank int, volumeAhead decimal.Decimal) {
+	if oq.index == nil {
+		oq.index = newPositionIndex(oq.orders)
+	}
+
+	slot, ok := oq.index.slots[e]
+	if !ok {
+		return 0, decimal.Zero
+	}
+
+	ahead, volumeAhead := oq.index.prefix(slot - 1)
+	return ahead + 1, volumeAhead
+}
+
+// QueuePosition returns rank of the order within its price level (starting from 1)
+// and total quantity of the orders ahead of it
+func (ob *OrderBook) QueuePosition(orderID string) (rank int, volumeAhead decimal.Decimal, err error) {
+	e, ok := ob.orders[orderID]
+	if !ok {
+		return 0, decimal.Zero, ErrOrderNotExists
+	}
+
+	o := e.Value.(*Order)
+	side := ob.asks
+	if o.Side() == Buy {
+		side = ob.bids
+	}
+
+	rank, volumeAhead = side.prices[o.Price().String()].Position(e)
+	return
+}
@@ -0,0 +1,94 @@
+package orderbook
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestQueuePosition(t *testing.T) {
+	ob := NewOrderBook()
+	for i := 1; i <= 4; i++ {
+		ob.ProcessLimitOrder(Sell, fmt.Sprintf("s-%d", i), decimal.New(int64(i), 0), decimal.New(100, 0))
+	}
+
+	rank, ahead, err := ob.QueuePosition("s-3")
+	if err != nil || rank != 3 || !ahead.Equal(decimal.New(3, 0)) {
+		t.Fatalf("invalid position: %d, %s, %v", rank, ahead, err)
+	}
+
+	// partial fill of the head order
+	ob.ProcessMarketQuantityOrder(Buy, decimal.New(2, 0))
+	if rank, ahead, _ = ob.QueuePosition("s-3"); rank != 2 || !ahead.Equal(decimal.New(1, 0)) {
+		t.Fatalf("invalid position after match: %d, %s", rank, ahead)
+	}
+
+	ob.CancelOrder("s-2")
+	ob.ProcessLimitOrder(Sell, "s-5", decimal.New(5, 0), decimal.New(100, 0))
+	if rank, ahead, _ = ob.QueuePosition("s-5"); rank != 3 || !ahead.Equal(decimal.New(7, 0)) {
+		t.Fatalf("invalid position after cancel: %d, %s", rank, ahead)
+	}
+
+	if rank, ahead, _ = ob.QueuePosition("s-3"); rank != 1 || ahead.Sign() != 0 {
+		t.Fatalf("invalid head position: %d, %s", rank, ahead)
+	}
+
+	if _, _, err := ob.QueuePosition("fake"); err != ErrOrderNotExists {
+		t.Fatal("can get position of fake order")
+	}
+}
+
+func TestQueuePositionRollback(t *testing.T) {
+	ob := NewOrderBook()
+	for i := 1; i <= 3; i++ {
+		ob.ProcessLimitOrder(Sell, fmt.Sprintf("s-%d", i), decimal.New(int64(i), 0), decimal.New(100, 0))
+	}
+
+	// fills s-1 and part of s-2, rollback puts s-1 back ahead of s-2
+	_, _, _, _, rollback, _ := ob.ProcessMarketQuantityOrder(Buy, decimal.New(2, 0))
+	if rank, _, _ := ob.QueuePosition("s-3"); rank != 2 {
+		t.Fatalf("invalid position after match: %d", rank)
+	}
+	rollback()
+
+	for i := 1; i <= 3; i++ {
+		rank, ahead, err := ob.QueuePosition(fmt.Sprintf("s-%d", i))
+		if err != nil || rank != i || !ahead.Equal(decimal.New(int64(i*(i-1)/2), 0)) {
+			t.Fatalf("invalid position of s-%d after rollback: %d, %s, %v", i, rank, ahead, err)
+		}
+	}
+}
+
+func TestQueuePositionRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	oq := NewOrderQueue(decimal.New(100, 0))
+	oq.Position(nil) // build index before changes
+
+	for i := 0; i < 2000; i++ {
+		switch n := rnd.Intn(10); {
+		case n < 5 || oq.Len() == 0:
+			oq.Append(NewOrder(fmt.Sprint(i), Buy, decimal.New(int64(rnd.Intn(10)+1), 0), oq.Price(), time.Now().UTC()))
+		case n < 8:
+			e := oq.Head()
+			for j := rnd.Intn(oq.Len()); j > 0; j-- {
+				e = e.Next()
+			}
+			oq.Remove(e)
+		default:
+			o := oq.Head().Value.(*Order)
+			oq.Update(oq.Head(), NewOrder(o.ID(), o.Side(), decimal.New(int64(rnd.Intn(10)+1), 0), o.Price(), o.Time()))
+		}
+
+		rank, ahead := 1, decimal.Zero
+		for e := oq.Head(); e != nil; e = e.Next() {
+			if r, v := oq.Position(e); r != rank || !v.Equal(ahead) {
+				t.Fatalf("step %d: invalid position %d, %s (want: %d, %s)", i, r, v, rank, ahead)
+			}
+			rank++
+			ahead = ahead.Add(e.Value.(*Order).Quantity())
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package orderbook
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// operation kinds of the property tests
+const (
+	opLimit = iota
+	opMarket
+	opMarketPrice
+	opCancel
+	opReplace
+	opCancelOwner
+	opUndo   // rolls back the most recent operations
+	opCommit // forgets rollbacks of the previous operations
+	opKinds
+)
+
+type operation struct {
+	kind     int
+	side     Side
+	id       string
+	owner    string
+	quantity decimal.Decimal
+	price    decimal.Decimal
+	n        int // number of operations to undo
+}
+
+func (op operation) String() string {
+	return fmt.Sprintf("%d %s %s %s %s@%s undo %d", op.kind, op.side, op.id, op.owner, op.quantity, op.price, op.n)
+}
+
+// decodeOperations maps every 4 bytes to operation, prices and IDs are picked from small ranges
+// so the operations cross, match, hit existing and missing orders
+func decodeOperations(data []byte) (ops []operation) {
+	for ; len(data) >= 4; data = data[4:] {
+		op := operation{
+			kind:     int(data[0]) % opKinds,
+			side:     Side(data[1] & 1),
+			id:       fmt.Sprintf("o%d", data[1]>>1%32),
+			owner:    fmt.Sprintf("u%d", data[1]>>6),
+			quantity: decimal.New(int64(data[2]%16)+1, -int32(data[2]>>4%3)),
+			price:    decimal.New(int64(data[3]%16)+90, -int32(data[3]>>4%2)),
+			n:        int(data[2]%4) + 1,
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// state is the observable state of the book restored by rollback
+type state struct {
+	hash      [sha256.Size]byte
+	lastPrice decimal.Decimal
+}
+
+func stateOf(ob *OrderBook) state {
+	return state{hash: ob.StateHash(), lastPrice: ob.LastPrice()}
+}
+
+func (s state) equal(other state) bool {
+	return s.hash == other.hash && s.lastPrice.Equal(other.lastPrice)
+}
+
+// step is the rollback of the operation with the state before it
+type step struct {
+	op       operation
+	before   state
+	rollback func()
+}
+
+// runOperations applies the operations and checks invariants of the book after every call:
+// validate (no crossed book, aggregates of sides and levels, ID and owner indexes), rejected
+// operations keep the state and rollbacks applied in reverse order restore the states before
+// the operations even after intervening operations
+func runOperations(t testing.TB, ops []operation) {
+	t.Helper()
+	ob := NewOrderBook()
+	var stack []step
+
+	for i, op := range ops {
+		before := stateOf(ob)
+		var rollback func()
+		var err error
+
+		switch op.kind {
+		case opLimit:
+			_, _, _, rollback, err = ob.ProcessLimitOrderFor(op.owner, op.side, op.id, op.quantity, op.price)
+		case opMarket:
+			_, _, _, _, rollback, err = ob.ProcessMarketQuantityOrderFor(op.owner, op.side, op.quantity)
+		case opMarketPrice:
+			_, _, _, _, rollback, err = ob.ProcessMarketPriceBuyFor(op.owner, op.price.Mul(op.quantity), 2)
+		case opCancel:
+			_, rollback = ob.CancelOrder(op.id)
+		case opReplace:
+			_, _, _, rollback, err = ob.ReplaceOrder(op.id, op.quantity, op.price)
+		case opCancelOwner:
+			_, rollback = ob.CancelAllOwner(op.owner)
+		case opUndo:
+			for n := 0; n < op.n && len(stack) > 0; n++ {
+				u := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				u.rollback()
+				if err := ob.validate(); err != nil {
+					t.Fatalf("operation %d: rollback of %s: %v", i, u.op, err)
+				}
+				if !stateOf(ob).equal(u.before) {
+					t.Fatalf("operation %d: rollback of %s does not restore the state", i, u.op)
+				}
+			}
+			continue
+		case opCommit:
+			stack = stack[:0]
+			continue
+		}
+
+		if err := ob.validate(); err != nil {
+			t.Fatalf("operation %d %s: %v", i, op, err)
+		}
+		if err != nil && (rollback != nil || !stateOf(ob).equal(before)) {
+			t.Fatalf("operation %d %s: rejected with %v but changed the book", i, op, err)
+		}
+		if rollback != nil {
+			stack = append(stack, step{op: op, before: before, rollback: rollback})
+		}
+	}
+}
+
+func TestPropertyOperations(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	data := make([]byte, 4*300)
+	for i := 0; i < 500; i++ {
+		r.Read(data)
+		runOperations(t, decodeOperations(data))
+	}
+}
+
+func TestPropertyRollbackPriority(t *testing.T) {
+	// market order fills the first order of the level and part of the second one,
+	// rollback must put the first order back ahead of the second one
+	runOperations(t, []operation{
+		{kind: opLimit, side: Sell, id: "a", quantity: decimal.New(1, 0), price: decimal.New(100, 0)},
+		{kind: opLimit, side: Sell, id: "b", quantity: decimal.New(5, 0), price: decimal.New(100, 0)},
+		{kind: opMarket, side: Buy, quantity: decimal.New(3, 0)},
+		{kind: opUndo, n: 1},
+	})
+}
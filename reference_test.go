@@ -0,0 +1,261 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// refOrder is the resting order of the reference book
+type refOrder struct {
+	id       string
+	owner    string
+	side     Side
+	quantity decimal.Decimal
+	price    decimal.Decimal
+}
+
+// refFill is the trade of the reference book, fields are the same as of Fill
+type refFill struct {
+	takerID    string
+	takerOwner string
+	makerID    string
+	makerOwner string
+	side       Side
+	price      decimal.Decimal
+	quantity   decimal.Decimal
+}
+
+// refBook is the deliberately simple reference matcher for differential tests. Sides are slices
+// of orders sorted from the best price, orders of the same price are kept in arrival order, every
+// operation scans the slices. It follows the specification of OrderBook without its data
+// structures, so both must produce the same fills and depth for the same operations
+type refBook struct {
+	asks      []refOrder
+	bids      []refOrder
+	lastPrice decimal.Decimal
+	fills     []refFill
+}
+
+func (b *refBook) clone() *refBook {
+	return &refBook{
+		asks:      append([]refOrder(nil), b.asks...),
+		bids:      append([]refOrder(nil), b.bids...),
+		lastPrice: b.lastPrice,
+	}
+}
+
+func (b *refBook) side(side Side) *[]refOrder {
+	if side == Buy {
+		return &b.bids
+	}
+	return &b.asks
+}
+
+func (b *refBook) opposite(side Side) *[]refOrder {
+	if side == Buy {
+		return &b.asks
+	}
+	return &b.bids
+}
+
+// better reports whether the price has priority over the other one on the side
+func better(side Side, price, other decimal.Decimal) bool {
+	if side == Buy {
+		return price.GreaterThan(other)
+	}
+	return price.LessThan(other)
+}
+
+func (b *refBook) find(id string) (*[]refOrder, int) {
+	for _, orders := range []*[]refOrder{&b.asks, &b.bids} {
+		for i, o := range *orders {
+			if o.id == id {
+				return orders, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// insert places the order after all orders with the same or better price
+func (b *refBook) insert(o refOrder) {
+	orders := b.side(o.side)
+	i := 0
+	for i < len(*orders) && !better(o.side, o.price, (*orders)[i].price) {
+		i++
+	}
+	*orders = append(*orders, refOrder{})
+	copy((*orders)[i+1:], (*orders)[i:])
+	(*orders)[i] = o
+}
+
+func (b *refBook) remove(orders *[]refOrder, i int) refOrder {
+	o := (*orders)[i]
+	*orders = append((*orders)[:i], (*orders)[i+1:]...)
+	return o
+}
+
+// match trades the taker with the head of the opposite side while it is accepted by the limit,
+// every trade has the maker price
+func (b *refBook) match(taker refOrder, quantity decimal.Decimal, limit func(decimal.Decimal) bool) decimal.Decimal {
+	orders := b.opposite(taker.side)
+	for quantity.Sign() > 0 && len(*orders) > 0 && limit((*orders)[0].price) {
+		maker := &(*orders)[0]
+		traded := decimal.Min(quantity, maker.quantity)
+		b.fills = append(b.fills, refFill{
+			takerID:    taker.id,
+			takerOwner: taker.owner,
+			makerID:    maker.id,
+			makerOwner: maker.owner,
+			side:       taker.side,
+			price:      maker.price,
+			quantity:   traded,
+		})
+		b.lastPrice = maker.price
+		quantity = quantity.Sub(traded)
+		maker.quantity = maker.quantity.Sub(traded)
+		if maker.quantity.Sign() == 0 {
+			b.remove(orders, 0)
+		}
+	}
+	return quantity
+}
+
+func anyPrice(decimal.Decimal) bool { return true }
+
+func (b *refBook) limit(owner string, side Side, id string, quantity, price decimal.Decimal) error {
+	b.fills = nil
+	if orders, _ := b.find(id); orders != nil {
+		return ErrOrderExists
+	}
+	if quantity.Sign() <= 0 {
+		return ErrInvalidQuantity
+	}
+	if price.Sign() <= 0 {
+		return ErrInvalidPrice
+	}
+
+	taker := refOrder{id: id, owner: owner, side: side, quantity: quantity, price: price}
+	left := b.match(taker, quantity, func(p decimal.Decimal) bool { return !better(side, p, price) })
+	if left.Sign() > 0 {
+		taker.quantity = left
+		b.insert(taker)
+	}
+	return nil
+}
+
+func (b *refBook) market(owner string, side Side, quantity decimal.Decimal) (decimal.Decimal, error) {
+	b.fills = nil
+	if quantity.Sign() <= 0 {
+		return decimal.Zero, ErrInvalidQuantity
+	}
+	return b.match(refOrder{owner: owner, side: side}, quantity, anyPrice), nil
+}
+
+// marketPrice buys for the amount, quantity of every price level is the amount left divided by
+// the level price rounded to the places
+func (b *refBook) marketPrice(owner string, amount decimal.Decimal, places int32) (decimal.Decimal, error) {
+	b.fills = nil
+	if amount.Sign() <= 0 {
+		return decimal.Zero, ErrInvalidPrice
+	}
+
+	taker := refOrder{owner: owner, side: Buy}
+	for amount.Sign() > 0 && len(b.asks) > 0 {
+		price := b.asks[0].price
+		quantity := amount.DivRound(price, places)
+		if quantity.Sign() <= 0 {
+			break
+		}
+		left := b.match(taker, quantity, price.Equal)
+		amount = amount.Sub(quantity.Sub(left).Mul(price))
+	}
+	return amount, nil
+}
+
+func (b *refBook) cancel(id string) bool {
+	orders, i := b.find(id)
+	if orders == nil {
+		return false
+	}
+	b.remove(orders, i)
+	return true
+}
+
+func (b *refBook) cancelOwner(owner string) {
+	for _, orders := range []*[]refOrder{&b.asks, &b.bids} {
+		kept := (*orders)[:0]
+		for _, o := range *orders {
+			if len(owner) == 0 || o.owner != owner {
+				kept = append(kept, o)
+			}
+		}
+		*orders = kept
+	}
+}
+
+// replace amends the order in place if the price is the same and quantity is not increased,
+// otherwise the order is cancelled and placed again as the new limit order
+func (b *refBook) replace(id string, quantity, price decimal.Decimal) error {
+	b.fills = nil
+	orders, i := b.find(id)
+	if orders == nil {
+		return ErrOrderNotExists
+	}
+	if quantity.Sign() <= 0 {
+		return ErrInvalidQuantity
+	}
+	if price.Sign() <= 0 {
+		return ErrInvalidPrice
+	}
+
+	o := (*orders)[i]
+	if price.Equal(o.price) && quantity.LessThanOrEqual(o.quantity) {
+		(*orders)[i].quantity = quantity
+		return nil
+	}
+	b.remove(orders, i)
+	return b.limit(o.owner, o.side, id, quantity, price)
+}
+
+// depth returns price levels of the side from the best price as Depth does
+func (b *refBook) depth(side Side) (levels [][]decimal.Decimal) {
+	for _, o := range *b.side(side) {
+		if n := len(levels); n > 0 && levels[n-1][0].Equal(o.price) {
+			levels[n-1][1] = levels[n-1][1].Add(o.quantity)
+			continue
+		}
+		levels = append(levels, []decimal.Decimal{o.price, o.quantity})
+	}
+	return
+}
+
+func TestReferenceBook(t *testing.T) {
+	b := &refBook{}
+	b.limit("u1", Sell, "s1", decimal.New(2, 0), decimal.New(101, 0))
+	b.limit("u1", Sell, "s2", decimal.New(1, 0), decimal.New(100, 0))
+	b.limit("u2", Sell, "s3", decimal.New(3, 0), decimal.New(100, 0))
+	if b.asks[0].id != "s2" || b.asks[1].id != "s3" || b.asks[2].id != "s1" {
+		t.Fatalf("invalid price-time order: %v", b.asks)
+	}
+
+	if err := b.limit("u3", Buy, "b1", decimal.New(5, 0), decimal.New(100, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.fills) != 2 || b.fills[0].makerID != "s2" || b.fills[1].makerID != "s3" || !b.lastPrice.Equal(decimal.New(100, 0)) {
+		t.Fatalf("invalid fills: %v", b.fills)
+	}
+	if len(b.bids) != 1 || !b.bids[0].quantity.Equal(decimal.New(1, 0)) {
+		t.Fatalf("invalid rest of the limit order: %v", b.bids)
+	}
+
+	left, _ := b.marketPrice("u4", decimal.New(303, 0), 0)
+	if len(b.fills) != 1 || !b.fills[0].quantity.Equal(decimal.New(2, 0)) || !left.Equal(decimal.New(101, 0)) {
+		t.Fatalf("invalid market price order: %v, left %s", b.fills, left)
+	}
+
+	if err := b.limit("u1", Buy, "b1", decimal.New(1, 0), decimal.New(99, 0)); err != ErrOrderExists {
+		t.Fatal("duplicate order ID is accepted")
+	}
+}
@@ -0,0 +1,78 @@
+package orderbook
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// ReplaceOrder amends price and quantity (left to trade) of the resting limit order.
+// The order keeps its time priority if price is not changed and quantity is not increased,
+// otherwise it is cancelled and processed as the new limit order with the same ID and owner.
+// Arguments and results are the same as for ProcessLimitOrder. The order is untouched on error
+func (ob *OrderBook) ReplaceOrder(orderID string, quantity, price decimal.Decimal) (done []*Order, partial *Order, partialQuantityProcessed decimal.Decimal, rollback func(), err error) {
+	ob.fills = nil
+	e, ok := ob.orders[orderID]
+	if !ok {
+		return nil, nil, decimal.Zero, nil, ErrOrderNotExists
+	}
+
+	if quantity.Sign() <= 0 {
+		return nil, nil, decimal.Zero, nil, ErrInvalidQuantity
+	}
+
+	if price.Sign() <= 0 {
+		return nil, nil, decimal.Zero, nil, ErrInvalidPrice
+	}
+
+	order := e.Value.(*Order)
+	if price.Equal(order.Price()) && quantity.LessThanOrEqual(order.Quantity()) {
+		if err = ob.checkPhase(OpCancel); err != nil {
+			return nil, nil, decimal.Zero, nil, err
+		}
+
+		ob.updateOrder(e, NewOrderWithOwner(orderID, order.Owner(), order.Side(), quantity, order.Price(), order.Time()))
+		rollback = func() {
+			if e, ok := ob.orders[orderID]; ok {
+				ob.updateOrder(e, order)
+			}
+		}
+		return nil, nil, decimal.Zero, rollback, nil
+	}
+
+	ops := OpCancel | OpLimitOrder
+	if ob.crosses(order.Side(), price) {
+		ops |= OpMatch
+	}
+	if err = ob.checkPhase(ops); err != nil {
+		return nil, nil, decimal.Zero, nil, err
+	}
+
+	if err = ob.checkBand(order.Side(), quantity, price, decimal.Zero); err != nil {
+		return nil, nil, decimal.Zero, nil, err
+	}
+
+	removed := []removedOrder{{order: order, next: ob.nextID(e)}}
+	ob.cancelOrder(orderID)
+	done, partial, partialQuantityProcessed, rollbackLimit, err := ob.ProcessLimitOrderFor(order.Owner(), order.Side(), orderID, quantity, price)
+	if err != nil {
+		ob.restoreOrders(removed)
+		return nil, nil, decimal.Zero, nil, err
+	}
+
+	rollback = func() {
+		if rollbackLimit != nil {
+			rollbackLimit()
+		}
+		ob.restoreOrders(removed)
+	}
+	return
+}
+
+// crosses reports whether the limit order with given side and price matches the opposite side
+func (ob *OrderBook) crosses(side Side, price decimal.Decimal) bool {
+	if side == Buy {
+		best := ob.asks.MinPriceQueue()
+		return best != nil && price.GreaterThanOrEqual(best.Price())
+	}
+	best := ob.bids.MaxPriceQueue()
+	return best != nil && price.LessThanOrEqual(best.Price())
+}
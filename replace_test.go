@@ -0,0 +1,95 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestReplaceOrderKeepsPriority(t *testing.T) {
+	ob := NewOrderBook()
+	addOwnerDepth(ob)
+
+	_, _, _, rollback, err := ob.ReplaceOrder("mm-b90", decimal.New(5, -1), decimal.New(90, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rank, _, _ := ob.QueuePosition("mm-b90"); rank != 1 {
+		t.Fatal("priority is lost")
+	}
+	if o := ob.Order("mm-b90"); !o.Quantity().Equal(decimal.New(5, -1)) || o.Owner() != "mm" {
+		t.Fatalf("invalid order: %s", o)
+	}
+	if err := ob.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	rollback()
+	if o := ob.Order("mm-b90"); !o.Quantity().Equal(decimal.New(1, 0)) {
+		t.Fatalf("rollback is not restored order: %s", o)
+	}
+}
+
+func TestReplaceOrderLosesPriority(t *testing.T) {
+	ob := NewOrderBook()
+	addOwnerDepth(ob)
+
+	_, _, _, rollback, err := ob.ReplaceOrder("mm-b90", decimal.New(2, 0), decimal.New(90, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rank, _, _ := ob.QueuePosition("mm-b90"); rank != 2 {
+		t.Fatal("increased order keeps priority")
+	}
+	rollback()
+	if rank, _, _ := ob.QueuePosition("mm-b90"); rank != 1 || !ob.Order("mm-b90").Quantity().Equal(decimal.New(1, 0)) {
+		t.Fatal("rollback is not restored priority")
+	}
+
+	done, partial, processed, rollback, err := ob.ReplaceOrder("mm-b80", decimal.New(2, 0), decimal.New(105, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 1 || done[0].ID() != "alice-s100" || partial == nil || partial.ID() != "mm-b80" ||
+		!processed.Equal(decimal.New(1, 0)) || len(ob.Fills()) != 1 {
+		t.Fatalf("invalid replace result: %v %v %s", done, partial, processed)
+	}
+	if o := ob.Order("mm-b80"); !o.Price().Equal(decimal.New(105, 0)) || o.Owner() != "mm" {
+		t.Fatalf("invalid order: %s", o)
+	}
+	if err := ob.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	rollback()
+	if ob.Order("alice-s100") == nil || !ob.Order("mm-b80").Price().Equal(decimal.New(80, 0)) {
+		t.Fatal("rollback is not restored orders")
+	}
+	if err := ob.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplaceOrderErrors(t *testing.T) {
+	ob := NewOrderBook()
+	addOwnerDepth(ob)
+
+	if _, _, _, _, err := ob.ReplaceOrder("none", decimal.New(1, 0), decimal.New(1, 0)); err != ErrOrderNotExists {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if _, _, _, _, err := ob.ReplaceOrder("mm-b90", decimal.Zero, decimal.New(90, 0)); err != ErrInvalidQuantity {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if _, _, _, _, err := ob.ReplaceOrder("mm-b90", decimal.New(1, 0), decimal.Zero); err != ErrInvalidPrice {
+		t.Fatalf("invalid error: %v", err)
+	}
+
+	ob.SetPhase(PreOpen)
+	if _, _, _, _, err := ob.ReplaceOrder("mm-b90", decimal.New(1, 0), decimal.New(100, 0)); err != ErrNotAllowed {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if rank, _, _ := ob.QueuePosition("mm-b90"); rank != 1 {
+		t.Fatal("rejected replace changed the order")
+	}
+}
@@ -0,0 +1,127 @@
+package replication
+
+import (
+	"sync"
+	"time"
+
+	"github.com/centny/orderbook"
+)
+
+// Follower applies commands replicated from the primary, it is safe for concurrent use
+type Follower struct {
+	mu       sync.Mutex
+	book     *orderbook.OrderBook
+	seq      uint64
+	last     time.Time // time of the last command, the book clock
+	promoted bool
+}
+
+// NewFollower creates Follower which owns the book, the book should have the same
+// configuration as the primary one
+func NewFollower(book *orderbook.OrderBook) *Follower {
+	f := &Follower{book: book}
+	book.SetClock(func() time.Time { return f.last })
+	return f
+}
+
+// Seq returns sequence number of the last applied command
+func (f *Follower) Seq() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seq
+}
+
+// View calls fn with the book under the follower lock, fn must not change the book
+func (f *Follower) View(fn func(book *orderbook.OrderBook)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f.book)
+}
+
+// Send implements Stream interface: the command is applied if it is the next one,
+// already applied commands are ignored, ErrGap is returned if commands are missed
+func (f *Follower) Send(cmd *orderbook.Command) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.apply(cmd)
+}
+
+func (f *Follower) apply(cmd *orderbook.Command) error {
+	if f.promoted {
+		return ErrPromoted
+	}
+	if cmd.Seq <= f.seq {
+		return nil
+	}
+	if cmd.Seq != f.seq+1 {
+		return ErrGap
+	}
+
+	f.book.Apply(cmd)
+	f.seq = cmd.Seq
+	f.last = cmd.Time
+	return nil
+}
+
+// Restore replaces state of the book with the snapshot if it is newer than the follower state
+func (f *Follower) Restore(s *Snapshot) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.promoted {
+		return ErrPromoted
+	}
+	if s.Seq <= f.seq {
+		return nil
+	}
+	if err := s.restore(f.book); err != nil {
+		return err
+	}
+	f.seq = s.Seq
+	f.last = s.Time
+	return nil
+}
+
+// CatchUp applies commands missed by the follower, the latest snapshot is restored
+// if the follower is behind it
+func (f *Follower) CatchUp(src Source) error {
+	tail, err := src.Since(f.Seq())
+	if err == ErrCompacted {
+		var snapshot *Snapshot
+		if snapshot, err = src.Snapshot(); err != nil {
+			return err
+		}
+		if err = f.Restore(snapshot); err != nil {
+			return err
+		}
+		tail, err = src.Since(f.Seq())
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range tail {
+		if err := f.Send(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Promote stops the follower and returns Primary which continues the sequence of
+// the follower with its book
+func (f *Follower) Promote() (*Primary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.promoted {
+		return nil, ErrPromoted
+	}
+
+	p, err := newPrimary(f.book, f.seq, f.last)
+	if err != nil {
+		return nil, err
+	}
+	f.promoted = true
+	return p, nil
+}
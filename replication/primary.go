@@ -0,0 +1,163 @@
+package replication
+
+import (
+	"sync"
+	"time"
+
+	"github.com/centny/orderbook"
+)
+
+// Primary is the leader of the replication, it is safe for concurrent use
+type Primary struct {
+	mu       sync.Mutex
+	book     *orderbook.OrderBook
+	seq      uint64
+	log      []*orderbook.Command // commands after the snapshot
+	snapshot *Snapshot
+	streams  []Stream
+	clock    func() time.Time // time of the commands without time
+	last     time.Time        // time of the last command, the book clock
+}
+
+// NewPrimary creates Primary which owns the book, the book should not be used directly after that
+func NewPrimary(book *orderbook.OrderBook) (*Primary, error) {
+	return newPrimary(book, 0, time.Time{})
+}
+
+func newPrimary(book *orderbook.OrderBook, seq uint64, last time.Time) (*Primary, error) {
+	p := &Primary{
+		book:  book,
+		seq:   seq,
+		clock: func() time.Time { return time.Now().UTC() },
+		last:  last,
+	}
+	book.SetClock(func() time.Time { return p.last })
+
+	snapshot, err := takeSnapshot(seq, last, book)
+	if err != nil {
+		return nil, err
+	}
+	p.snapshot = snapshot
+	return p, nil
+}
+
+// Seq returns sequence number of the last submitted command
+func (p *Primary) Seq() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.seq
+}
+
+// View calls fn with the book under the primary lock, fn must not change the book
+func (p *Primary) View(fn func(book *orderbook.OrderBook)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn(p.book)
+}
+
+// Submit assigns next sequence number (and current time if the command has no time) to the
+// copy of the command, applies it to the book and sends it to the subscribed streams.
+// Result has no rollback, because rollback is not replicated
+func (p *Primary) Submit(cmd *orderbook.Command) *orderbook.Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c := *cmd
+	c.Seq = p.seq + 1
+	if c.Time.IsZero() {
+		c.Time = p.clock()
+	}
+
+	result := p.book.Apply(&c)
+	result.Rollback = nil
+	p.seq = c.Seq
+	p.last = c.Time
+	p.log = append(p.log, &c)
+
+	streams := p.streams[:0]
+	for _, s := range p.streams {
+		if err := s.Send(&c); err == nil {
+			streams = append(streams, s)
+		}
+	}
+	for i := len(streams); i < len(p.streams); i++ {
+		p.streams[i] = nil
+	}
+	p.streams = streams
+	return result
+}
+
+// Checkpoint takes snapshot of the book and drops the log before it
+func (p *Primary) Checkpoint() (*Snapshot, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snapshot, err := takeSnapshot(p.seq, p.last, p.book)
+	if err != nil {
+		return nil, err
+	}
+	p.snapshot = snapshot
+	p.log = nil
+	return snapshot, nil
+}
+
+// Snapshot implements Source interface
+func (p *Primary) Snapshot() (*Snapshot, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.snapshot, nil
+}
+
+// Since implements Source interface
+func (p *Primary) Since(seq uint64) ([]*orderbook.Command, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.since(seq)
+}
+
+func (p *Primary) since(seq uint64) ([]*orderbook.Command, error) {
+	if seq < p.snapshot.Seq {
+		return nil, ErrCompacted
+	}
+	if seq > p.seq {
+		return nil, ErrAhead
+	}
+
+	tail := p.log[seq-p.snapshot.Seq:]
+	commands := make([]*orderbook.Command, len(tail))
+	copy(commands, tail)
+	return commands, nil
+}
+
+// Subscribe sends commands after the from sequence number to the stream and then every
+// submitted command. Stream is unsubscribed on the first Send error
+func (p *Primary) Subscribe(s Stream, from uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tail, err := p.since(from)
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range tail {
+		if err := s.Send(cmd); err != nil {
+			return err
+		}
+	}
+	p.streams = append(p.streams, s)
+	return nil
+}
+
+// Unsubscribe stops sending commands to the stream
+func (p *Primary) Unsubscribe(s Stream) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, stream := range p.streams {
+		if stream == s {
+			p.streams = append(p.streams[:i], p.streams[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package replication implements leader/follower replication of the OrderBook.
+//
+// Primary assigns sequence numbers to the commands, applies them to its book and streams
+// them to followers. Followers apply the commands in the same order with the same time, so
+// their books stay identical to the primary one (see OrderBook.StateHash). A follower catches
+// up from the latest primary snapshot plus the log tail and can be promoted to primary.
+//
+// The book clock of the primary and followers is the time of the last applied command, so
+// scheduled phase transitions (e.g. the end of the band halt cool-down) are applied by the
+// command times on every replica, never by the wall clock of the replica.
+//
+// Books of the primary and followers should be created with the same configuration
+// (price bands, phase operations), the configuration is not replicated.
+package replication
+
+import (
+	"errors"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// Replication errors
+var (
+	ErrGap          = errors.New("replication: command sequence gap")
+	ErrCompacted    = errors.New("replication: log tail is compacted, catch up from snapshot")
+	ErrPromoted     = errors.New("replication: follower is promoted")
+	ErrSlowFollower = errors.New("replication: follower is too slow")
+	ErrAhead        = errors.New("replication: follower is ahead of primary")
+)
+
+// Snapshot stores state of the book after the command with sequence number Seq
+type Snapshot struct {
+	Seq       uint64                      `json:"seq"`
+	Time      time.Time                   `json:"time"` // time of the command, the book clock
+	Book      []byte                      `json:"book"` // OrderBook binary snapshot
+	Phase     orderbook.Phase             `json:"phase"`
+	Schedule  []orderbook.PhaseTransition `json:"schedule,omitempty"`
+	LastPrice decimal.Decimal             `json:"lastPrice"`
+}
+
+func takeSnapshot(seq uint64, at time.Time, book *orderbook.OrderBook) (*Snapshot, error) {
+	data, err := book.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Seq:       seq,
+		Time:      at,
+		Book:      data,
+		Phase:     book.Phase(),
+		Schedule:  book.Schedule(),
+		LastPrice: book.LastPrice(),
+	}, nil
+}
+
+func (s *Snapshot) restore(book *orderbook.OrderBook) error {
+	if err := book.UnmarshalBinary(s.Book); err != nil {
+		return err
+	}
+	book.SetPhase(s.Phase)
+	book.SetSchedule(s.Schedule)
+	book.SetLastPrice(s.LastPrice)
+	return nil
+}
+
+// Source provides data to catch up a follower
+type Source interface {
+	// Snapshot returns the latest snapshot
+	Snapshot() (*Snapshot, error)
+	// Since returns commands with sequence number greater than seq
+	Since(seq uint64) ([]*orderbook.Command, error)
+}
+
+// Stream receives sequenced commands from the primary
+type Stream interface {
+	Send(cmd *orderbook.Command) error
+}
@@ -0,0 +1,248 @@
+package replication
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+func randomCommand(rnd *rand.Rand, i int) *orderbook.Command {
+	side := orderbook.Side(rnd.Intn(2))
+	switch n := rnd.Intn(10); {
+	case n < 6:
+		return &orderbook.Command{
+			Type:     orderbook.CommandLimit,
+			ID:       fmt.Sprint("o-", i),
+			Owner:    fmt.Sprint("acc-", rnd.Intn(3)),
+			Side:     side,
+			Quantity: decimal.New(int64(rnd.Intn(10)+1), 0),
+			Price:    decimal.New(int64(95+rnd.Intn(10)), 0),
+		}
+	case n < 8:
+		return &orderbook.Command{Type: orderbook.CommandCancel, ID: fmt.Sprint("o-", rnd.Intn(i+1))}
+	default:
+		return &orderbook.Command{Type: orderbook.CommandMarket, Side: side, Quantity: decimal.New(int64(rnd.Intn(5)+1), 0)}
+	}
+}
+
+func stateHash(view func(func(*orderbook.OrderBook))) (hash [32]byte) {
+	view(func(book *orderbook.OrderBook) { hash = book.StateHash() })
+	return
+}
+
+func TestInProcessReplication(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	primary, err := NewPrimary(orderbook.NewOrderBook())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	follower := NewFollower(orderbook.NewOrderBook())
+	if err := primary.Subscribe(follower, follower.Seq()); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 500; i++ {
+		if result := primary.Submit(randomCommand(rnd, i)); result.Rollback != nil {
+			t.Fatal("primary result has rollback")
+		}
+		if i == 200 {
+			primary.Submit(&orderbook.Command{Type: orderbook.CommandPhase, Phase: orderbook.Halted})
+			primary.Submit(&orderbook.Command{Type: orderbook.CommandPhase, Phase: orderbook.Open})
+		}
+	}
+
+	if follower.Seq() != primary.Seq() || stateHash(follower.View) != stateHash(primary.View) {
+		t.Fatal("follower differs from primary")
+	}
+
+	// late follower catches up from snapshot and log tail
+	if _, err := primary.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 500; i < 600; i++ {
+		primary.Submit(randomCommand(rnd, i))
+	}
+
+	late := NewFollower(orderbook.NewOrderBook())
+	if err := primary.Subscribe(late, late.Seq()); err != ErrCompacted {
+		t.Fatal("compacted log tail is streamed")
+	}
+	if err := late.CatchUp(primary); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.Subscribe(late, late.Seq()); err != nil {
+		t.Fatal(err)
+	}
+
+	primary.Submit(randomCommand(rnd, 600))
+	if late.Seq() != primary.Seq() || stateHash(late.View) != stateHash(primary.View) {
+		t.Fatal("late follower differs from primary")
+	}
+
+	// failover
+	promoted, err := follower.Promote()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := follower.Send(&orderbook.Command{Seq: follower.Seq() + 1}); err != ErrPromoted {
+		t.Fatal("promoted follower applies commands")
+	}
+
+	primary.Unsubscribe(late)
+	if err := promoted.Subscribe(late, late.Seq()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 601; i < 700; i++ {
+		promoted.Submit(randomCommand(rnd, i))
+	}
+	if late.Seq() != promoted.Seq() || stateHash(late.View) != stateHash(promoted.View) {
+		t.Fatal("follower differs from promoted primary")
+	}
+}
+
+func TestFollowerGap(t *testing.T) {
+	f := NewFollower(orderbook.NewOrderBook())
+	cmd := &orderbook.Command{Seq: 1, Type: orderbook.CommandLimit, ID: "a", Quantity: decimal.New(1, 0), Price: decimal.New(1, 0)}
+	if err := f.Send(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Send(cmd); err != nil || f.Seq() != 1 {
+		t.Fatal("duplicated command is not ignored")
+	}
+	if err := f.Send(&orderbook.Command{Seq: 3}); err != ErrGap {
+		t.Fatal("gap is not detected")
+	}
+}
+
+func TestFollowerAhead(t *testing.T) {
+	primary, _ := NewPrimary(orderbook.NewOrderBook())
+	primary.Submit(&orderbook.Command{Type: orderbook.CommandLimit, ID: "a", Quantity: decimal.New(1, 0), Price: decimal.New(1, 0)})
+
+	if _, err := primary.Since(2); err != ErrAhead {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if err := primary.Subscribe(NewFollower(orderbook.NewOrderBook()), 5); err != ErrAhead {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if tail, err := primary.Since(1); err != nil || len(tail) != 0 {
+		t.Fatalf("invalid tail of the last command: %v, %v", tail, err)
+	}
+
+	// connection of the follower ahead is closed
+	client, server := net.Pipe()
+	go primary.serveConn(server)
+	go client.Write([]byte(`{"seq":10}` + "\n"))
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := client.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("connection is not closed: %v", err)
+	}
+}
+
+func TestReplicatedPhaseSchedule(t *testing.T) {
+	band := &orderbook.PriceBand{Static: decimal.New(1, -1), Action: orderbook.BandHalt, CoolDown: time.Minute}
+	book := func() *orderbook.OrderBook {
+		ob := orderbook.NewOrderBook()
+		ob.SetPriceBand(band)
+		ob.SetReferencePrice(decimal.New(100, 0))
+		return ob
+	}
+	at := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	primary, _ := NewPrimary(book())
+	primary.Submit(&orderbook.Command{Time: at, Type: orderbook.CommandLimit, ID: "s", Side: orderbook.Sell, Quantity: decimal.New(1, 0), Price: decimal.New(120, 0)})
+	// trade outside of the band halts the book for the cool-down
+	primary.Submit(&orderbook.Command{Time: at, Type: orderbook.CommandMarket, Side: orderbook.Buy, Quantity: decimal.New(1, 0)})
+	primary.Checkpoint()
+
+	follower := NewFollower(book())
+	if err := follower.CatchUp(primary); err != nil {
+		t.Fatal(err)
+	}
+
+	// the wall clock is far after the cool-down, the command clock is not
+	var halted bool
+	follower.View(func(ob *orderbook.OrderBook) { halted = ob.Halted() && len(ob.Schedule()) == 1 })
+	if !halted {
+		t.Fatal("follower is not halted until the end of the cool-down")
+	}
+
+	primary.Submit(&orderbook.Command{Time: at.Add(2 * time.Minute), Type: orderbook.CommandCancel, ID: "none"})
+	if err := follower.CatchUp(primary); err != nil {
+		t.Fatal(err)
+	}
+	follower.View(func(ob *orderbook.OrderBook) { halted = ob.Halted() })
+	if halted || stateHash(follower.View) != stateHash(primary.View) {
+		t.Fatal("follower is not reopened with the primary")
+	}
+}
+
+func waitSeq(t *testing.T, f *Follower, seq uint64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for f.Seq() != seq {
+		if time.Now().After(deadline) {
+			t.Fatalf("follower seq is %d, want %d", f.Seq(), seq)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLoopbackReplication(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	primary, _ := NewPrimary(orderbook.NewOrderBook())
+	for i := 0; i < 100; i++ {
+		primary.Submit(randomCommand(rnd, i))
+	}
+	primary.Checkpoint()
+	for i := 100; i < 150; i++ {
+		primary.Submit(randomCommand(rnd, i))
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go Serve(l, primary)
+
+	follower := NewFollower(orderbook.NewOrderBook())
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- Follow(conn, follower) }()
+
+	waitSeq(t, follower, primary.Seq())
+	for i := 150; i < 400; i++ {
+		primary.Submit(randomCommand(rnd, i))
+	}
+	waitSeq(t, follower, primary.Seq())
+
+	if stateHash(follower.View) != stateHash(primary.View) {
+		t.Fatal("follower differs from primary")
+	}
+
+	// reconnect continues from the follower sequence
+	conn.Close()
+	<-done
+	for i := 400; i < 450; i++ {
+		primary.Submit(randomCommand(rnd, i))
+	}
+
+	conn, _ = net.Dial("tcp", l.Addr().String())
+	defer conn.Close()
+	go Follow(conn, follower)
+	waitSeq(t, follower, primary.Seq())
+
+	if stateHash(follower.View) != stateHash(primary.View) {
+		t.Fatal("reconnected follower differs from primary")
+	}
+}
@@ -0,0 +1,149 @@
+package replication
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"sync"
+
+	"github.com/centny/orderbook"
+)
+
+// Stream protocol is JSON lines: follower sends hello with its sequence number,
+// primary replies with snapshot (if follower is behind it) and log tail, then streams
+// every submitted command
+type hello struct {
+	Seq uint64 `json:"seq"`
+}
+
+type message struct {
+	Snapshot *Snapshot          `json:"snapshot,omitempty"`
+	Command  *orderbook.Command `json:"command,omitempty"`
+}
+
+// StreamBuffer is amount of commands buffered for network follower, follower which
+// is behind more is disconnected
+var StreamBuffer = 4096
+
+type connStream struct {
+	commands chan *orderbook.Command
+	once     sync.Once
+}
+
+func (cs *connStream) Send(cmd *orderbook.Command) error {
+	select {
+	case cs.commands <- cmd:
+		return nil
+	default:
+		cs.close()
+		return ErrSlowFollower
+	}
+}
+
+func (cs *connStream) close() {
+	cs.once.Do(func() { close(cs.commands) })
+}
+
+// Serve accepts follower connections on the listener and streams the primary commands to them.
+// Serve returns when the listener is closed
+func Serve(l net.Listener, p *Primary) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go p.serveConn(conn)
+	}
+}
+
+func (p *Primary) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	var h hello
+	if err := json.NewDecoder(conn).Decode(&h); err != nil {
+		return
+	}
+
+	// catch-up data is taken and the stream is subscribed atomically, so nothing is missed
+	cs := &connStream{commands: make(chan *orderbook.Command, StreamBuffer)}
+	p.mu.Lock()
+	var snapshot *Snapshot
+	tail, err := p.since(h.Seq)
+	if err == ErrCompacted {
+		snapshot = p.snapshot
+		tail, err = p.since(snapshot.Seq)
+	}
+	if err != nil {
+		// the follower is ahead (e.g. of the promoted primary), its book can not be caught up
+		p.mu.Unlock()
+		return
+	}
+	p.streams = append(p.streams, cs)
+	p.mu.Unlock()
+
+	defer func() {
+		p.Unsubscribe(cs)
+		cs.close()
+	}()
+
+	w := bufio.NewWriter(conn)
+	enc := json.NewEncoder(w)
+	if snapshot != nil {
+		if err := enc.Encode(&message{Snapshot: snapshot}); err != nil {
+			return
+		}
+	}
+	for _, cmd := range tail {
+		if err := enc.Encode(&message{Command: cmd}); err != nil {
+			return
+		}
+	}
+
+	for {
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		cmd, ok := <-cs.commands
+		if !ok {
+			return
+		}
+		if err := enc.Encode(&message{Command: cmd}); err != nil {
+			return
+		}
+
+		// write all pending commands before flushing
+		for n := len(cs.commands); n > 0; n-- {
+			if err := enc.Encode(&message{Command: <-cs.commands}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Follow replicates commands of the primary served on the other end of the connection
+// to the follower. Follow returns when the connection is closed or replication fails
+func Follow(conn net.Conn, f *Follower) error {
+	if err := json.NewEncoder(conn).Encode(&hello{Seq: f.Seq()}); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var msg message
+		if err := dec.Decode(&msg); err != nil {
+			return err
+		}
+
+		if msg.Snapshot != nil {
+			if err := f.Restore(msg.Snapshot); err != nil {
+				return err
+			}
+		}
+		if msg.Command != nil {
+			if err := f.Send(msg.Command); err != nil {
+				return err
+			}
+		}
+	}
+}
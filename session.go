@@ -0,0 +1,199 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Phase is the trading phase of the OrderBook
+type Phase int
+
+// Open is the default phase of the new OrderBook
+const (
+	Open Phase = iota
+	PreOpen
+	Halted
+	Closed
+)
+
+// String implements fmt.Stringer interface
+func (p Phase) String() string {
+	switch p {
+	case PreOpen:
+		return "pre-open"
+	case Halted:
+		return "halted"
+	case Closed:
+		return "closed"
+	}
+	return "open"
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (p Phase) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + p.String() + `"`), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (p *Phase) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case `"open"`:
+		*p = Open
+	case `"pre-open"`:
+		*p = PreOpen
+	case `"halted"`:
+		*p = Halted
+	case `"closed"`:
+		*p = Closed
+	default:
+		return &json.UnsupportedValueError{
+			Value: reflect.New(reflect.TypeOf(data)),
+			Str:   string(data),
+		}
+	}
+
+	return nil
+}
+
+// Operation is a set of operations allowed in the trading phase
+type Operation int
+
+// Operations checked by the OrderBook, OpMatch allows orders to trade with resting orders
+const (
+	OpLimitOrder Operation = 1 << iota
+	OpMarketOrder
+	OpCancel
+	OpMatch
+)
+
+// DefaultOperations returns operations allowed in the phase by default:
+//
+//	open     - everything
+//	pre-open - limit orders which don't cross the book and cancels
+//	halted   - cancels only
+//	closed   - nothing
+func DefaultOperations(p Phase) Operation {
+	switch p {
+	case PreOpen:
+		return OpLimitOrder | OpCancel
+	case Halted:
+		return OpCancel
+	case Closed:
+		return 0
+	}
+	return OpLimitOrder | OpMarketOrder | OpCancel | OpMatch
+}
+
+// PhaseTransition stores scheduled change of the trading phase. CoolDown marks the end of
+// the price band halt, it is dropped on any other phase change
+type PhaseTransition struct {
+	At       time.Time `json:"at"`
+	Phase    Phase     `json:"phase"`
+	CoolDown bool      `json:"coolDown,omitempty"`
+}
+
+// SetPhaseOperations overrides operations allowed in the phase
+func (ob *OrderBook) SetPhaseOperations(p Phase, ops Operation) {
+	if ob.operations == nil {
+		ob.operations = map[Phase]Operation{}
+	}
+	ob.operations[p] = ops
+}
+
+// Allowed reports whether all given operations are allowed in the current phase
+func (ob *OrderBook) Allowed(ops Operation) bool {
+	allowed, ok := ob.operations[ob.Phase()]
+	if !ok {
+		allowed = DefaultOperations(ob.phase)
+	}
+	return allowed&ops == ops
+}
+
+// Phase returns current trading phase, due scheduled transitions are applied
+// according to the book clock
+func (ob *OrderBook) Phase() Phase {
+	now := ob.now()
+	for len(ob.schedule) > 0 && !ob.schedule[0].At.After(now) {
+		t := ob.schedule[0]
+		ob.schedule = ob.schedule[1:]
+		if !t.CoolDown {
+			ob.dropCoolDown()
+		}
+		ob.phase = t.Phase
+	}
+	return ob.phase
+}
+
+// SetPhase switches trading phase immediately, scheduled transitions are kept except
+// the end of the halt cool-down
+func (ob *OrderBook) SetPhase(p Phase) {
+	ob.Phase()
+	ob.dropCoolDown()
+	ob.phase = p
+}
+
+// SchedulePhase schedules switching to the phase at the given time of the book clock
+func (ob *OrderBook) SchedulePhase(at time.Time, p Phase) {
+	ob.schedulePhase(PhaseTransition{At: at, Phase: p})
+}
+
+func (ob *OrderBook) schedulePhase(t PhaseTransition) {
+	i := sort.Search(len(ob.schedule), func(i int) bool {
+		return ob.schedule[i].At.After(t.At)
+	})
+	ob.schedule = append(ob.schedule, PhaseTransition{})
+	copy(ob.schedule[i+1:], ob.schedule[i:])
+	ob.schedule[i] = t
+}
+
+// SetSchedule replaces pending phase transitions (e.g. when the book is restored from snapshot)
+func (ob *OrderBook) SetSchedule(schedule []PhaseTransition) {
+	ob.schedule = nil
+	for _, t := range schedule {
+		ob.schedulePhase(t)
+	}
+}
+
+// dropCoolDown removes the end of the halt cool-down from the schedule
+func (ob *OrderBook) dropCoolDown() {
+	schedule := ob.schedule[:0]
+	for _, t := range ob.schedule {
+		if !t.CoolDown {
+			schedule = append(schedule, t)
+		}
+	}
+	ob.schedule = schedule
+}
+
+// Schedule returns pending phase transitions in time order
+func (ob *OrderBook) Schedule() []PhaseTransition {
+	ob.Phase()
+	schedule := make([]PhaseTransition, len(ob.schedule))
+	copy(schedule, ob.schedule)
+	return schedule
+}
+
+// ClearSchedule removes all pending phase transitions
+func (ob *OrderBook) ClearSchedule() {
+	ob.schedule = nil
+}
+
+// halt switches the book to Halted phase and schedules Open phase after the cool-down if it is not zero
+func (ob *OrderBook) halt(coolDown time.Duration) {
+	ob.SetPhase(Halted)
+	if coolDown > 0 {
+		ob.schedulePhase(PhaseTransition{At: ob.now().Add(coolDown), Phase: Open, CoolDown: true})
+	}
+}
+
+func (ob *OrderBook) checkPhase(ops Operation) error {
+	if ob.Allowed(ops) {
+		return nil
+	}
+	if ob.phase == Halted {
+		return ErrHalted
+	}
+	return ErrNotAllowed
+}
@@ -0,0 +1,171 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPhaseOperations(t *testing.T) {
+	ob := NewOrderBook()
+	if ob.Phase() != Open {
+		t.Fatal("new book is not open")
+	}
+	addDepth(ob, "", decimal.New(2, 0))
+
+	ob.SetPhase(PreOpen)
+	if _, _, _, _, _, err := ob.ProcessMarketQuantityOrder(Buy, decimal.New(1, 0)); err != ErrNotAllowed {
+		t.Fatal("market order is processed in pre-open")
+	}
+
+	if _, _, _, _, err := ob.ProcessLimitOrder(Buy, "b-100", decimal.New(1, 0), decimal.New(100, 0)); err != ErrNotAllowed {
+		t.Fatal("crossing limit order is processed in pre-open")
+	}
+
+	if _, _, _, _, err := ob.ProcessLimitOrder(Buy, "b-95", decimal.New(1, 0), decimal.New(95, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	ob.SetPhase(Halted)
+	if _, _, _, _, err := ob.ProcessLimitOrder(Buy, "b-96", decimal.New(1, 0), decimal.New(96, 0)); err != ErrHalted {
+		t.Fatal("limit order is processed in halted book")
+	}
+
+	if o, _ := ob.CancelOrder("b-95"); o == nil {
+		t.Fatal("can't cancel order in halted book")
+	}
+
+	ob.SetPhase(Closed)
+	if o, _ := ob.CancelOrder("buy-90"); o != nil || ob.Order("buy-90") == nil {
+		t.Fatal("order is cancelled in closed book")
+	}
+
+	if orders, _ := ob.CancelAll(); len(orders) != 0 {
+		t.Fatal("orders are cancelled in closed book")
+	}
+
+	ob.SetPhaseOperations(Closed, OpCancel)
+	if !ob.Allowed(OpCancel) || ob.Allowed(OpCancel|OpLimitOrder) {
+		t.Fatal("operations are not overridden")
+	}
+
+	if orders, _ := ob.CancelAll(); len(orders) != 10 {
+		t.Fatal("orders are not cancelled")
+	}
+}
+
+func TestPhaseSchedule(t *testing.T) {
+	now := time.Date(2019, 3, 1, 8, 0, 0, 0, time.UTC)
+	ob := NewOrderBook()
+	ob.SetClock(func() time.Time { return now })
+	ob.SetPhase(Closed)
+
+	ob.SchedulePhase(now.Add(2*time.Hour), Open)
+	ob.SchedulePhase(now.Add(8*time.Hour), Closed)
+	ob.SchedulePhase(now.Add(time.Hour), PreOpen)
+	if s := ob.Schedule(); len(s) != 3 || s[0].Phase != PreOpen || s[2].Phase != Closed {
+		t.Fatalf("invalid schedule: %v", s)
+	}
+
+	now = now.Add(time.Hour)
+	if ob.Phase() != PreOpen {
+		t.Fatalf("invalid phase: %s", ob.Phase())
+	}
+
+	now = now.Add(3 * time.Hour)
+	if ob.Phase() != Open || len(ob.Schedule()) != 1 {
+		t.Fatalf("invalid phase: %s", ob.Phase())
+	}
+
+	ob.ClearSchedule()
+	now = now.Add(24 * time.Hour)
+	if ob.Phase() != Open {
+		t.Fatal("cleared schedule is applied")
+	}
+}
+
+func TestPhaseJSON(t *testing.T) {
+	data, _ := json.Marshal([]Phase{Open, PreOpen, Halted, Closed})
+	if string(data) != `["open","pre-open","halted","closed"]` {
+		t.Fatalf("invalid json: %s", data)
+	}
+
+	var phases []Phase
+	if err := json.Unmarshal(data, &phases); err != nil || len(phases) != 4 || phases[1] != PreOpen {
+		t.Fatal("can't unmarshal phases", err)
+	}
+
+	if err := json.Unmarshal([]byte(`["fake"]`), &phases); err == nil {
+		t.Fatal("can unmarshal unsupported value")
+	}
+}
+
+func TestHaltCoolDown(t *testing.T) {
+	now := time.Date(2019, 3, 1, 8, 0, 0, 0, time.UTC)
+	ob := NewOrderBook()
+	ob.SetClock(func() time.Time { return now })
+
+	ob.halt(time.Minute)
+	if s := ob.Schedule(); len(s) != 1 || !s[0].CoolDown {
+		t.Fatalf("invalid schedule: %v", s)
+	}
+	ob.Resume()
+	ob.SetPhase(Closed)
+	now = now.Add(time.Minute)
+	if ob.Phase() != Closed || len(ob.Schedule()) != 0 {
+		t.Fatal("cool-down is applied after resume")
+	}
+
+	// scheduled close during the halt cancels the cool-down
+	ob.SetPhase(Open)
+	ob.SchedulePhase(now.Add(30*time.Second), Closed)
+	ob.halt(time.Minute)
+	now = now.Add(2 * time.Minute)
+	if ob.Phase() != Closed {
+		t.Fatalf("invalid phase: %s", ob.Phase())
+	}
+
+	// manual phase change cancels the cool-down, other transitions are kept
+	ob.SetPhase(Open)
+	ob.SchedulePhase(now.Add(time.Hour), Closed)
+	ob.halt(time.Minute)
+	ob.SetPhase(PreOpen)
+	if s := ob.Schedule(); len(s) != 1 || s[0].Phase != Closed || s[0].CoolDown {
+		t.Fatalf("invalid schedule: %v", s)
+	}
+	now = now.Add(time.Minute)
+	if ob.Phase() != PreOpen {
+		t.Fatalf("invalid phase: %s", ob.Phase())
+	}
+
+	ob.SetSchedule([]PhaseTransition{{At: now.Add(time.Minute), Phase: Open, CoolDown: true}})
+	ob.SetPhase(Halted)
+	if len(ob.Schedule()) != 0 {
+		t.Fatal("restored cool-down is not dropped")
+	}
+}
+
+func TestCancelPhaseErrors(t *testing.T) {
+	ob := NewOrderBook()
+	addDepth(ob, "", decimal.New(2, 0))
+
+	ob.SetPhase(Halted)
+	ob.SetPhaseOperations(Halted, 0)
+	if r := ob.Apply(&Command{Type: CommandCancel, ID: "buy-90"}); r.Err != ErrHalted {
+		t.Fatalf("invalid error: %v", r.Err)
+	}
+	if r := ob.Apply(&Command{Type: CommandCancelAll}); r.Err != ErrHalted || ob.Order("buy-90") == nil {
+		t.Fatalf("invalid error: %v", r.Err)
+	}
+
+	ob.SetPhase(Closed)
+	if r := ob.Apply(&Command{Type: CommandCancel, ID: "buy-90"}); r.Err != ErrNotAllowed {
+		t.Fatalf("invalid error: %v", r.Err)
+	}
+	ob.SetPhase(Open)
+	if r := ob.Apply(&Command{Type: CommandCancel, ID: "none"}); r.Err != ErrOrderNotExists {
+		t.Fatalf("invalid error: %v", r.Err)
+	}
+}
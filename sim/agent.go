@@ -0,0 +1,135 @@
+package sim
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// Agent is the trading strategy. Callbacks are invoked synchronously by the simulator, orders
+// submitted through the context reach the book after the latency of the simulator
+type Agent interface {
+	// OnBook is called after every command applied to the book
+	OnBook(ctx *Context)
+	// OnFill is called for every fill of the agent orders before OnBook
+	OnFill(ctx *Context, fill *orderbook.Fill)
+}
+
+// account of the agent
+type account struct {
+	owner string
+	agent Agent
+
+	nextID  int
+	ids     []string // submitted limit orders, filled and cancelled are dropped lazily
+	pending int      // submitted commands not applied yet
+
+	orders    int
+	rejects   int
+	fills     int
+	submitted decimal.Decimal
+	filled    decimal.Decimal
+	inventory decimal.Decimal
+	cash      decimal.Decimal
+	fees      decimal.Decimal
+	maxAbs    decimal.Decimal // max absolute inventory
+}
+
+// fill updates the account with the fill in which the agent traded on the side
+func (a *account) fill(f *orderbook.Fill, side orderbook.Side, fee decimal.Decimal) {
+	a.fills++
+	a.filled = a.filled.Add(f.Quantity)
+	if side == orderbook.Buy {
+		a.inventory = a.inventory.Add(f.Quantity)
+		a.cash = a.cash.Sub(f.Notional())
+	} else {
+		a.inventory = a.inventory.Sub(f.Quantity)
+		a.cash = a.cash.Add(f.Notional())
+	}
+	a.cash = a.cash.Sub(fee)
+	a.fees = a.fees.Add(fee)
+	if abs := a.inventory.Abs(); abs.GreaterThan(a.maxAbs) {
+		a.maxAbs = abs
+	}
+}
+
+// Context gives the agent access to the book and its account
+type Context struct {
+	sim     *Simulator
+	account *account
+}
+
+// Now returns the simulation time
+func (c *Context) Now() time.Time {
+	return c.sim.now
+}
+
+// Book returns the book, it must not be modified by the agent
+func (c *Context) Book() *orderbook.OrderBook {
+	return c.sim.book
+}
+
+// Owner returns owner of the agent orders
+func (c *Context) Owner() string {
+	return c.account.owner
+}
+
+// Inventory returns the position of the agent, negative if short
+func (c *Context) Inventory() decimal.Decimal {
+	return c.account.inventory
+}
+
+// Cash returns cash balance of the agent including fees
+func (c *Context) Cash() decimal.Decimal {
+	return c.account.cash
+}
+
+// Pending returns number of the agent commands not applied to the book yet
+func (c *Context) Pending() int {
+	return c.account.pending
+}
+
+// Orders returns IDs of the agent orders resting in the book
+func (c *Context) Orders() []string {
+	live := c.account.ids[:0]
+	for _, id := range c.account.ids {
+		if c.sim.book.Order(id) != nil {
+			live = append(live, id)
+		}
+	}
+	c.account.ids = live
+	return append([]string(nil), live...)
+}
+
+// Limit submits limit order and returns its ID
+func (c *Context) Limit(side orderbook.Side, quantity, price decimal.Decimal) string {
+	c.account.nextID++
+	id := c.account.owner + "-" + strconv.Itoa(c.account.nextID)
+	c.account.ids = append(c.account.ids, id)
+	c.submit(&orderbook.Command{Type: orderbook.CommandLimit, ID: id, Side: side, Quantity: quantity, Price: price})
+	return id
+}
+
+// Market submits market order of the quantity
+func (c *Context) Market(side orderbook.Side, quantity decimal.Decimal) {
+	c.submit(&orderbook.Command{Type: orderbook.CommandMarket, Side: side, Quantity: quantity})
+}
+
+// Cancel submits cancel of the order
+func (c *Context) Cancel(orderID string) {
+	c.submit(&orderbook.Command{Type: orderbook.CommandCancel, ID: orderID})
+}
+
+// Replace submits replace of the order (see OrderBook.ReplaceOrder)
+func (c *Context) Replace(orderID string, quantity, price decimal.Decimal) {
+	c.submit(&orderbook.Command{Type: orderbook.CommandReplace, ID: orderID, Quantity: quantity, Price: price})
+}
+
+func (c *Context) submit(cmd *orderbook.Command) {
+	cmd.Owner = c.account.owner
+	cmd.Time = c.sim.now.Add(c.sim.Latency)
+	c.account.pending++
+	c.sim.schedule(c.account, cmd)
+}
@@ -0,0 +1,87 @@
+package sim
+
+import (
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// MarketMaker is the example agent quoting both sides around the mid price of other orders,
+// quotes are skewed against the inventory and the side increasing the inventory is pulled at
+// MaxInventory. Quotes are not moved while previous commands are in flight
+type MarketMaker struct {
+	HalfSpread   decimal.Decimal // distance of quotes from the fair price
+	Size         decimal.Decimal // quantity of quotes
+	Skew         decimal.Decimal // fair price shift per unit of inventory
+	MaxInventory decimal.Decimal // absolute inventory limit, unlimited if zero
+	Tick         decimal.Decimal // price increment of quotes
+
+	bid, ask           string
+	bidPrice, askPrice decimal.Decimal
+}
+
+// OnBook implements Agent interface
+func (m *MarketMaker) OnBook(ctx *Context) {
+	if ctx.Pending() > 0 {
+		return
+	}
+	depth := ctx.Book().Depth(2)
+	bestBid, ok := m.touch(ctx, depth.Bids, m.bid)
+	if !ok {
+		return
+	}
+	bestAsk, ok := m.touch(ctx, depth.Asks, m.ask)
+	if !ok {
+		return
+	}
+	mid := bestBid.Add(bestAsk).Div(decimal.New(2, 0))
+
+	fair := mid.Sub(ctx.Inventory().Mul(m.Skew))
+	bid := fair.Sub(m.HalfSpread).Div(m.Tick).Floor().Mul(m.Tick)
+	ask := fair.Add(m.HalfSpread).Div(m.Tick).Ceil().Mul(m.Tick)
+	limited := m.MaxInventory.Sign() > 0
+
+	m.bid, m.bidPrice = m.quote(ctx, orderbook.Buy, m.bid, m.bidPrice, bid,
+		!limited || ctx.Inventory().LessThan(m.MaxInventory))
+	m.ask, m.askPrice = m.quote(ctx, orderbook.Sell, m.ask, m.askPrice, ask,
+		!limited || ctx.Inventory().GreaterThan(m.MaxInventory.Neg()))
+}
+
+// touch returns the best price of the levels without the own quote
+func (m *MarketMaker) touch(ctx *Context, levels [][]decimal.Decimal, quote string) (decimal.Decimal, bool) {
+	o := ctx.Book().Order(quote)
+	for _, level := range levels {
+		volume := level[1]
+		if o != nil && o.Price().Equal(level[0]) {
+			volume = volume.Sub(o.Quantity())
+		}
+		if volume.Sign() > 0 {
+			return level[0], true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// quote moves the quote to the price, the quote is cancelled if it is not allowed
+func (m *MarketMaker) quote(ctx *Context, side orderbook.Side, id string, current, price decimal.Decimal, allowed bool) (string, decimal.Decimal) {
+	if len(id) > 0 && allowed && current.Equal(price) {
+		return id, current
+	}
+	if len(id) > 0 {
+		ctx.Cancel(id)
+	}
+	if !allowed || price.Sign() <= 0 {
+		return "", decimal.Zero
+	}
+	return ctx.Limit(side, m.Size, price), price
+}
+
+// OnFill implements Agent interface
+func (m *MarketMaker) OnFill(ctx *Context, fill *orderbook.Fill) {
+	// filled quote is placed again on the next book update
+	if fill.MakerID == m.bid && ctx.Book().Order(m.bid) == nil {
+		m.bid = ""
+	}
+	if fill.MakerID == m.ask && ctx.Book().Order(m.ask) == nil {
+		m.ask = ""
+	}
+}
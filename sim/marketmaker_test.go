@@ -0,0 +1,57 @@
+package sim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/centny/orderbook/loadgen"
+	"github.com/shopspring/decimal"
+)
+
+func TestMarketMaker(t *testing.T) {
+	book := orderbook.NewOrderBook()
+	config := loadgen.DefaultConfig()
+	config.Start = time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	g, err := loadgen.NewGenerator(book, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(book, NewGeneratorSource(g, 20000))
+	s.Latency = time.Millisecond
+
+	mm := &MarketMaker{
+		HalfSpread:   decimal.New(5, -2),
+		Size:         decimal.New(10, 0),
+		Skew:         decimal.New(1, -3),
+		MaxInventory: decimal.New(100, 0),
+		Tick:         config.Tick,
+	}
+	s.AddAgent("mm", mm)
+
+	report, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	a := report.Agents[0]
+	if report.Flow != 20000 || a.Fills == 0 || a.FillRatio <= 0 || a.FillRatio > 1 {
+		t.Fatalf("report %+v", a)
+	}
+	// inventory limit can be exceeded by fills of the quote in flight
+	if a.MaxInventory.GreaterThan(mm.MaxInventory.Add(mm.Size.Mul(decimal.New(2, 0)))) {
+		t.Fatalf("max inventory %s", a.MaxInventory)
+	}
+	if !a.PnL.Equal(a.Cash.Add(a.Inventory.Mul(report.Mark))) {
+		t.Fatalf("pnl %s", a.PnL)
+	}
+
+	if len(mm.bid) > 0 {
+		if o := book.Order(mm.bid); o != nil && (o.Owner() != "mm" || o.Side() != orderbook.Buy) {
+			t.Fatalf("bid quote %v", o)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Package sim is a discrete-event backtesting simulator: it drives an OrderBook with historical
+// or synthetic flow and lets strategy agents trade against the flow with the book matching rules.
+package sim
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// ErrDuplicateAgent is returned by AddAgent if the owner already has an agent
+var ErrDuplicateAgent = errors.New("sim: duplicate agent")
+
+// event is the agent command applied to the book at its time
+type event struct {
+	seq     uint64
+	account *account
+	cmd     *orderbook.Command
+}
+
+// events is the priority queue of agent commands by time then submission order
+type events []*event
+
+func (q events) Len() int { return len(q) }
+func (q events) Less(i, j int) bool {
+	if !q[i].cmd.Time.Equal(q[j].cmd.Time) {
+		return q[i].cmd.Time.Before(q[j].cmd.Time)
+	}
+	return q[i].seq < q[j].seq
+}
+func (q events) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *events) Push(x interface{}) { *q = append(*q, x.(*event)) }
+func (q *events) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// Simulator applies the flow and the agent commands to the book in time order. Agent commands
+// due at the same time as the flow command are applied first.
+//
+// Agent commands submitted while the agent commands due before the flow command are applied
+// are deferred until the next flow command, so with zero Latency agents reacting to their own
+// commands (e.g. submitting on every OnBook) get one round of commands per flow command
+type Simulator struct {
+	book   *orderbook.OrderBook
+	source Source
+
+	Latency time.Duration // delay of agent commands, zero applies them before the next flow command
+
+	now      time.Time
+	seq      uint64
+	queue    events
+	accounts []*account
+	owners   map[string]*account
+	flow     int
+	last     decimal.Decimal // last trade price
+}
+
+// New creates simulator of the book driven by the source
+func New(book *orderbook.OrderBook, source Source) *Simulator {
+	return &Simulator{
+		book:   book,
+		source: source,
+		owners: map[string]*account{},
+	}
+}
+
+// AddAgent adds the agent trading with orders of the owner
+func (s *Simulator) AddAgent(owner string, agent Agent) error {
+	if _, ok := s.owners[owner]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateAgent, owner)
+	}
+	a := &account{owner: owner, agent: agent}
+	s.accounts = append(s.accounts, a)
+	s.owners[owner] = a
+	return nil
+}
+
+func (s *Simulator) schedule(a *account, cmd *orderbook.Command) {
+	s.seq++
+	heap.Push(&s.queue, &event{seq: s.seq, account: a, cmd: cmd})
+}
+
+// Run applies the flow until the end of the source with the commands agents submitted meanwhile,
+// agent commands due after the last flow command are not applied
+func (s *Simulator) Run() (*Report, error) {
+	for {
+		next, err := s.source.Next()
+		if err == io.EOF {
+			return s.Report(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		submitted := s.seq
+		for s.queue.Len() > 0 && !s.queue[0].cmd.Time.After(s.time(next)) && s.queue[0].seq <= submitted {
+			e := heap.Pop(&s.queue).(*event)
+			e.account.pending--
+			s.apply(e.account, e.cmd)
+		}
+		s.flow++
+		s.apply(nil, next)
+	}
+}
+
+// time returns time of the flow command, commands without time happen now
+func (s *Simulator) time(cmd *orderbook.Command) time.Time {
+	if cmd.Time.IsZero() {
+		return s.now
+	}
+	return cmd.Time
+}
+
+func (s *Simulator) apply(a *account, cmd *orderbook.Command) {
+	if at := s.time(cmd); at.After(s.now) {
+		s.now = at
+	}
+	if cmd.Time.IsZero() {
+		cmd.Time = s.now
+	}
+
+	result := s.book.Apply(cmd)
+	if a != nil {
+		switch {
+		case result.Err != nil:
+			a.rejects++
+		case cmd.Type == orderbook.CommandLimit || cmd.Type == orderbook.CommandMarket:
+			a.orders++
+			a.submitted = a.submitted.Add(cmd.Quantity)
+		}
+	}
+
+	for _, f := range result.Fills {
+		s.last = f.Price
+		if maker, ok := s.owners[f.MakerOwner]; ok {
+			maker.fill(f, opposite(f.Side), f.MakerFee)
+			maker.agent.OnFill(&Context{sim: s, account: maker}, f)
+		}
+		if taker, ok := s.owners[f.TakerOwner]; ok {
+			taker.fill(f, f.Side, f.TakerFee)
+			taker.agent.OnFill(&Context{sim: s, account: taker}, f)
+		}
+	}
+	for _, a := range s.accounts {
+		a.agent.OnBook(&Context{sim: s, account: a})
+	}
+}
+
+func opposite(side orderbook.Side) orderbook.Side {
+	if side == orderbook.Buy {
+		return orderbook.Sell
+	}
+	return orderbook.Buy
+}
+
+// AgentReport is the performance of the agent
+type AgentReport struct {
+	Owner        string          `json:"owner"`
+	Orders       int             `json:"orders"`  // accepted limit and market orders
+	Rejects      int             `json:"rejects"` // rejected commands
+	Fills        int             `json:"fills"`
+	Submitted    decimal.Decimal `json:"submitted"` // quantity of accepted orders
+	Filled       decimal.Decimal `json:"filled"`
+	FillRatio    float64         `json:"fillRatio"`
+	Inventory    decimal.Decimal `json:"inventory"`
+	MaxInventory decimal.Decimal `json:"maxInventory"` // max absolute inventory
+	Cash         decimal.Decimal `json:"cash"`
+	Fees         decimal.Decimal `json:"fees"`
+	PnL          decimal.Decimal `json:"pnl"` // cash plus inventory marked to the mark price
+}
+
+// Report of the simulation
+type Report struct {
+	Time   time.Time       `json:"time"`
+	Flow   int             `json:"flow"` // applied flow commands
+	Mark   decimal.Decimal `json:"mark"` // mid price, last trade price if the book is one-sided
+	Agents []*AgentReport  `json:"agents"`
+}
+
+// Report returns performance of the agents at the current time
+func (s *Simulator) Report() *Report {
+	mark, err := s.book.MidPrice()
+	if err != nil {
+		mark = s.last
+	}
+
+	r := &Report{Time: s.now, Flow: s.flow, Mark: mark}
+	for _, a := range s.accounts {
+		ar := &AgentReport{
+			Owner:        a.owner,
+			Orders:       a.orders,
+			Rejects:      a.rejects,
+			Fills:        a.fills,
+			Submitted:    a.submitted,
+			Filled:       a.filled,
+			Inventory:    a.inventory,
+			MaxInventory: a.maxAbs,
+			Cash:         a.cash,
+			Fees:         a.fees,
+			PnL:          a.cash.Add(a.inventory.Mul(mark)),
+		}
+		if a.submitted.Sign() > 0 {
+			ar.FillRatio, _ = a.filled.Div(a.submitted).Float64()
+		}
+		r.Agents = append(r.Agents, ar)
+	}
+	sort.Slice(r.Agents, func(i, j int) bool { return r.Agents[i].Owner < r.Agents[j].Owner })
+	return r
+}
@@ -0,0 +1,157 @@
+package sim
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/centny/orderbook"
+	"github.com/shopspring/decimal"
+)
+
+// script submits commands once at the first book update and records callbacks
+type script struct {
+	submit func(ctx *Context)
+	times  []time.Time
+	fills  []*orderbook.Fill
+}
+
+func (s *script) OnBook(ctx *Context) {
+	s.times = append(s.times, ctx.Now())
+	if s.submit != nil {
+		s.submit(ctx)
+		s.submit = nil
+	}
+}
+
+func (s *script) OnFill(ctx *Context, fill *orderbook.Fill) {
+	s.fills = append(s.fills, fill)
+}
+
+const flow = `{"type":"limit","time":"2024-01-02T09:30:00Z","id":"a1","owner":"flow","side":"sell","quantity":"5","price":"101"}
+{"type":"limit","time":"2024-01-02T09:30:01Z","id":"b1","owner":"flow","side":"buy","quantity":"5","price":"99"}
+
+{"type":"market","time":"2024-01-02T09:30:02Z","owner":"flow","side":"sell","quantity":"3"}
+{"type":"market","time":"2024-01-02T09:30:03Z","owner":"flow","side":"buy","quantity":"1"}
+`
+
+func TestSimulator(t *testing.T) {
+	s := New(orderbook.NewOrderBook(), NewLogSource(strings.NewReader(flow)))
+	s.Latency = 1500 * time.Millisecond
+
+	var ids []string
+	agent := &script{submit: func(ctx *Context) {
+		ids = append(ids, ctx.Limit(orderbook.Buy, decimal.New(2, 0), decimal.New(100, 0)))
+		ids = append(ids, ctx.Limit(orderbook.Buy, decimal.New(1, 0), decimal.New(0, 0)))
+		ctx.Market(orderbook.Buy, decimal.New(1, 0))
+	}}
+	if err := s.AddAgent("mm", agent); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddAgent("mm", &script{}); !errors.Is(err, ErrDuplicateAgent) {
+		t.Fatalf("duplicate agent: %v", err)
+	}
+
+	report, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// agent commands submitted at 09:30:00 are applied at 09:30:01.5 before the flow market sell
+	start := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+	if len(agent.times) != 7 || !agent.times[2].Equal(start.Add(1500*time.Millisecond)) {
+		t.Fatalf("book updates at %v", agent.times)
+	}
+	if ids[0] != "mm-1" || len(agent.fills) != 2 {
+		t.Fatalf("ids %v, fills %v", ids, agent.fills)
+	}
+	if f := agent.fills[0]; f.TakerOwner != "mm" || !f.Price.Equal(decimal.New(101, 0)) {
+		t.Fatalf("market buy fill %v", f)
+	}
+	if f := agent.fills[1]; f.MakerID != "mm-1" || !f.Quantity.Equal(decimal.New(2, 0)) {
+		t.Fatalf("bid fill %v", f)
+	}
+
+	if report.Flow != 4 || !report.Time.Equal(start.Add(3*time.Second)) || len(report.Agents) != 1 {
+		t.Fatalf("report %+v", report)
+	}
+	a := report.Agents[0]
+	// bought 1 at 101 and 2 at 100, marked at mid (99 + 101) / 2
+	if a.Owner != "mm" || a.Orders != 2 || a.Rejects != 1 || a.Fills != 2 || a.FillRatio != 1 ||
+		!a.Inventory.Equal(decimal.New(3, 0)) || !a.Cash.Equal(decimal.New(-301, 0)) ||
+		!report.Mark.Equal(decimal.New(100, 0)) || !a.PnL.Equal(decimal.New(-1, 0)) {
+		t.Fatalf("agent report %+v, mark %s", a, report.Mark)
+	}
+}
+
+// chatty submits limit order on every book update
+type chatty struct{}
+
+func (chatty) OnBook(ctx *Context) {
+	ctx.Limit(orderbook.Buy, decimal.New(1, 0), decimal.New(1, 0))
+}
+
+func (chatty) OnFill(ctx *Context, fill *orderbook.Fill) {}
+
+func TestSimulatorZeroLatency(t *testing.T) {
+	s := New(orderbook.NewOrderBook(), NewLogSource(strings.NewReader(flow)))
+	if err := s.AddAgent("mm", chatty{}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// commands submitted by the agent commands wait for the next flow command: 1 + 2 + 3
+	if report.Flow != 4 || report.Agents[0].Orders != 6 {
+		t.Fatalf("report %+v", report.Agents[0])
+	}
+}
+
+func TestSimulatorLogSourceError(t *testing.T) {
+	s := New(orderbook.NewOrderBook(), NewLogSource(strings.NewReader(flow+"{\n")))
+	if _, err := s.Run(); err == nil || !strings.Contains(err.Error(), "line 6") {
+		t.Fatalf("error %v", err)
+	}
+}
+
+func TestContextOrders(t *testing.T) {
+	s := New(orderbook.NewOrderBook(), NewLogSource(strings.NewReader(flow)))
+	var orders [][]string
+	agent := &script{submit: func(ctx *Context) {
+		ctx.Limit(orderbook.Sell, decimal.New(1, 0), decimal.New(110, 0))
+		id := ctx.Limit(orderbook.Sell, decimal.New(1, 0), decimal.New(111, 0))
+		ctx.Replace(id, decimal.New(1, 0), decimal.New(112, 0))
+		ctx.Cancel(ctx.Owner() + "-1")
+		if ctx.Pending() != 4 {
+			t.Errorf("pending %d", ctx.Pending())
+		}
+	}}
+	s.AddAgent("mm", agent)
+	s.AddAgent("observer", &observer{func(ctx *Context) {
+		orders = append(orders, ctx.Orders())
+	}})
+
+	report, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := orders[len(orders)-1]; len(got) != 0 || s.owners["mm"].pending != 0 {
+		t.Fatalf("observer orders %v", got)
+	}
+	if o := s.book.Order("mm-2"); o == nil || !o.Price().Equal(decimal.New(112, 0)) || o.Owner() != "mm" {
+		t.Fatalf("replaced order %v", o)
+	}
+	if s.book.Order("mm-1") != nil || report.Agents[0].Orders != 2 || report.Agents[0].FillRatio != 0 {
+		t.Fatalf("report %+v", report.Agents[0])
+	}
+}
+
+type observer struct {
+	onBook func(ctx *Context)
+}
+
+func (o *observer) OnBook(ctx *Context)                       { o.onBook(ctx) }
+func (o *observer) OnFill(ctx *Context, fill *orderbook.Fill) {}
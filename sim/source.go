@@ -0,0 +1,67 @@
+package sim
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/centny/orderbook"
+	"github.com/centny/orderbook/loadgen"
+)
+
+// Source is the flow of commands driving the book, Next returns io.EOF at the end of flow
+type Source interface {
+	Next() (*orderbook.Command, error)
+}
+
+type logSource struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewLogSource creates source of historical flow from JSON lines of orderbook.Command
+// (the format of replication, the HTTP gateway and cmd/obreplay)
+func NewLogSource(r io.Reader) Source {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &logSource{scanner: scanner}
+}
+
+func (s *logSource) Next() (*orderbook.Command, error) {
+	for s.scanner.Scan() {
+		s.line++
+		data := s.scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+		cmd := &orderbook.Command{}
+		if err := json.Unmarshal(data, cmd); err != nil {
+			return nil, fmt.Errorf("line %d: %w", s.line, err)
+		}
+		return cmd, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+type generatorSource struct {
+	generator *loadgen.Generator
+	left      int
+}
+
+// NewGeneratorSource creates source of n synthetic commands, the generator must be created for
+// the book of the simulator
+func NewGeneratorSource(g *loadgen.Generator, n int) Source {
+	return &generatorSource{generator: g, left: n}
+}
+
+func (s *generatorSource) Next() (*orderbook.Command, error) {
+	if s.left <= 0 {
+		return nil, io.EOF
+	}
+	s.left--
+	return s.generator.Next(), nil
+}
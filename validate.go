@@ -0,0 +1,163 @@
+package orderbook
+
+import (
+	"container/list"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+func validateOrder(o *Order, price decimal.Decimal) error {
+	if o == nil {
+		return fmt.Errorf("price level %s contains empty order", price)
+	}
+	if len(o.ID()) == 0 {
+		return fmt.Errorf("price level %s contains order without ID", price)
+	}
+	if o.Quantity().Sign() <= 0 {
+		return fmt.Errorf("order %s has invalid quantity %s", o.ID(), o.Quantity())
+	}
+	if !o.Price().Equal(price) {
+		return fmt.Errorf("order %s price %s doesn't match price level %s", o.ID(), o.Price(), price)
+	}
+	return nil
+}
+
+func validateSide(os *OrderSide, side Side) error {
+	if os.priceTree.Size() != len(os.prices) || os.depth != len(os.prices) {
+		return fmt.Errorf("%s side depth is %d, price tree has %d levels, price map has %d levels",
+			side, os.depth, os.priceTree.Size(), len(os.prices))
+	}
+
+	numOrders := 0
+	volume := decimal.Zero
+	for iter := os.priceTree.Iterator(); iter.Next(); {
+		price, queue := iter.Key().(decimal.Decimal), iter.Value().(*OrderQueue)
+		if os.prices[price.String()] != queue || !queue.Price().Equal(price) {
+			return fmt.Errorf("%s side price level %s is not indexed", side, price)
+		}
+		if queue.Len() == 0 {
+			return fmt.Errorf("%s side price level %s is empty", side, price)
+		}
+
+		queueVolume := decimal.Zero
+		for e := queue.Head(); e != nil; e = e.Next() {
+			o := e.Value.(*Order)
+			if err := validateOrder(o, price); err != nil {
+				return err
+			}
+			if o.Side() != side {
+				return fmt.Errorf("%s order %s is placed to %s side", o.Side(), o.ID(), side)
+			}
+			queueVolume = queueVolume.Add(o.Quantity())
+		}
+
+		if !queueVolume.Equal(queue.Volume()) {
+			return fmt.Errorf("%s side price level %s volume is %s, orders volume is %s", side, price, queue.Volume(), queueVolume)
+		}
+		numOrders += queue.Len()
+		volume = volume.Add(queueVolume)
+	}
+
+	if numOrders != os.numOrders {
+		return fmt.Errorf("%s side has %d orders, counter is %d", side, numOrders, os.numOrders)
+	}
+	if !volume.Equal(os.volume) {
+		return fmt.Errorf("%s side volume is %s, orders volume is %s", side, os.volume, volume)
+	}
+	return nil
+}
+
+// validate checks invariants of the book and returns description of the first violated one
+func (ob *OrderBook) validate() error {
+	if err := validateSide(ob.asks, Sell); err != nil {
+		return err
+	}
+	if err := validateSide(ob.bids, Buy); err != nil {
+		return err
+	}
+
+	if n := ob.asks.Len() + ob.bids.Len(); n != len(ob.orders) {
+		return fmt.Errorf("sides have %d orders, order index has %d", n, len(ob.orders))
+	}
+
+	owned := 0
+	for _, os := range []*OrderSide{ob.asks, ob.bids} {
+		for _, e := range os.Orders() {
+			o := e.Value.(*Order)
+			if ob.orders[o.ID()] != e {
+				return fmt.Errorf("order %s is not indexed", o.ID())
+			}
+			if len(o.Owner()) > 0 {
+				if ob.owners[o.Owner()][o.ID()] != e {
+					return fmt.Errorf("order %s is not indexed by owner %s", o.ID(), o.Owner())
+				}
+				owned++
+			}
+		}
+	}
+
+	for owner, orders := range ob.owners {
+		owned -= len(orders)
+		if len(orders) == 0 {
+			return fmt.Errorf("owner %s index is empty", owner)
+		}
+	}
+	if owned != 0 {
+		return fmt.Errorf("owner index contains removed orders")
+	}
+
+	if bid, ask := ob.BestBid(), ob.BestAsk(); bid != nil && ask != nil && bid.Price().GreaterThanOrEqual(ask.Price()) {
+		return fmt.Errorf("book is crossed: best bid %s, best ask %s", bid.Price(), ask.Price())
+	}
+	return nil
+}
+
+// Validate checks internal consistency of the book: aggregates of every side and price level
+// match the orders, every order is placed to the right side and price level and is indexed
+// by ID and owner, the book is not crossed. It is intended to run after restoring the book
+// from a snapshot, it walks all orders
+func (ob *OrderBook) Validate() error {
+	if err := ob.validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidState, err)
+	}
+	return nil
+}
+
+// restore replaces orders of the book with the given sides, the book is not changed if
+// restored orders are inconsistent
+func (ob *OrderBook) restore(asks, bids *OrderSide) error {
+	if asks == nil {
+		asks = NewOrderSide()
+	}
+	if bids == nil {
+		bids = NewOrderSide()
+	}
+
+	restored := &OrderBook{
+		orders: map[string]*list.Element{},
+		owners: map[string]map[string]*list.Element{},
+		asks:   asks,
+		bids:   bids,
+	}
+
+	for _, os := range []*OrderSide{asks, bids} {
+		for _, e := range os.Orders() {
+			id := e.Value.(*Order).ID()
+			if _, ok := restored.orders[id]; ok {
+				return fmt.Errorf("%w: duplicated order ID %s", ErrInvalidSnapshot, id)
+			}
+			restored.indexOrder(e)
+		}
+	}
+
+	if err := restored.validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+
+	ob.asks = restored.asks
+	ob.bids = restored.bids
+	ob.orders = restored.orders
+	ob.owners = restored.owners
+	return nil
+}
@@ -0,0 +1,85 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestValidate(t *testing.T) {
+	ob := NewOrderBook()
+	if err := ob.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	addDepth(ob, "", decimal.New(2, 0))
+	ob.ProcessLimitOrderFor("alice", Buy, "alice-1", decimal.New(3, 0), decimal.New(100, 0))
+	ob.ProcessMarketQuantityOrder(Sell, decimal.New(1, 0))
+	if err := ob.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ob.bids.volume = ob.bids.volume.Add(decimal.New(1, 0))
+	if err := ob.Validate(); !errors.Is(err, ErrInvalidState) || !strings.Contains(err.Error(), "buy side volume") {
+		t.Fatal("inconsistent volume is not detected", err)
+	}
+	ob.bids.volume = ob.bids.volume.Sub(decimal.New(1, 0))
+
+	delete(ob.orders, "sell-110")
+	if err := ob.Validate(); err == nil {
+		t.Fatal("missing index is not detected")
+	}
+}
+
+func TestRestoreValidation(t *testing.T) {
+	ob := NewOrderBook()
+	addDepth(ob, "", decimal.New(2, 0))
+	data, _ := json.Marshal(ob)
+	snapshot := string(data)
+
+	restored := NewOrderBook()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := map[string]string{
+		"numOrders":  strings.Replace(snapshot, `"numOrders":5`, `"numOrders":7`, 1),
+		"depth":      strings.Replace(snapshot, `"depth":5`, `"depth":4`, 1),
+		"volume":     strings.Replace(snapshot, `"volume":"2"`, `"volume":"3"`, 1),
+		"price":      strings.Replace(snapshot, `"quantity":"2","price":"100"`, `"quantity":"2","price":"101"`, 1),
+		"level key":  strings.Replace(snapshot, `"100":{`, `"100.5":{`, 1),
+		"duplicated": strings.Replace(snapshot, `"id":"buy-50"`, `"id":"sell-100"`, 1),
+		"side":       strings.Replace(snapshot, `"side":"buy","id":"buy-50"`, `"side":"sell","id":"buy-50"`, 1),
+		"quantity":   strings.Replace(snapshot, `"quantity":"2","price":"50"`, `"quantity":"0","price":"50"`, 1),
+	}
+
+	for name, payload := range broken {
+		if payload == snapshot {
+			t.Fatalf("%s: snapshot is not changed", name)
+		}
+		restored := NewOrderBook()
+		addDepth(restored, "keep-", decimal.New(1, 0))
+		err := json.Unmarshal([]byte(payload), restored)
+		if !errors.Is(err, ErrInvalidSnapshot) {
+			t.Fatalf("%s: inconsistent snapshot is loaded: %v", name, err)
+		}
+		if restored.Order("keep-buy-50") == nil {
+			t.Fatalf("%s: book is changed on error", name)
+		}
+	}
+
+	crossed := NewOrderBook()
+	crossed.ProcessLimitOrder(Buy, "b", decimal.New(1, 0), decimal.New(100, 0))
+	data, _ = json.Marshal(crossed)
+	data = []byte(strings.Replace(string(data), `"asks":{"numOrders":0,"depth":0,"prices":{}}`,
+		`"asks":{"prices":{"90":{"price":"90","orders":[{"side":"sell","id":"s","quantity":"1","price":"90"}]}}}`, 1))
+	if err := json.Unmarshal(data, NewOrderBook()); !errors.Is(err, ErrInvalidSnapshot) || !strings.Contains(err.Error(), "crossed") {
+		t.Fatal("crossed snapshot is loaded", err)
+	}
+}